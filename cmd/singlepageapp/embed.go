@@ -0,0 +1,21 @@
+//go:build embed
+
+package main
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// static is embedded when building with -tags embed, so the binary serves it without -static
+//
+//go:embed all:static
+var static embed.FS
+
+func embeddedFS() (fs.FS, bool) {
+	fsys, err := fs.Sub(static, "static")
+	if err != nil {
+		panic(err)
+	}
+	return fsys, true
+}
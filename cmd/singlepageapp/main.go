@@ -1,22 +1,175 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/janmbaco/go-infrastructure/v2/logs"
+	"github.com/janmbaco/go-infrastructure/v2/server"
+	"github.com/janmbaco/go-infrastructure/v2/server/devtls"
 	"github.com/janmbaco/go-infrastructure/v2/server/facades"
 )
 
+// overrides are the config changes of the flags set in the command line, which win over the config file
+type overrides map[string]func(config *facades.SinglePageAppConfig)
+
 func main() {
-	port := flag.String("port", ":8080", "port to listen on, like :8080")
-	staticPath := flag.String("static", "./static", "path to static files")
-	index := flag.String("index", "index.html", "index file name")
+	defaults := facades.DefaultSinglePageAppConfig()
+	// unlike the facades, the command sends the security headers and has the health endpoints at the usual paths
+	defaults.SecurityHeaders.Enabled = true
+	defaults.Health.LivenessPath, defaults.Health.ReadinessPath = "/healthz", "/readyz"
+	flags := make(overrides)
+
+	configFile := flag.String("config", os.Args[0]+".json", "config file, created with the defaults when it does not exist; the flags set override it")
+	printConfig := flag.Bool("print-config", false, "print the configuration that would be applied and exit")
+	validateConfig := flag.Bool("validate-config", false, "validate the configuration and exit, with status 1 when it is invalid")
+
+	flags.string("port", defaults.Port, "port to listen on, like :8080", func(c *facades.SinglePageAppConfig, v string) { c.Port = v })
+	flags.string("static", defaults.StaticPath, "path to static files, ignored when built with -tags embed", func(c *facades.SinglePageAppConfig, v string) { c.StaticPath = v })
+	flags.string("index", defaults.Index, "index file name", func(c *facades.SinglePageAppConfig, v string) { c.Index = v })
+	flags.string("base-path", defaults.BasePath, "path the app is mounted below, like /app", func(c *facades.SinglePageAppConfig, v string) { c.BasePath = v })
+	flags.string("tls-cert", "", "certificate file to serve HTTPS, reloaded when it changes", func(c *facades.SinglePageAppConfig, v string) { c.TLS.CertFile = v })
+	flags.string("tls-key", "", "key file of -tls-cert", func(c *facades.SinglePageAppConfig, v string) { c.TLS.KeyFile = v })
+	flags.string("log-dir", defaults.Log.Dir, "directory of the log files, none when empty", func(c *facades.SinglePageAppConfig, v string) { c.Log.Dir = v })
+	flags.string("log-level", defaults.Log.Level, "trace, info, warning, error or fatal", func(c *facades.SinglePageAppConfig, v string) { c.Log.Level = v })
+	flags.bool("security-headers", defaults.SecurityHeaders.Enabled, "send X-Content-Type-Options, X-Frame-Options and Referrer-Policy", func(c *facades.SinglePageAppConfig, v bool) { c.SecurityHeaders.Enabled = v })
+	flags.string("csp", defaults.SecurityHeaders.ContentSecurityPolicy, "Content-Security-Policy of the responses, needs -security-headers", func(c *facades.SinglePageAppConfig, v string) {
+		c.SecurityHeaders.ContentSecurityPolicy = v
+	})
+	flags.duration("hsts", time.Duration(defaults.SecurityHeaders.HSTSMaxAge), "Strict-Transport-Security max-age of HTTPS responses, like 8760h, needs -security-headers", func(c *facades.SinglePageAppConfig, v time.Duration) {
+		c.SecurityHeaders.HSTSMaxAge = server.Duration(v)
+	})
+	flags.string("cache-policy", defaults.Cache.Policy, "fingerprinted: fingerprinted assets are immutable; revalidate: every file is revalidated", func(c *facades.SinglePageAppConfig, v string) { c.Cache.Policy = v })
+	flags.duration("cache-max-age", time.Duration(defaults.Cache.AssetsMaxAge), "max-age of the assets that are neither the index nor fingerprinted", func(c *facades.SinglePageAppConfig, v time.Duration) {
+		c.Cache.AssetsMaxAge = server.Duration(v)
+	})
+	flags.bool("compress", defaults.Compression.Dynamic, "compress with gzip or deflate the responses without a precompressed file", func(c *facades.SinglePageAppConfig, v bool) { c.Compression.Dynamic = v })
+	flags.string("precompressed", strings.Join(defaults.Compression.Precompressed, ","), "encodings of the precompressed .br and .gz files to serve, in order of preference, or none", func(c *facades.SinglePageAppConfig, v string) {
+		c.Compression.Precompressed = []string{}
+		if v != "none" && v != "" {
+			c.Compression.Precompressed = strings.Split(v, ",")
+		}
+	})
+	flags.bool("health", defaults.Health.Enabled, "serve the liveness and readiness endpoints", func(c *facades.SinglePageAppConfig, v bool) { c.Health.Enabled = v })
+	flags.string("health-path", defaults.Health.LivenessPath, "path of the liveness endpoint, needs -health", func(c *facades.SinglePageAppConfig, v string) { c.Health.LivenessPath = v })
+	flags.string("ready-path", defaults.Health.ReadinessPath, "path of the readiness endpoint, needs -health", func(c *facades.SinglePageAppConfig, v string) { c.Health.ReadinessPath = v })
+
+	devTLS := flag.String("dev-tls", "", "serve HTTPS with a development certificate for these comma separated hosts, like localhost,127.0.0.1")
+	devTLSDir := flag.String("dev-tls-dir", "", "directory where the development CA and certificates are cached (default: user cache dir)")
+	clientCA := flag.String("client-ca", "", "require client certificates signed by a CA of this PEM bundle (needs -dev-tls)")
 	flag.Parse()
 
+	options := facades.SinglePageAppOptions{ConfigFile: *configFile, Defaults: defaults, Override: flags.set()}
+	if fsys, ok := embeddedFS(); ok {
+		options.FS = fsys
+	}
+	if *printConfig || *validateConfig {
+		os.Exit(checkConfig(options, *printConfig, *validateConfig))
+	}
+
 	logger := logs.NewLogger()
-	logger.Info(fmt.Sprintf("Iniciando servidor SPA en puerto %s, static: %s, index: %s", *port, *staticPath, *index))
-	facades.SinglePageAppStart(*port, *staticPath, *index)
+	tlsConfig, err := devTLSConfig(*devTLS, *devTLSDir, *clientCA)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+	options.TLSConfig = tlsConfig
+
+	logger.Infof("Starting the single page app server with the config file %v", *configFile)
+	if err := facades.SinglePageAppRun(context.Background(), options); err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
 	fmt.Fprintln(os.Stderr, "server exited")
 }
+
+func (o overrides) string(name, value, usage string, apply func(*facades.SinglePageAppConfig, string)) {
+	v := flag.String(name, value, usage)
+	o[name] = func(c *facades.SinglePageAppConfig) { apply(c, *v) }
+}
+
+func (o overrides) bool(name string, value bool, usage string, apply func(*facades.SinglePageAppConfig, bool)) {
+	v := flag.Bool(name, value, usage)
+	o[name] = func(c *facades.SinglePageAppConfig) { apply(c, *v) }
+}
+
+func (o overrides) duration(name string, value time.Duration, usage string, apply func(*facades.SinglePageAppConfig, time.Duration)) {
+	v := flag.Duration(name, value, usage)
+	o[name] = func(c *facades.SinglePageAppConfig) { apply(c, *v) }
+}
+
+// set returns the overrides of the flags set in the command line
+func (o overrides) set() func(*facades.SinglePageAppConfig) {
+	var applies []func(*facades.SinglePageAppConfig)
+	flag.Visit(func(f *flag.Flag) {
+		if apply, ok := o[f.Name]; ok {
+			applies = append(applies, apply)
+		}
+	})
+	return func(config *facades.SinglePageAppConfig) {
+		for _, apply := range applies {
+			apply(config)
+		}
+	}
+}
+
+// checkConfig prints or validates the configuration without starting the server, returning the exit status
+func checkConfig(options facades.SinglePageAppOptions, print, validate bool) int {
+	config, err := options.Config()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if print {
+		content, err := json.MarshalIndent(config, "", "\t")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Println(string(content))
+	}
+	if validate {
+		if err := config.Validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "%v is not valid:\n", options.ConfigFile)
+			for _, line := range strings.Split(err.Error(), "\n") {
+				fmt.Fprintln(os.Stderr, "  "+line)
+			}
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "%v is valid\n", options.ConfigFile)
+	}
+	return 0
+}
+
+func devTLSConfig(hosts, dir, clientCA string) (*tls.Config, error) {
+	if hosts == "" {
+		if clientCA != "" {
+			return nil, errors.New("-client-ca needs -dev-tls")
+		}
+		return nil, nil
+	}
+	authority, err := devtls.LoadOrCreateAuthority(dir)
+	if err != nil {
+		return nil, err
+	}
+	names := strings.Split(hosts, ",")
+	for i := range names {
+		names[i] = strings.TrimSpace(names[i])
+	}
+	tlsConfig, err := authority.ServerTLSConfig(names...)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(os.Stderr, "development CA: %v\n", authority.CertFile())
+	if clientCA == "" {
+		return tlsConfig, nil
+	}
+	return devtls.MutualTLSConfig(tlsConfig, clientCA)
+}
@@ -0,0 +1,9 @@
+//go:build !embed
+
+package main
+
+import "io/fs"
+
+func embeddedFS() (fs.FS, bool) {
+	return nil, false
+}
@@ -24,4 +24,9 @@ type (
 		SetRefreshTime(Period) error
 		ForceRefresh() error
 	}
+	// NotifyingRestorer is implemented by the ConfigHandlers that can restore the previous configuration
+	// and notify it to the subscribers of the modified configuration, like the file config handler
+	NotifyingRestorer interface {
+		RestoreAndNotify() error
+	}
 )
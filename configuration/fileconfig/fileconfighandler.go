@@ -88,11 +88,15 @@ func (f *fileConfigHandler) SetRefreshTime(period configuration.Period) error {
 
 // Freeze causes configuration changes to not be made until the end of the specified period
 func (f *fileConfigHandler) Freeze() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
 	f.isFreezed = true
 }
 
 // Unfreeze causes configuration changes to be made when they occur
 func (f *fileConfigHandler) Unfreeze() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
 	f.isFreezed = false
 }
 
@@ -164,6 +168,25 @@ func (f *fileConfigHandler) CanRestore() bool {
 
 // Restore restores the configuration to an older version
 func (f *fileConfigHandler) Restore() error {
+	if err := f.restore(); err != nil {
+		return err
+	}
+	eventsmanager.Publish(f.eventManager, events.RestoredEvent{})
+	return nil
+}
+
+// RestoreAndNotify restores the configuration to an older version and notifies it to the subscribers of
+// both the restored and the modified configuration
+func (f *fileConfigHandler) RestoreAndNotify() error {
+	if err := f.restore(); err != nil {
+		return err
+	}
+	eventsmanager.Publish(f.eventManager, events.RestoredEvent{})
+	eventsmanager.Publish(f.eventManager, events.ModifiedEvent{})
+	return nil
+}
+
+func (f *fileConfigHandler) restore() error {
 	f.mutex.Lock()
 
 	if f.oldconfig == nil {
@@ -202,7 +225,6 @@ func (f *fileConfigHandler) Restore() error {
 	}
 
 	f.mutex.Unlock()
-	eventsmanager.Publish(f.eventManager, events.RestoredEvent{})
 	return nil
 }
 
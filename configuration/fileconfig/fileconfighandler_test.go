@@ -4,6 +4,7 @@ import (
 	"log"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/janmbaco/go-infrastructure/v2/configuration"
 	"github.com/janmbaco/go-infrastructure/v2/disk"
@@ -264,6 +265,39 @@ func TestFileConfigHandler_Restore_WhenCanRestore_ThenRestoresOldConfig(t *testi
 	assert.False(t, handler.CanRestore())
 }
 
+func TestFileConfigHandler_RestoreAndNotify_WhenCanRestore_ThenRestoresAndNotifiesTheModifiedConfig(t *testing.T) {
+	// Arrange
+	tempDir := t.TempDir()
+	filePath := filepath.Join(tempDir, "config.json")
+	em := eventsmanager.NewEventManager()
+	errorCatcher := &mockErrorCatcher{}
+	notifier := &mockFileChangedNotifier{}
+	logger := &mockLogger{}
+	defaults := &testConfig{Name: "default", Value: 42}
+	handler, _ := NewFileConfigHandler(filePath, defaults, errorCatcher, em, notifier, logger)
+	require.NoError(t, handler.SetConfig(&testConfig{Name: "updated", Value: 100}))
+	modified, restored := make(chan struct{}, 1), make(chan struct{}, 1)
+	onModified := func() { modified <- struct{}{} }
+	onRestored := func() { restored <- struct{}{} }
+	handler.ModifiedSubscribe(&onModified)
+	handler.RestoredSubscribe(&onRestored)
+
+	// Act
+	err := handler.(configuration.NotifyingRestorer).RestoreAndNotify()
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "default", handler.GetConfig().(*testConfig).Name)
+	assert.False(t, handler.CanRestore())
+	for _, event := range []chan struct{}{modified, restored} {
+		select {
+		case <-event:
+		case <-time.After(time.Second):
+			t.Fatal("the restore was not notified")
+		}
+	}
+}
+
 func TestFileConfigHandler_Restore_WhenCannotRestore_ThenReturnsError(t *testing.T) {
 	// Arrange
 	tempDir := t.TempDir()
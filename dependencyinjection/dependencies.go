@@ -71,7 +71,18 @@ type dependencyObject struct {
 	dependecyType dependecyType
 }
 
+// scopedCreator is implemented by the dependency objects that record, in the order they are created,
+// the scoped instances they add to scopedObjects
+type scopedCreator interface {
+	createScoped(ctx context.Context, params map[string]interface{}, dependencies Dependencies, scopedObjects map[DependencyObject]interface{}, created *[]interface{}) interface{}
+}
+
 func (do *dependencyObject) Create(ctx context.Context, params map[string]interface{}, dependencies Dependencies, scopedObjects map[DependencyObject]interface{}) interface{} {
+	return do.createScoped(ctx, params, dependencies, scopedObjects, nil)
+}
+
+// createScoped creates the instance like Create and appends the scoped instances it creates to created, when not nil
+func (do *dependencyObject) createScoped(ctx context.Context, params map[string]interface{}, dependencies Dependencies, scopedObjects map[DependencyObject]interface{}, created *[]interface{}) interface{} {
 
 	// Check for context cancellation
 	if err := ctx.Err(); err != nil {
@@ -112,7 +123,7 @@ func (do *dependencyObject) Create(ctx context.Context, params map[string]interf
 			if object, isInParamas := params[do.argNames[i]]; name != "" && isInParamas {
 				args = append(args, reflect.ValueOf(object))
 			} else {
-				args = append(args, reflect.ValueOf(dependencies.Get(DependencyKey{Iface: functionType.In(i)}).Create(ctx, params, dependencies, scopedObjects)))
+				args = append(args, reflect.ValueOf(createDependency(ctx, dependencies.Get(DependencyKey{Iface: functionType.In(i)}), params, dependencies, scopedObjects, created)))
 			}
 		}
 	}
@@ -136,8 +147,19 @@ func (do *dependencyObject) Create(ctx context.Context, params map[string]interf
 			do.object = result
 		case _ScopedType:
 			scopedObjects[do] = result
+			if created != nil {
+				*created = append(*created, result)
+			}
 		}
 	}
 
 	return result
 }
+
+// createDependency creates the instance of object, recording its scoped instances in created when it can
+func createDependency(ctx context.Context, object DependencyObject, params map[string]interface{}, dependencies Dependencies, scopedObjects map[DependencyObject]interface{}, created *[]interface{}) interface{} {
+	if creator, ok := object.(scopedCreator); ok {
+		return creator.createScoped(ctx, params, dependencies, scopedObjects, created)
+	}
+	return object.Create(ctx, params, dependencies, scopedObjects)
+}
@@ -3,9 +3,12 @@ package main
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"time"
 
 	di "github.com/janmbaco/go-infrastructure/v2/dependencyinjection"
+	"github.com/janmbaco/go-infrastructure/v2/dependencyinjection/requestscope"
 )
 
 // Example services demonstrating context usage
@@ -112,6 +115,12 @@ func main() {
 	fmt.Println("Example 4: Context propagation")
 	fmt.Println("-------------------------------")
 	demonstrateContextPropagation(resolver)
+	fmt.Println()
+
+	// Example 5: One scope per HTTP request
+	fmt.Println("Example 5: Request scope middleware")
+	fmt.Println("-----------------------------------")
+	demonstrateRequestScope(resolver)
 }
 
 func simulateHTTPRequest(resolver di.Resolver, requestID string) {
@@ -195,6 +204,22 @@ func demonstrateContextPropagation(resolver di.Resolver) {
 	fmt.Printf("  - Logger has access to the same context\n")
 }
 
+func demonstrateRequestScope(resolver di.Resolver) {
+	handler := requestscope.Middleware(resolver, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), requestIDKey, r.Header.Get("X-Request-ID"))
+		service := di.FromContext[*UserService](ctx)
+		repo := di.FromContext[*Repository](ctx)
+
+		fmt.Printf("Service and handler share the scoped Repository: %v\n", service.Repo == repo)
+	}))
+
+	for _, requestID := range []string{"req-scope-001", "req-scope-002"} {
+		request := httptest.NewRequest(http.MethodGet, "/users", nil)
+		request.Header.Set("X-Request-ID", requestID)
+		handler.ServeHTTP(httptest.NewRecorder(), request)
+	}
+}
+
 // Helper types for examples
 type SlowService struct{}
 type CancellableService struct{}
@@ -0,0 +1,72 @@
+// Package requestscope opens a dependency injection scope per request for
+// net/http servers and gRPC servers, so scoped providers live as long as the
+// request that resolved them.
+//
+// Basic usage:
+//
+//	handler := requestscope.Middleware(container.Resolver(), logger)(mux)
+//
+//	func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+//	    service := di.FromContext[*UserService](r.Context())
+//	}
+package requestscope
+
+import (
+	"context"
+	"net/http"
+
+	"google.golang.org/grpc"
+
+	di "github.com/janmbaco/go-infrastructure/v2/dependencyinjection"
+	"github.com/janmbaco/go-infrastructure/v2/logs"
+)
+
+type scopedServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the context that carries the request scope
+func (s *scopedServerStream) Context() context.Context {
+	return s.ctx
+}
+
+// Middleware returns a net/http middleware that opens a scope for each request,
+// stores it in the request context and closes it when the request ends.
+// Errors closing the scope are sent to errorLogger when it is not nil.
+func Middleware(resolver di.Resolver, errorLogger logs.ErrorLogger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, closeScope := open(r.Context(), resolver, errorLogger)
+			defer closeScope()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// UnaryServerInterceptor returns a gRPC unary interceptor that opens a scope for each call
+func UnaryServerInterceptor(resolver di.Resolver, errorLogger logs.ErrorLogger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, closeScope := open(ctx, resolver, errorLogger)
+		defer closeScope()
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a gRPC stream interceptor that opens a scope for each stream
+func StreamServerInterceptor(resolver di.Resolver, errorLogger logs.ErrorLogger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, closeScope := open(stream.Context(), resolver, errorLogger)
+		defer closeScope()
+		return handler(srv, &scopedServerStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+func open(ctx context.Context, resolver di.Resolver, errorLogger logs.ErrorLogger) (context.Context, func()) {
+	scope := di.NewScope(resolver)
+	return di.WithScope(ctx, scope), func() {
+		if err := scope.Close(); err != nil && errorLogger != nil {
+			errorLogger.TryError(err)
+		}
+	}
+}
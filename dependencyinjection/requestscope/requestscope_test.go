@@ -0,0 +1,109 @@
+package requestscope
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	di "github.com/janmbaco/go-infrastructure/v2/dependencyinjection"
+)
+
+type requestState struct {
+	closed bool
+}
+
+func (s *requestState) Close() error {
+	s.closed = true
+	return nil
+}
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *fakeServerStream) Context() context.Context { return s.ctx }
+
+func newTestResolver() di.Resolver {
+	return di.NewBuilder().
+		Register(func(r di.Register) {
+			r.AsScope(new(*requestState), func() *requestState { return &requestState{} }, nil)
+		}).
+		MustBuild().
+		Resolver()
+}
+
+func TestMiddleware_WhenRequestEnds_ThenClosesScopedInstances(t *testing.T) {
+	// Arrange
+	var first, second *requestState
+	handler := Middleware(newTestResolver(), nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		first = di.FromContext[*requestState](r.Context())
+		second = di.FromContext[*requestState](r.Context())
+		assert.False(t, first.closed)
+	}))
+
+	// Act
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	// Assert
+	require.NotNil(t, first)
+	assert.Same(t, first, second)
+	assert.True(t, first.closed)
+}
+
+func TestMiddleware_WhenTwoRequests_ThenEachGetsItsOwnScope(t *testing.T) {
+	// Arrange
+	states := make([]*requestState, 0, 2)
+	handler := Middleware(newTestResolver(), nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		states = append(states, di.FromContext[*requestState](r.Context()))
+	}))
+
+	// Act
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	// Assert
+	require.Len(t, states, 2)
+	assert.NotSame(t, states[0], states[1])
+}
+
+func TestUnaryServerInterceptor_WhenCallEnds_ThenClosesScopedInstances(t *testing.T) {
+	// Arrange
+	var state *requestState
+	interceptor := UnaryServerInterceptor(newTestResolver(), nil)
+
+	// Act
+	result, err := interceptor(context.Background(), "request", &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		state = di.FromContext[*requestState](ctx)
+		return "response", nil
+	})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "response", result)
+	require.NotNil(t, state)
+	assert.True(t, state.closed)
+}
+
+func TestStreamServerInterceptor_WhenStreamEnds_ThenClosesScopedInstances(t *testing.T) {
+	// Arrange
+	var state *requestState
+	interceptor := StreamServerInterceptor(newTestResolver(), nil)
+	stream := &fakeServerStream{ctx: context.Background()}
+
+	// Act
+	err := interceptor(nil, stream, &grpc.StreamServerInfo{}, func(srv interface{}, ss grpc.ServerStream) error {
+		state = di.FromContext[*requestState](ss.Context())
+		return nil
+	})
+
+	// Assert
+	require.NoError(t, err)
+	require.NotNil(t, state)
+	assert.True(t, state.closed)
+}
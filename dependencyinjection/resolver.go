@@ -45,3 +45,8 @@ func (r *resolver) TenantCtx(ctx context.Context, tenant string, iface interface
 		Iface:  reflect.Indirect(reflect.ValueOf(iface)).Type(),
 	}).Create(ctx, params, r.dependencies, make(map[DependencyObject]interface{}))
 }
+
+// NewScope opens a scope that keeps scoped instances alive until it is closed
+func (r *resolver) NewScope() Scope {
+	return newScope(r.dependencies)
+}
@@ -0,0 +1,129 @@
+package dependencyinjection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+)
+
+// Scope defines a Resolver that keeps its scoped instances alive until it is closed.
+// Instances registered with AsScope are created once per Scope and, when they implement
+// io.Closer, they are closed in reverse creation order when the Scope is closed.
+// Resolutions on the same Scope are serialized, so providers must not resolve from it.
+type Scope interface {
+	Resolver
+	io.Closer
+}
+
+// ScopeFactory defines an object responsible to open new scopes
+type ScopeFactory interface {
+	NewScope() Scope
+}
+
+type scopeContextKey struct{}
+
+type scope struct {
+	dependencies Dependencies
+	objects      map[DependencyObject]interface{}
+	created      []interface{}
+	mutex        sync.Mutex
+	closed       bool
+}
+
+// NewScope opens a new scope from the resolver.
+// It panics if the resolver was not created by this package.
+func NewScope(resolver Resolver) Scope {
+	factory, ok := resolver.(ScopeFactory)
+	if !ok {
+		panic(fmt.Errorf("%T is not able to open scopes", resolver))
+	}
+	return factory.NewScope()
+}
+
+func newScope(dependencies Dependencies) Scope {
+	return &scope{dependencies: dependencies, objects: make(map[DependencyObject]interface{})}
+}
+
+// Type gets a dependency by the interface and params within the scope
+func (s *scope) Type(iface interface{}, params map[string]interface{}) interface{} {
+	return s.TypeCtx(context.Background(), iface, params)
+}
+
+// Tenant gets a dependency by the interface, the tenant key and params within the scope
+func (s *scope) Tenant(tenant string, iface interface{}, params map[string]interface{}) interface{} {
+	return s.TenantCtx(context.Background(), tenant, iface, params)
+}
+
+// TypeCtx resolves with context within the scope
+func (s *scope) TypeCtx(ctx context.Context, iface interface{}, params map[string]interface{}) interface{} {
+	return s.create(ctx, DependencyKey{Iface: reflect.Indirect(reflect.ValueOf(iface)).Type()}, params)
+}
+
+// TenantCtx resolves with context within the scope
+func (s *scope) TenantCtx(ctx context.Context, tenant string, iface interface{}, params map[string]interface{}) interface{} {
+	return s.create(ctx, DependencyKey{
+		Tenant: tenant,
+		Iface:  reflect.Indirect(reflect.ValueOf(iface)).Type(),
+	}, params)
+}
+
+// NewScope opens a child scope that shares singletons but not scoped instances
+func (s *scope) NewScope() Scope {
+	return newScope(s.dependencies)
+}
+
+// Close closes the scoped instances that implement io.Closer in reverse creation order
+func (s *scope) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	var errs []error
+	for i := len(s.created) - 1; i >= 0; i-- {
+		if closer, ok := s.created[i].(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	s.created = nil
+	s.objects = nil
+	return errors.Join(errs...)
+}
+
+func (s *scope) create(ctx context.Context, key DependencyKey, params map[string]interface{}) interface{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closed {
+		panic(fmt.Errorf("%v can not be resolved from a closed scope", key.Iface.Name()))
+	}
+	return createDependency(ctx, s.dependencies.Get(key), params, s.dependencies, s.objects, &s.created)
+}
+
+// WithScope returns a copy of ctx that carries the scope
+func WithScope(ctx context.Context, scope Scope) context.Context {
+	return context.WithValue(ctx, scopeContextKey{}, scope)
+}
+
+// ScopeFromContext gets the scope carried by ctx
+func ScopeFromContext(ctx context.Context) (Scope, bool) {
+	scope, ok := ctx.Value(scopeContextKey{}).(Scope)
+	return scope, ok
+}
+
+// FromContext resolves a dependency from the scope carried by ctx.
+// It panics if ctx does not carry a scope.
+func FromContext[T any](ctx context.Context) T {
+	scope, ok := ScopeFromContext(ctx)
+	if !ok {
+		var instance T
+		panic("no scope in context to resolve " + reflect.TypeOf(&instance).Elem().String())
+	}
+	return ResolveCtx[T](ctx, scope)
+}
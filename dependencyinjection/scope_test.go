@@ -0,0 +1,219 @@
+package dependencyinjection
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type scopedResource struct {
+	name   string
+	closed *[]string
+	err    error
+}
+
+func (r *scopedResource) Close() error {
+	*r.closed = append(*r.closed, r.name)
+	return r.err
+}
+
+type scopedConsumer struct {
+	resource *scopedResource
+	closed   *[]string
+}
+
+func (c *scopedConsumer) Close() error {
+	*c.closed = append(*c.closed, "consumer")
+	return nil
+}
+
+// contextKeeper keeps the context its provider received
+type contextKeeper struct {
+	ctx context.Context
+}
+
+func TestScope_Type_WhenResolvedTwice_ThenReusesScopedInstance(t *testing.T) {
+	// Arrange
+	container := NewContainer()
+	closed := make([]string, 0)
+	container.Register().AsScope(new(*scopedResource), func() *scopedResource {
+		return &scopedResource{name: "resource", closed: &closed}
+	}, nil)
+	scope := NewScope(container.Resolver())
+
+	// Act
+	first := scope.Type(new(*scopedResource), nil)
+	second := scope.Type(new(*scopedResource), nil)
+
+	// Assert
+	assert.Same(t, first, second)
+}
+
+func TestScope_Type_WhenDifferentScopes_ThenCreatesDifferentInstances(t *testing.T) {
+	// Arrange
+	container := NewContainer()
+	closed := make([]string, 0)
+	container.Register().AsScope(new(*scopedResource), func() *scopedResource {
+		return &scopedResource{name: "resource", closed: &closed}
+	}, nil)
+
+	// Act
+	first := NewScope(container.Resolver()).Type(new(*scopedResource), nil)
+	second := NewScope(container.Resolver()).Type(new(*scopedResource), nil)
+
+	// Assert
+	assert.NotSame(t, first, second)
+}
+
+func TestScope_Close_WhenScopedInstancesAreClosers_ThenClosesInReverseCreationOrder(t *testing.T) {
+	// Arrange
+	container := NewContainer()
+	closed := make([]string, 0)
+	container.Register().AsScope(new(*scopedResource), func() *scopedResource {
+		return &scopedResource{name: "resource", closed: &closed}
+	}, nil)
+	container.Register().AsScope(new(*scopedConsumer), func(resource *scopedResource) *scopedConsumer {
+		return &scopedConsumer{resource: resource, closed: &closed}
+	}, nil)
+	scope := NewScope(container.Resolver())
+	scope.Type(new(*scopedConsumer), nil)
+
+	// Act
+	err := scope.Close()
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []string{"consumer", "resource"}, closed)
+}
+
+func TestScope_Type_WhenScopedInstancesAreCreated_ThenKeepsOnlyThemAmongItsObjects(t *testing.T) {
+	// Arrange
+	container := NewContainer()
+	closed := make([]string, 0)
+	container.Register().AsScope(new(*scopedResource), func() *scopedResource {
+		return &scopedResource{name: "resource", closed: &closed}
+	}, nil)
+	container.Register().AsScope(new(*scopedConsumer), func(resource *scopedResource) *scopedConsumer {
+		return &scopedConsumer{resource: resource, closed: &closed}
+	}, nil)
+	scope := NewScope(container.Resolver()).(*scope)
+
+	// Act
+	consumer := scope.Type(new(*scopedConsumer), nil).(*scopedConsumer)
+
+	// Assert
+	assert.Len(t, scope.objects, 2)
+	assert.Equal(t, []interface{}{consumer.resource, consumer}, scope.created)
+}
+
+func TestScope_Close_WhenProviderContextResolvesFromTheRootResolver_ThenDoesNotCloseThoseInstances(t *testing.T) {
+	// Arrange
+	container := NewContainer()
+	closed := make([]string, 0)
+	container.Register().AsScope(new(*scopedResource), func() *scopedResource {
+		return &scopedResource{name: "resource", closed: &closed}
+	}, nil)
+	container.Register().AsScope(new(*contextKeeper), func(ctx context.Context) *contextKeeper {
+		return &contextKeeper{ctx: ctx}
+	}, nil)
+	scope := NewScope(container.Resolver()).(*scope)
+	keeper := scope.Type(new(*contextKeeper), nil).(*contextKeeper)
+	container.Resolver().TypeCtx(keeper.ctx, new(*scopedResource), nil)
+
+	// Act
+	created := append([]interface{}{}, scope.created...)
+	err := scope.Close()
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{keeper}, created)
+	assert.Empty(t, closed)
+}
+
+func TestScope_Close_WhenInstanceIsTransient_ThenDoesNotCloseIt(t *testing.T) {
+	// Arrange
+	container := NewContainer()
+	closed := make([]string, 0)
+	container.Register().AsType(new(*scopedResource), func() *scopedResource {
+		return &scopedResource{name: "resource", closed: &closed}
+	}, nil)
+	scope := NewScope(container.Resolver())
+	scope.Type(new(*scopedResource), nil)
+
+	// Act
+	err := scope.Close()
+
+	// Assert
+	require.NoError(t, err)
+	assert.Empty(t, closed)
+}
+
+func TestScope_Close_WhenCloserFails_ThenReturnsError(t *testing.T) {
+	// Arrange
+	container := NewContainer()
+	closed := make([]string, 0)
+	closeErr := errors.New("close failed")
+	container.Register().AsScope(new(*scopedResource), func() *scopedResource {
+		return &scopedResource{name: "resource", closed: &closed, err: closeErr}
+	}, nil)
+	scope := NewScope(container.Resolver())
+	scope.Type(new(*scopedResource), nil)
+
+	// Act
+	err := scope.Close()
+
+	// Assert
+	assert.ErrorIs(t, err, closeErr)
+}
+
+func TestScope_Type_WhenClosed_ThenPanics(t *testing.T) {
+	// Arrange
+	container := NewContainer()
+	container.Register().AsType(new(string), func() string { return "value" }, nil)
+	scope := NewScope(container.Resolver())
+	require.NoError(t, scope.Close())
+
+	// Act & Assert
+	assert.Panics(t, func() {
+		scope.Type(new(string), nil)
+	})
+}
+
+func TestNewScope_WhenResolverIsForeign_ThenPanics(t *testing.T) {
+	// Arrange
+	var foreign struct{ Resolver }
+
+	// Act & Assert
+	assert.Panics(t, func() {
+		NewScope(foreign)
+	})
+}
+
+func TestFromContext_WhenContextCarriesScope_ThenResolvesFromScope(t *testing.T) {
+	// Arrange
+	container := NewContainer()
+	closed := make([]string, 0)
+	container.Register().AsScope(new(*scopedResource), func() *scopedResource {
+		return &scopedResource{name: "resource", closed: &closed}
+	}, nil)
+	scope := NewScope(container.Resolver())
+	ctx := WithScope(context.Background(), scope)
+
+	// Act
+	first := FromContext[*scopedResource](ctx)
+	second := FromContext[*scopedResource](ctx)
+
+	// Assert
+	assert.NotNil(t, first)
+	assert.Same(t, first, second)
+}
+
+func TestFromContext_WhenContextHasNoScope_ThenPanics(t *testing.T) {
+	// Act & Assert
+	assert.Panics(t, func() {
+		FromContext[*scopedResource](context.Background())
+	})
+}
@@ -48,6 +48,11 @@ func (f *fileChangedNotifier) Subscribe(subscribeFunc func()) error {
 	return nil
 }
 
+// Close stops watching the file, the subscribed functions are no longer called
+func (f *fileChangedNotifier) Close() error {
+	return f.watcher.Close()
+}
+
 func (f *fileChangedNotifier) watchFile() {
 	for evt := range f.watcher.Events {
 		if evt.Op == fsnotify.Write {
@@ -0,0 +1,121 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ContentType is the content type of the Prometheus text format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var (
+	helpEscaper       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+// Handler returns the handler that writes the metrics in the Prometheus text format
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", ContentType)
+		w.Header().Set("Cache-Control", "no-store")
+		if req.Method == http.MethodGet {
+			_ = r.Write(w) //nolint:errcheck // the client is gone when writing fails
+		}
+	})
+}
+
+// Write writes the metrics in the Prometheus text format, sorted by name and label values
+func (r *Registry) Write(w io.Writer) error {
+	r.mutex.RLock()
+	metrics := make([]*metric, 0, len(r.metrics))
+	for _, m := range r.metrics {
+		metrics = append(metrics, m)
+	}
+	r.mutex.RUnlock()
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].name < metrics[j].name })
+
+	buffered := bufio.NewWriter(w)
+	for _, m := range metrics {
+		m.write(buffered)
+	}
+	return buffered.Flush()
+}
+
+func (m *metric) write(w *bufio.Writer) {
+	m.mutex.RLock()
+	all := make([]*series, 0, len(m.series))
+	for _, s := range m.series {
+		all = append(all, s)
+	}
+	m.mutex.RUnlock()
+	sort.Slice(all, func(i, j int) bool {
+		return strings.Join(all[i].labelValues, "\xff") < strings.Join(all[j].labelValues, "\xff")
+	})
+
+	if m.help != "" {
+		_, _ = w.WriteString("# HELP " + m.name + " " + helpEscaper.Replace(m.help) + "\n")
+	}
+	_, _ = w.WriteString("# TYPE " + m.name + " " + m.kind.String() + "\n")
+	for _, s := range all {
+		labels := m.labels(s.labelValues)
+		if m.kind != histogramKind {
+			writeSample(w, m.name, labels, "", formatFloat(math.Float64frombits(s.value.Load())))
+			continue
+		}
+		s.mutex.Lock()
+		counts, sum, count := append([]uint64(nil), s.counts...), s.sum, s.count
+		s.mutex.Unlock()
+		for i, bound := range m.buckets {
+			writeSample(w, m.name+"_bucket", labels, `le="`+formatFloat(bound)+`"`, strconv.FormatUint(counts[i], 10))
+		}
+		writeSample(w, m.name+"_bucket", labels, `le="+Inf"`, strconv.FormatUint(count, 10))
+		writeSample(w, m.name+"_sum", labels, "", formatFloat(sum))
+		writeSample(w, m.name+"_count", labels, "", strconv.FormatUint(count, 10))
+	}
+}
+
+// labels returns the label pairs of labelValues, like method="GET",code="200"
+func (m *metric) labels(labelValues []string) string {
+	pairs := make([]string, len(labelValues))
+	for i, value := range labelValues {
+		pairs[i] = m.labelNames[i] + `="` + labelValueEscaper.Replace(value) + `"`
+	}
+	return strings.Join(pairs, ",")
+}
+
+func writeSample(w *bufio.Writer, name, labels, extra, value string) {
+	_, _ = w.WriteString(name)
+	if extra != "" {
+		if labels != "" {
+			labels += ","
+		}
+		labels += extra
+	}
+	if labels != "" {
+		_, _ = w.WriteString("{" + labels + "}")
+	}
+	_, _ = w.WriteString(" " + value + "\n")
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
@@ -0,0 +1,21 @@
+package ioc
+
+import (
+	"github.com/janmbaco/go-infrastructure/v2/dependencyinjection"
+	"github.com/janmbaco/go-infrastructure/v2/metrics"
+)
+
+// MetricsModule implements Module for metrics services
+type MetricsModule struct{}
+
+// NewMetricsModule creates a new metrics module
+func NewMetricsModule() *MetricsModule {
+	return &MetricsModule{}
+}
+
+// RegisterServices registers all metrics services
+func (m *MetricsModule) RegisterServices(register dependencyinjection.Register) error {
+	dependencyinjection.RegisterSingleton[*metrics.Registry](register, metrics.NewRegistry)
+
+	return nil
+}
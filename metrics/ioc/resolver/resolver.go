@@ -0,0 +1,14 @@
+package resolver
+
+import (
+	"github.com/janmbaco/go-infrastructure/v2/dependencyinjection"
+	"github.com/janmbaco/go-infrastructure/v2/metrics"
+)
+
+func GetRegistry(resolver dependencyinjection.Resolver) *metrics.Registry {
+	result := resolver.Type(new(*metrics.Registry), nil)
+	if registry, ok := result.(*metrics.Registry); ok {
+		return registry
+	}
+	panic("failed to resolve metrics.Registry")
+}
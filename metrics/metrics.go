@@ -0,0 +1,272 @@
+// Package metrics provides counters, gauges and histograms with labels, without dependencies,
+// exposed in the Prometheus text format.
+//
+// Metrics are created in a Registry, which returns the same metric when it is asked again
+// for a name with the same kind and labels, so every component can declare the metrics it uses.
+//
+// Basic usage:
+//
+//	registry := metrics.NewRegistry()
+//	requests := registry.MustCounter("jobs_processed_total", "Processed jobs.", "queue", "result")
+//	requests.Inc("emails", "ok")
+//
+//	mux.Handle("/metrics", registry.Handler())
+package metrics
+
+import (
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	metricNameRegexp = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelNameRegexp  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// DefaultBuckets are the upper bounds of the histograms of durations in seconds, from 5ms to 10s
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type (
+	// Registry holds the metrics of the process by name
+	Registry struct {
+		mutex   sync.RWMutex
+		metrics map[string]*metric
+	}
+
+	// Counter is a value per label values that only increases
+	Counter struct{ metric *metric }
+
+	// Gauge is a value per label values that can go up and down
+	Gauge struct{ metric *metric }
+
+	// Histogram counts the observations per label values in buckets of upper bounds
+	Histogram struct{ metric *metric }
+
+	kind uint8
+
+	metric struct {
+		name       string
+		help       string
+		kind       kind
+		labelNames []string
+		buckets    []float64
+		mutex      sync.RWMutex
+		series     map[string]*series
+	}
+
+	series struct {
+		labelValues []string
+		// value holds the float64 bits of counters and gauges
+		value atomic.Uint64
+		// counts, sum and count are the observations of histograms
+		mutex  sync.Mutex
+		counts []uint64
+		sum    float64
+		count  uint64
+	}
+)
+
+const (
+	counterKind kind = iota
+	gaugeKind
+	histogramKind
+)
+
+func (k kind) String() string {
+	switch k {
+	case counterKind:
+		return "counter"
+	case gaugeKind:
+		return "gauge"
+	default:
+		return "histogram"
+	}
+}
+
+// NewRegistry returns an empty Registry
+func NewRegistry() *Registry {
+	return &Registry{metrics: make(map[string]*metric)}
+}
+
+// Counter returns the counter name with labelNames, creating it when it does not exist
+func (r *Registry) Counter(name, help string, labelNames ...string) (*Counter, error) {
+	m, err := r.register(name, help, counterKind, nil, labelNames)
+	if err != nil {
+		return nil, err
+	}
+	return &Counter{metric: m}, nil
+}
+
+// Gauge returns the gauge name with labelNames, creating it when it does not exist
+func (r *Registry) Gauge(name, help string, labelNames ...string) (*Gauge, error) {
+	m, err := r.register(name, help, gaugeKind, nil, labelNames)
+	if err != nil {
+		return nil, err
+	}
+	return &Gauge{metric: m}, nil
+}
+
+// Histogram returns the histogram name with buckets and labelNames, creating it when it does not exist.
+// DefaultBuckets are used when buckets is empty
+func (r *Registry) Histogram(name, help string, buckets []float64, labelNames ...string) (*Histogram, error) {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	m, err := r.register(name, help, histogramKind, buckets, labelNames)
+	if err != nil {
+		return nil, err
+	}
+	return &Histogram{metric: m}, nil
+}
+
+// MustCounter is Counter that panics when the counter can not be registered
+func (r *Registry) MustCounter(name, help string, labelNames ...string) *Counter {
+	counter, err := r.Counter(name, help, labelNames...)
+	if err != nil {
+		panic(err)
+	}
+	return counter
+}
+
+// MustGauge is Gauge that panics when the gauge can not be registered
+func (r *Registry) MustGauge(name, help string, labelNames ...string) *Gauge {
+	gauge, err := r.Gauge(name, help, labelNames...)
+	if err != nil {
+		panic(err)
+	}
+	return gauge
+}
+
+// MustHistogram is Histogram that panics when the histogram can not be registered
+func (r *Registry) MustHistogram(name, help string, buckets []float64, labelNames ...string) *Histogram {
+	histogram, err := r.Histogram(name, help, buckets, labelNames...)
+	if err != nil {
+		panic(err)
+	}
+	return histogram
+}
+
+func (r *Registry) register(name, help string, kind kind, buckets []float64, labelNames []string) (*metric, error) {
+	if !metricNameRegexp.MatchString(name) {
+		return nil, newMetricsError(InvalidMetricName, "the metric name `"+name+"` is not valid", nil)
+	}
+	for _, labelName := range labelNames {
+		if !labelNameRegexp.MatchString(labelName) || strings.HasPrefix(labelName, "__") || (kind == histogramKind && labelName == "le") {
+			return nil, newMetricsError(InvalidLabelName, "the label name `"+labelName+"` of `"+name+"` is not valid", nil)
+		}
+	}
+	if kind == histogramKind && !sort.Float64sAreSorted(buckets) {
+		return nil, newMetricsError(InvalidBuckets, "the buckets of `"+name+"` are not sorted", nil)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if existing, ok := r.metrics[name]; ok {
+		if existing.kind != kind || !slices.Equal(existing.labelNames, labelNames) || !slices.Equal(existing.buckets, buckets) {
+			return nil, newMetricsError(ConflictingMetric, "the metric `"+name+"` is already registered as a "+existing.kind.String()+" with other labels or buckets", nil)
+		}
+		return existing, nil
+	}
+	m := &metric{
+		name:       name,
+		help:       help,
+		kind:       kind,
+		labelNames: append([]string(nil), labelNames...),
+		buckets:    append([]float64(nil), buckets...),
+		series:     make(map[string]*series),
+	}
+	if len(labelNames) == 0 {
+		// metrics without labels are exposed from the start
+		m.get(nil)
+	}
+	r.metrics[name] = m
+	return m, nil
+}
+
+// Inc adds 1 to the counter of labelValues
+func (c *Counter) Inc(labelValues ...string) {
+	c.metric.get(labelValues).add(1)
+}
+
+// Add adds delta to the counter of labelValues; negative deltas are ignored because counters only increase
+func (c *Counter) Add(delta float64, labelValues ...string) {
+	if delta > 0 {
+		c.metric.get(labelValues).add(delta)
+	}
+}
+
+// Set sets the gauge of labelValues to value
+func (g *Gauge) Set(value float64, labelValues ...string) {
+	g.metric.get(labelValues).value.Store(math.Float64bits(value))
+}
+
+// Add adds delta, which may be negative, to the gauge of labelValues
+func (g *Gauge) Add(delta float64, labelValues ...string) {
+	g.metric.get(labelValues).add(delta)
+}
+
+// Inc adds 1 to the gauge of labelValues
+func (g *Gauge) Inc(labelValues ...string) {
+	g.Add(1, labelValues...)
+}
+
+// Dec subtracts 1 from the gauge of labelValues
+func (g *Gauge) Dec(labelValues ...string) {
+	g.Add(-1, labelValues...)
+}
+
+// Observe adds value to the histogram of labelValues
+func (h *Histogram) Observe(value float64, labelValues ...string) {
+	s := h.metric.get(labelValues)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for i, bound := range h.metric.buckets {
+		if value <= bound {
+			s.counts[i]++
+		}
+	}
+	s.sum += value
+	s.count++
+}
+
+// get returns the series of labelValues, creating it when it does not exist.
+// It panics when the number of label values does not match the label names, like an index out of range.
+func (m *metric) get(labelValues []string) *series {
+	if len(labelValues) != len(m.labelNames) {
+		panic(newMetricsError(LabelValuesMismatch, "the metric `"+m.name+"` has labels "+strings.Join(m.labelNames, ", ")+" but got "+strings.Join(labelValues, ", "), nil))
+	}
+	key := strings.Join(labelValues, "\xff")
+	m.mutex.RLock()
+	s, ok := m.series[key]
+	m.mutex.RUnlock()
+	if ok {
+		return s
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if s, ok := m.series[key]; ok {
+		return s
+	}
+	s = &series{labelValues: append([]string(nil), labelValues...)}
+	if m.kind == histogramKind {
+		s.counts = make([]uint64, len(m.buckets))
+	}
+	m.series[key] = s
+	return s
+}
+
+func (s *series) add(delta float64) {
+	for {
+		current := s.value.Load()
+		next := math.Float64bits(math.Float64frombits(current) + delta)
+		if s.value.CompareAndSwap(current, next) {
+			return
+		}
+	}
+}
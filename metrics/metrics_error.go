@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"github.com/janmbaco/go-infrastructure/v2/errors"
+)
+
+// MetricsError is the errors of Registry and of the metrics
+type MetricsError interface {
+	errors.CustomError
+	GetErrorType() MetricsErrorType
+}
+
+type metricsError struct {
+	errors.CustomizableError
+	ErrorType MetricsErrorType
+}
+
+func newMetricsError(errorType MetricsErrorType, message string, internalError error) MetricsError {
+	return &metricsError{
+		CustomizableError: errors.CustomizableError{
+			Message:       message,
+			InternalError: internalError,
+		},
+		ErrorType: errorType,
+	}
+}
+
+func (e *metricsError) GetErrorType() MetricsErrorType {
+	return e.ErrorType
+}
+
+type MetricsErrorType uint8
+
+const (
+	UnexpectedError MetricsErrorType = iota
+	InvalidMetricName
+	InvalidLabelName
+	InvalidBuckets
+	ConflictingMetric
+	LabelValuesMismatch
+)
@@ -0,0 +1,171 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func exposition(t *testing.T, registry *Registry) string {
+	var out strings.Builder
+	require.NoError(t, registry.Write(&out))
+	return out.String()
+}
+
+func TestRegistry_Write_WhenCountersAndGaugesHaveValues_ThenWritesTextFormat(t *testing.T) {
+	// Arrange
+	registry := NewRegistry()
+	requests := registry.MustCounter("requests_total", "Requests served.", "method", "code")
+	temperature := registry.MustGauge("temperature_celsius", "Current temperature.\nIn celsius.")
+	requests.Inc("GET", "200")
+	requests.Add(2, "GET", "200")
+	requests.Add(-5, "GET", "200")
+	requests.Inc("POST", "500")
+	temperature.Set(21.5)
+	temperature.Dec()
+
+	// Act
+	out := exposition(t, registry)
+
+	// Assert
+	assert.Equal(t, `# HELP requests_total Requests served.
+# TYPE requests_total counter
+requests_total{method="GET",code="200"} 3
+requests_total{method="POST",code="500"} 1
+# HELP temperature_celsius Current temperature.\nIn celsius.
+# TYPE temperature_celsius gauge
+temperature_celsius 20.5
+`, out)
+}
+
+func TestRegistry_Write_WhenHistogramHasObservations_ThenWritesCumulativeBuckets(t *testing.T) {
+	// Arrange
+	registry := NewRegistry()
+	durations := registry.MustHistogram("duration_seconds", "", []float64{0.1, 1}, "route")
+	durations.Observe(0.05, "/a")
+	durations.Observe(0.5, "/a")
+	durations.Observe(3, "/a")
+
+	// Act
+	out := exposition(t, registry)
+
+	// Assert
+	assert.Equal(t, `# TYPE duration_seconds histogram
+duration_seconds_bucket{route="/a",le="0.1"} 1
+duration_seconds_bucket{route="/a",le="1"} 2
+duration_seconds_bucket{route="/a",le="+Inf"} 3
+duration_seconds_sum{route="/a"} 3.55
+duration_seconds_count{route="/a"} 3
+`, out)
+}
+
+func TestRegistry_Write_WhenLabelValuesHaveSpecialCharacters_ThenEscapesThem(t *testing.T) {
+	// Arrange
+	registry := NewRegistry()
+	registry.MustCounter("errors_total", "", "message").Inc("say \"hi\"\\\n")
+
+	// Act
+	out := exposition(t, registry)
+
+	// Assert
+	assert.Contains(t, out, `errors_total{message="say \"hi\"\\\n"} 1`)
+}
+
+func TestRegistry_Counter_WhenAlreadyRegistered_ThenReturnsTheSameMetric(t *testing.T) {
+	// Arrange
+	registry := NewRegistry()
+	first := registry.MustCounter("jobs_total", "Jobs.", "queue")
+
+	// Act
+	second, err := registry.Counter("jobs_total", "Jobs.", "queue")
+	first.Inc("emails")
+	second.Inc("emails")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Contains(t, exposition(t, registry), `jobs_total{queue="emails"} 2`)
+}
+
+func TestRegistry_WhenMetricIsInvalid_ThenReturnsError(t *testing.T) {
+	// Arrange
+	registry := NewRegistry()
+	registry.MustCounter("jobs_total", "", "queue")
+
+	cases := []struct {
+		register func() error
+		expected MetricsErrorType
+	}{
+		{func() error { _, err := registry.Counter("jobs-total", ""); return err }, InvalidMetricName},
+		{func() error { _, err := registry.Gauge("queue_size", "", "queue name"); return err }, InvalidLabelName},
+		{func() error { _, err := registry.Gauge("queue_size", "", "__name"); return err }, InvalidLabelName},
+		{func() error { _, err := registry.Histogram("latency_seconds", "", nil, "le"); return err }, InvalidLabelName},
+		{func() error { _, err := registry.Histogram("latency_seconds", "", []float64{1, 0.5}); return err }, InvalidBuckets},
+		{func() error { _, err := registry.Gauge("jobs_total", "", "queue"); return err }, ConflictingMetric},
+		{func() error { _, err := registry.Counter("jobs_total", "", "queue", "result"); return err }, ConflictingMetric},
+	}
+
+	for _, c := range cases {
+		// Act
+		err := c.register()
+
+		// Assert
+		var metricsErr MetricsError
+		require.ErrorAs(t, err, &metricsErr)
+		assert.Equal(t, c.expected, metricsErr.GetErrorType(), err.Error())
+	}
+}
+
+func TestCounter_Inc_WhenLabelValuesDoNotMatch_ThenPanics(t *testing.T) {
+	// Arrange
+	counter := NewRegistry().MustCounter("jobs_total", "", "queue", "result")
+
+	// Act & Assert
+	assert.Panics(t, func() { counter.Inc("emails") })
+}
+
+func TestRegistry_WhenUsedConcurrently_ThenCountsEveryUpdate(t *testing.T) {
+	// Arrange
+	registry := NewRegistry()
+	counter := registry.MustCounter("hits_total", "", "worker")
+	histogram := registry.MustHistogram("work_seconds", "", nil)
+	var wg sync.WaitGroup
+
+	// Act
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				counter.Inc("shared")
+				histogram.Observe(0.01)
+				_ = registry.Write(&strings.Builder{})
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Assert
+	out := exposition(t, registry)
+	assert.Contains(t, out, `hits_total{worker="shared"} 8000`)
+	assert.Contains(t, out, "work_seconds_count 8000")
+}
+
+func TestRegistry_Handler_WhenRequested_ThenServesTextFormat(t *testing.T) {
+	// Arrange
+	registry := NewRegistry()
+	registry.MustGauge("up", "").Set(1)
+	w := httptest.NewRecorder()
+
+	// Act
+	registry.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, ContentType, w.Header().Get("Content-Type"))
+	assert.Equal(t, "# TYPE up gauge\nup 1\n", w.Body.String())
+}
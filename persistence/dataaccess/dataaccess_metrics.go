@@ -0,0 +1,61 @@
+package dataaccess //nolint:revive // established package name, changing would break API
+
+import (
+	"time"
+
+	"github.com/janmbaco/go-infrastructure/v2/metrics"
+)
+
+type instrumentedDataAccess struct {
+	DataAccess
+	model     string
+	durations *metrics.Histogram
+}
+
+// Instrument returns dataAccess recording the duration in seconds of its operations in registry, as
+// dataaccess_operation_duration_seconds by model, operation (insert, select, update or delete) and result (ok or error)
+func Instrument(dataAccess DataAccess, registry *metrics.Registry, model string) DataAccess {
+	return &instrumentedDataAccess{
+		DataAccess: dataAccess,
+		model:      model,
+		durations: registry.MustHistogram("dataaccess_operation_duration_seconds",
+			"Duration of the data access operations in seconds by model, operation and result.",
+			metrics.DefaultBuckets, "model", "operation", "result"),
+	}
+}
+
+func (i *instrumentedDataAccess) Insert(datarow interface{}) error {
+	start := time.Now()
+	err := i.DataAccess.Insert(datarow)
+	i.observe("insert", start, err)
+	return err
+}
+
+func (i *instrumentedDataAccess) Select(datafilter interface{}, preloads ...string) (interface{}, error) {
+	start := time.Now()
+	rows, err := i.DataAccess.Select(datafilter, preloads...)
+	i.observe("select", start, err)
+	return rows, err
+}
+
+func (i *instrumentedDataAccess) Update(datafilter, datarow interface{}) error {
+	start := time.Now()
+	err := i.DataAccess.Update(datafilter, datarow)
+	i.observe("update", start, err)
+	return err
+}
+
+func (i *instrumentedDataAccess) Delete(datafilter interface{}, associateds ...string) error {
+	start := time.Now()
+	err := i.DataAccess.Delete(datafilter, associateds...)
+	i.observe("delete", start, err)
+	return err
+}
+
+func (i *instrumentedDataAccess) observe(operation string, start time.Time, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	i.durations.Observe(time.Since(start).Seconds(), i.model, operation, result)
+}
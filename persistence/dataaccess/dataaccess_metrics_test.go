@@ -0,0 +1,35 @@
+package dataaccess
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/janmbaco/go-infrastructure/v2/metrics"
+)
+
+func TestInstrument_WhenOperationsRun_ThenRecordsTheirDurationByResult(t *testing.T) {
+	// Arrange
+	registry := metrics.NewRegistry()
+	modelType := reflect.TypeOf((*TestModel)(nil))
+	succeeding := Instrument(NewDataAccessWithInterface(NewMockDB(), modelType), registry, "test_model")
+	failing := Instrument(NewDataAccessWithInterface(&MockDB{shouldError: true, errorType: "create"}, modelType), registry, "test_model")
+
+	// Act
+	insertErr := succeeding.Insert(&TestModel{Name: "a"})
+	failedErr := failing.Insert(&TestModel{Name: "b"})
+	_, selectErr := succeeding.Select("invalid filter")
+
+	// Assert
+	require.NoError(t, insertErr)
+	require.Error(t, failedErr)
+	require.Error(t, selectErr)
+	var out strings.Builder
+	require.NoError(t, registry.Write(&out))
+	assert.Contains(t, out.String(), `dataaccess_operation_duration_seconds_count{model="test_model",operation="insert",result="ok"} 1`)
+	assert.Contains(t, out.String(), `dataaccess_operation_duration_seconds_count{model="test_model",operation="insert",result="error"} 1`)
+	assert.Contains(t, out.String(), `dataaccess_operation_duration_seconds_count{model="test_model",operation="select",result="error"} 1`)
+}
@@ -0,0 +1,330 @@
+// Package admin exposes a configuration.ConfigHandler over HTTP to view and change the configuration at runtime.
+//
+// The endpoint answers, relative to where it is mounted:
+//
+//	GET   /          the current configuration, with secrets redacted
+//	PUT   /          replaces the configuration
+//	PATCH /          merges a JSON merge patch (RFC 7396) into the configuration
+//	POST  /restore   restores the previous configuration
+//	POST  /freeze    holds back the changes to the config file and rejects PUT and PATCH
+//	POST  /unfreeze  applies the changes to the config file and accepts PUT and PATCH again
+//
+// Every request is authenticated with a bearer token or a verified client certificate.
+//
+// Basic usage:
+//
+//	endpoint, err := admin.NewConfigEndpoint(configHandler, logger, admin.Options{
+//		Token:     os.Getenv("ADMIN_TOKEN"),
+//		Validator: validator,
+//	})
+//	mux.Handle("/admin/config/", http.StripPrefix("/admin/config", endpoint))
+package admin
+
+import (
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/janmbaco/go-infrastructure/v2/configuration"
+	"github.com/janmbaco/go-infrastructure/v2/logs"
+	"github.com/janmbaco/go-infrastructure/v2/server"
+)
+
+// DefaultMaxBodyBytes is the size limit of the configurations sent to the endpoint
+const DefaultMaxBodyBytes = 1 << 20
+
+type (
+	// Options configures the authentication and validation of the endpoint
+	Options struct {
+		// Token is accepted as Authorization: Bearer <Token>
+		Token string
+		// ClientCertificate accepts the requests with a client certificate verified by the TLS server,
+		// which needs ClientAuth set to tls.RequireAndVerifyClientCert or tls.VerifyClientCertIfGiven
+		ClientCertificate bool
+		// ClientNames restricts the accepted client certificates to these common or DNS names; any when empty
+		ClientNames []string
+		// Validator is run on every new configuration before it is persisted, usually the ConfigValidatorFunc
+		// of the listener
+		Validator server.ConfigValidatorFunc
+		// SecretKeys are the parts of the keys whose values are redacted, DefaultSecretKeys when nil
+		SecretKeys []string
+		// MaxBodyBytes limits the size of the configurations, DefaultMaxBodyBytes when zero
+		MaxBodyBytes int64
+	}
+
+	// ValidationErrors is the body of the responses to rejected requests
+	ValidationErrors struct {
+		Errors []ValidationError `json:"errors"`
+	}
+
+	// ValidationError is a reason why a request was rejected
+	ValidationError struct {
+		Message string `json:"message"`
+	}
+
+	configEndpoint struct {
+		configHandler configuration.ConfigHandler
+		logger        logs.Logger
+		options       Options
+		secrets       secretKeys
+		mutex         sync.Mutex
+		frozen        bool
+	}
+)
+
+// NewConfigEndpoint returns the admin handler of configHandler; it fails when neither a token nor client certificates
+// are accepted
+func NewConfigEndpoint(configHandler configuration.ConfigHandler, logger logs.Logger, options Options) (http.Handler, error) {
+	if options.Token == "" && !options.ClientCertificate {
+		return nil, newAdminError(MissingAuthentication, "the admin endpoint needs a token or client certificates", nil)
+	}
+	if options.SecretKeys == nil {
+		options.SecretKeys = DefaultSecretKeys
+	}
+	if options.MaxBodyBytes == 0 {
+		options.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	return &configEndpoint{
+		configHandler: configHandler,
+		logger:        logger,
+		options:       options,
+		secrets:       newSecretKeys(options.SecretKeys),
+	}, nil
+}
+
+func (e *configEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	if !e.authenticated(r) {
+		if e.options.Token != "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+		}
+		writeErrors(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+
+	switch path := strings.TrimSuffix(r.URL.Path, "/"); path {
+	case "":
+		e.serveConfig(w, r)
+	case "/restore", "/freeze", "/unfreeze":
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeErrors(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		e.serveAction(w, path[1:], r)
+	default:
+		writeErrors(w, http.StatusNotFound, "not found")
+	}
+}
+
+func (e *configEndpoint) authenticated(r *http.Request) bool {
+	if e.options.Token != "" {
+		if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok &&
+			subtle.ConstantTimeCompare([]byte(token), []byte(e.options.Token)) == 1 {
+			return true
+		}
+	}
+	if e.options.ClientCertificate && r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		return e.acceptsClient(r.TLS.VerifiedChains[0][0])
+	}
+	return false
+}
+
+func (e *configEndpoint) acceptsClient(certificate *x509.Certificate) bool {
+	if len(e.options.ClientNames) == 0 {
+		return true
+	}
+	for _, name := range e.options.ClientNames {
+		if certificate.Subject.CommonName == name {
+			return true
+		}
+		for _, dnsName := range certificate.DNSNames {
+			if dnsName == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (e *configEndpoint) serveConfig(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		e.writeConfig(w, r)
+	case http.MethodPut, http.MethodPatch:
+		e.updateConfig(w, r)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT, PATCH")
+		writeErrors(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// updateConfig validates the configuration of the request and persists it through the config handler,
+// which notifies the listeners as a modified configuration; it is rejected while the endpoint froze the configuration
+func (e *configEndpoint) updateConfig(w http.ResponseWriter, r *http.Request) {
+	var body interface{}
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, e.options.MaxBodyBytes))
+	if err := decoder.Decode(&body); err != nil {
+		writeErrors(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return
+	}
+	if _, ok := body.(map[string]interface{}); !ok && r.Method == http.MethodPut {
+		writeErrors(w, http.StatusBadRequest, "the configuration must be a JSON object")
+		return
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if e.frozen {
+		writeErrors(w, http.StatusConflict, "the configuration is frozen")
+		return
+	}
+	current, err := e.current()
+	if err != nil {
+		writeErrors(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	var document interface{}
+	if r.Method == http.MethodPatch {
+		document = mergePatch(current, e.secrets.unredact(body, current))
+	} else {
+		document = e.secrets.unredact(body, current)
+	}
+
+	config, err := e.decode(document)
+	if err != nil {
+		writeErrors(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if e.options.Validator != nil {
+		valid, err := e.options.Validator(config)
+		if err != nil {
+			writeValidationError(w, err)
+			return
+		}
+		if !valid {
+			writeErrors(w, http.StatusUnprocessableEntity, "the configuration was rejected")
+			return
+		}
+	}
+	if err := e.configHandler.SetConfig(config); err != nil {
+		writeErrors(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	e.logger.Infof("admin - configuration updated with %v from %v", r.Method, r.RemoteAddr)
+	e.writeConfig(w, r)
+}
+
+func (e *configEndpoint) serveAction(w http.ResponseWriter, action string, r *http.Request) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	switch action {
+	case "restore":
+		if !e.configHandler.CanRestore() {
+			writeErrors(w, http.StatusConflict, "there is no previous configuration to restore")
+			return
+		}
+		if err := e.restore(); err != nil {
+			writeErrors(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		e.logger.Infof("admin - configuration restored from %v", r.RemoteAddr)
+		e.writeConfig(w, r)
+		return
+	case "freeze":
+		e.configHandler.Freeze()
+		e.frozen = true
+	case "unfreeze":
+		e.configHandler.Unfreeze()
+		e.frozen = false
+	}
+	e.logger.Infof("admin - configuration %vd from %v", action, r.RemoteAddr)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// restore restores the previous configuration and notifies it to the listeners, which only apply the modified
+// configurations. A config handler that is not a configuration.NotifyingRestorer gets the restored configuration
+// set again to notify it, which keeps it as the previous one to restore.
+func (e *configEndpoint) restore() error {
+	if restorer, ok := e.configHandler.(configuration.NotifyingRestorer); ok {
+		return restorer.RestoreAndNotify()
+	}
+	if err := e.configHandler.Restore(); err != nil {
+		return err
+	}
+	current, err := e.current()
+	if err != nil {
+		return err
+	}
+	config, err := e.decode(current)
+	if err != nil {
+		return err
+	}
+	return e.configHandler.SetConfig(config)
+}
+
+// current returns the configuration in use as a JSON document
+func (e *configEndpoint) current() (interface{}, error) {
+	content, err := json.Marshal(e.configHandler.GetConfig())
+	if err != nil {
+		return nil, err
+	}
+	var document interface{}
+	if err := json.Unmarshal(content, &document); err != nil {
+		return nil, err
+	}
+	return document, nil
+}
+
+// decode returns document as a new value of the type of the configuration
+func (e *configEndpoint) decode(document interface{}) (interface{}, error) {
+	content, err := json.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+	config := reflect.New(reflect.TypeOf(e.configHandler.GetConfig()).Elem()).Interface()
+	if err := json.Unmarshal(content, config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func (e *configEndpoint) writeConfig(w http.ResponseWriter, r *http.Request) {
+	current, err := e.current()
+	if err != nil {
+		writeErrors(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		_ = json.NewEncoder(w).Encode(e.secrets.redact(current)) //nolint:errcheck // the client is gone when writing fails
+	}
+}
+
+// writeValidationError answers every error joined in err
+func writeValidationError(w http.ResponseWriter, err error) {
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+	messages := make([]string, 0, len(errs))
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+	writeErrors(w, http.StatusUnprocessableEntity, messages...)
+}
+
+func writeErrors(w http.ResponseWriter, status int, messages ...string) {
+	body := ValidationErrors{Errors: make([]ValidationError, 0, len(messages))}
+	for _, message := range messages {
+		body.Errors = append(body.Errors, ValidationError{Message: message})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body) //nolint:errcheck // the client is gone when writing fails
+}
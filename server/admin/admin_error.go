@@ -0,0 +1,37 @@
+package admin
+
+import (
+	"github.com/janmbaco/go-infrastructure/v2/errors"
+)
+
+// AdminError is the errors of the admin endpoint
+type AdminError interface {
+	errors.CustomError
+	GetErrorType() AdminErrorType
+}
+
+type adminError struct {
+	errors.CustomizableError
+	ErrorType AdminErrorType
+}
+
+func newAdminError(errorType AdminErrorType, message string, internalError error) AdminError {
+	return &adminError{
+		CustomizableError: errors.CustomizableError{
+			Message:       message,
+			InternalError: internalError,
+		},
+		ErrorType: errorType,
+	}
+}
+
+func (e *adminError) GetErrorType() AdminErrorType {
+	return e.ErrorType
+}
+
+type AdminErrorType uint8
+
+const (
+	UnexpectedError AdminErrorType = iota
+	MissingAuthentication
+)
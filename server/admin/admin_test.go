@@ -0,0 +1,330 @@
+package admin
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/janmbaco/go-infrastructure/v2/configuration"
+	"github.com/janmbaco/go-infrastructure/v2/logs"
+)
+
+type (
+	testConfig struct {
+		Port     string            `json:"port"`
+		Database testDatabase      `json:"database"`
+		Tags     map[string]string `json:"tags,omitempty"`
+	}
+
+	testDatabase struct {
+		Host     string `json:"host"`
+		Password string `json:"password"`
+		APIKey   string `json:"api_key,omitempty"`
+	}
+
+	// memoryConfigHandler keeps the configuration and its previous version in memory
+	memoryConfigHandler struct {
+		configuration.ConfigHandler
+		config   *testConfig
+		old      *testConfig
+		frozen   bool
+		modified int
+	}
+
+	silentLogger struct {
+		logs.Logger
+	}
+)
+
+func (h *memoryConfigHandler) GetConfig() interface{} { return h.config }
+
+func (h *memoryConfigHandler) SetConfig(config interface{}) error {
+	content, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	next := &testConfig{}
+	if err := json.Unmarshal(content, next); err != nil {
+		return err
+	}
+	h.old, h.config = h.config, next
+	h.modified++
+	return nil
+}
+
+func (h *memoryConfigHandler) CanRestore() bool { return h.old != nil }
+
+func (h *memoryConfigHandler) Restore() error {
+	h.config, h.old = h.old, nil
+	return nil
+}
+
+func (h *memoryConfigHandler) RestoreAndNotify() error {
+	h.config, h.old = h.old, nil
+	h.modified++
+	return nil
+}
+
+func (h *memoryConfigHandler) Freeze() { h.frozen = true }
+
+func (h *memoryConfigHandler) Unfreeze() { h.frozen = false }
+
+func (silentLogger) Infof(string, ...interface{}) {}
+
+func newTestEndpoint(t *testing.T, options Options) (http.Handler, *memoryConfigHandler) {
+	configHandler := &memoryConfigHandler{config: &testConfig{Port: ":8080", Database: testDatabase{Host: "db", Password: "s3cret"}}}
+	if options.Token == "" && !options.ClientCertificate {
+		options.Token = "admin-token"
+	}
+	endpoint, err := NewConfigEndpoint(configHandler, silentLogger{}, options)
+	require.NoError(t, err)
+	return endpoint, configHandler
+}
+
+func adminRequest(handler http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer admin-token")
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func decodeErrors(t *testing.T, w *httptest.ResponseRecorder) []string {
+	var body ValidationErrors
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	messages := make([]string, 0, len(body.Errors))
+	for _, e := range body.Errors {
+		messages = append(messages, e.Message)
+	}
+	return messages
+}
+
+func TestNewConfigEndpoint_WhenNoAuthentication_ThenReturnsError(t *testing.T) {
+	// Act
+	_, err := NewConfigEndpoint(&memoryConfigHandler{}, silentLogger{}, Options{})
+
+	// Assert
+	var adminErr AdminError
+	require.ErrorAs(t, err, &adminErr)
+	assert.Equal(t, MissingAuthentication, adminErr.GetErrorType())
+}
+
+func TestConfigEndpoint_WhenNotAuthenticated_ThenReturnsUnauthorized(t *testing.T) {
+	// Arrange
+	endpoint, _ := newTestEndpoint(t, Options{})
+
+	for _, authorization := range []string{"", "Bearer wrong", "admin-token"} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", authorization)
+
+		// Act
+		endpoint.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, http.StatusUnauthorized, w.Code, authorization)
+		assert.Equal(t, `Bearer realm="admin"`, w.Header().Get("WWW-Authenticate"))
+	}
+}
+
+func TestConfigEndpoint_WhenClientCertificateIsVerified_ThenAcceptsAllowedNames(t *testing.T) {
+	// Arrange
+	endpoint, _ := newTestEndpoint(t, Options{ClientCertificate: true, ClientNames: []string{"ops"}})
+
+	cases := map[string]int{"ops": http.StatusOK, "intruder": http.StatusUnauthorized}
+	for name, expected := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: name}}}}}
+
+		// Act
+		endpoint.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, expected, w.Code, name)
+	}
+}
+
+func TestConfigEndpoint_Get_WhenConfigHasSecrets_ThenRedactsThem(t *testing.T) {
+	// Arrange
+	endpoint, _ := newTestEndpoint(t, Options{})
+
+	// Act
+	w := adminRequest(endpoint, http.MethodGet, "/", "")
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	assert.JSONEq(t, `{"port": ":8080", "database": {"host": "db", "password": "[REDACTED]"}}`, w.Body.String())
+}
+
+func TestConfigEndpoint_Put_WhenConfigIsValid_ThenPersistsItKeepingRedactedSecrets(t *testing.T) {
+	// Arrange
+	var validated *testConfig
+	endpoint, configHandler := newTestEndpoint(t, Options{Validator: func(config interface{}) (bool, error) {
+		validated = config.(*testConfig)
+		return true, nil
+	}})
+
+	// Act
+	w := adminRequest(endpoint, http.MethodPut, "/", `{"port": ":9090", "database": {"host": "db2", "password": "[REDACTED]", "api_key": "k"}}`)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	expected := &testConfig{Port: ":9090", Database: testDatabase{Host: "db2", Password: "s3cret", APIKey: "k"}}
+	assert.Equal(t, expected, validated)
+	assert.Equal(t, expected, configHandler.config)
+	assert.JSONEq(t, `{"port": ":9090", "database": {"host": "db2", "password": "[REDACTED]", "api_key": "[REDACTED]"}}`, w.Body.String())
+}
+
+func TestConfigEndpoint_Patch_WhenPatchIsMerged_ThenKeepsTheRest(t *testing.T) {
+	// Arrange
+	endpoint, configHandler := newTestEndpoint(t, Options{})
+	configHandler.config.Tags = map[string]string{"env": "dev", "team": "web"}
+
+	// Act
+	w := adminRequest(endpoint, http.MethodPatch, "/", `{"database": {"host": "db2"}, "tags": {"team": null}}`)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, &testConfig{Port: ":8080", Database: testDatabase{Host: "db2", Password: "s3cret"}, Tags: map[string]string{"env": "dev"}}, configHandler.config)
+}
+
+func TestConfigEndpoint_Put_WhenValidatorFails_ThenReturnsEveryErrorWithoutPersisting(t *testing.T) {
+	// Arrange
+	endpoint, configHandler := newTestEndpoint(t, Options{Validator: func(config interface{}) (bool, error) {
+		return false, errors.Join(errors.New("port is required"), errors.New("database.host is required"))
+	}})
+
+	// Act
+	w := adminRequest(endpoint, http.MethodPut, "/", `{"port": ""}`)
+
+	// Assert
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, []string{"port is required", "database.host is required"}, decodeErrors(t, w))
+	assert.Zero(t, configHandler.modified)
+}
+
+func TestConfigEndpoint_Put_WhenBodyIsInvalid_ThenReturnsBadRequest(t *testing.T) {
+	// Arrange
+	endpoint, configHandler := newTestEndpoint(t, Options{MaxBodyBytes: 64})
+
+	for _, body := range []string{`{"port":`, `[1, 2]`, `{"port": 8080}`, `{"port": "` + strings.Repeat("8", 100) + `"}`} {
+		// Act
+		w := adminRequest(endpoint, http.MethodPut, "/", body)
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+		assert.Len(t, decodeErrors(t, w), 1)
+	}
+	assert.Zero(t, configHandler.modified)
+}
+
+func TestConfigEndpoint_Restore_WhenThereIsAPreviousConfig_ThenSetsIt(t *testing.T) {
+	// Arrange
+	endpoint, configHandler := newTestEndpoint(t, Options{})
+	require.Equal(t, http.StatusConflict, adminRequest(endpoint, http.MethodPost, "/restore", "").Code)
+	require.Equal(t, http.StatusOK, adminRequest(endpoint, http.MethodPatch, "/", `{"port": ":9090"}`).Code)
+
+	// Act
+	w := adminRequest(endpoint, http.MethodPost, "/restore", "")
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, ":8080", configHandler.config.Port)
+	assert.Equal(t, 2, configHandler.modified)
+}
+
+func TestConfigEndpoint_Restore_WhenRestoredTwiceInARow_ThenRejectsTheSecond(t *testing.T) {
+	// Arrange
+	endpoint, configHandler := newTestEndpoint(t, Options{})
+	require.Equal(t, http.StatusOK, adminRequest(endpoint, http.MethodPatch, "/", `{"port": ":9090"}`).Code)
+	require.Equal(t, http.StatusOK, adminRequest(endpoint, http.MethodPost, "/restore", "").Code)
+
+	// Act
+	w := adminRequest(endpoint, http.MethodPost, "/restore", "")
+
+	// Assert
+	assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+	assert.Equal(t, ":8080", configHandler.config.Port)
+	assert.False(t, configHandler.CanRestore())
+	assert.Equal(t, 2, configHandler.modified)
+}
+
+func TestConfigEndpoint_Restore_WhenConfigHandlerCannotNotifyTheRestore_ThenSetsTheRestoredConfig(t *testing.T) {
+	// Arrange
+	configHandler := &memoryConfigHandler{config: &testConfig{Port: ":8080", Database: testDatabase{Host: "db", Password: "s3cret"}}}
+	endpoint, err := NewConfigEndpoint(struct{ configuration.ConfigHandler }{configHandler}, silentLogger{}, Options{Token: "admin-token"})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, adminRequest(endpoint, http.MethodPatch, "/", `{"port": ":9090"}`).Code)
+
+	// Act
+	w := adminRequest(endpoint, http.MethodPost, "/restore", "")
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, ":8080", configHandler.config.Port)
+	assert.Equal(t, 2, configHandler.modified)
+}
+
+func TestConfigEndpoint_WhenFrozen_ThenRejectsUpdatesUntilUnfrozen(t *testing.T) {
+	// Arrange
+	endpoint, configHandler := newTestEndpoint(t, Options{})
+	require.Equal(t, http.StatusNoContent, adminRequest(endpoint, http.MethodPost, "/freeze", "").Code)
+
+	// Act
+	put := adminRequest(endpoint, http.MethodPut, "/", `{"port": ":9090", "database": {"host": "db"}}`)
+	patch := adminRequest(endpoint, http.MethodPatch, "/", `{"port": ":9090"}`)
+	modifiedWhileFrozen := configHandler.modified
+	require.Equal(t, http.StatusNoContent, adminRequest(endpoint, http.MethodPost, "/unfreeze", "").Code)
+	unfrozen := adminRequest(endpoint, http.MethodPatch, "/", `{"port": ":9090"}`)
+
+	// Assert
+	assert.Equal(t, http.StatusConflict, put.Code)
+	assert.Equal(t, []string{"the configuration is frozen"}, decodeErrors(t, put))
+	assert.Equal(t, http.StatusConflict, patch.Code)
+	assert.Zero(t, modifiedWhileFrozen)
+	assert.Equal(t, http.StatusOK, unfrozen.Code, unfrozen.Body.String())
+	assert.Equal(t, ":9090", configHandler.config.Port)
+}
+
+func TestConfigEndpoint_FreezeAndUnfreeze_ThenChangesTheConfigHandler(t *testing.T) {
+	// Arrange
+	endpoint, configHandler := newTestEndpoint(t, Options{})
+
+	// Act
+	frozen := adminRequest(endpoint, http.MethodPost, "/freeze", "")
+	wasFrozen := configHandler.frozen
+	unfrozen := adminRequest(endpoint, http.MethodPost, "/unfreeze/", "")
+
+	// Assert
+	assert.Equal(t, http.StatusNoContent, frozen.Code)
+	assert.True(t, wasFrozen)
+	assert.Equal(t, http.StatusNoContent, unfrozen.Code)
+	assert.False(t, configHandler.frozen)
+}
+
+func TestConfigEndpoint_WhenMethodOrPathIsUnknown_ThenRejectsIt(t *testing.T) {
+	// Arrange
+	endpoint, _ := newTestEndpoint(t, Options{})
+
+	// Act
+	getRestore := adminRequest(endpoint, http.MethodGet, "/restore", "")
+	deleteConfig := adminRequest(endpoint, http.MethodDelete, "/", "")
+	unknown := adminRequest(endpoint, http.MethodGet, "/secrets", "")
+
+	// Assert
+	assert.Equal(t, http.StatusMethodNotAllowed, getRestore.Code)
+	assert.Equal(t, http.MethodPost, getRestore.Header().Get("Allow"))
+	assert.Equal(t, http.StatusMethodNotAllowed, deleteConfig.Code)
+	assert.Equal(t, http.StatusNotFound, unknown.Code)
+}
@@ -0,0 +1,113 @@
+package admin
+
+import (
+	"strings"
+)
+
+// Redacted replaces the values of the secret keys in the configurations sent by the endpoint.
+// A request that sends it back keeps the current value.
+const Redacted = "[REDACTED]"
+
+// DefaultSecretKeys are the parts of the keys whose values are redacted, matched without case, dashes and underscores
+var DefaultSecretKeys = []string{"password", "passwd", "secret", "token", "apikey", "privatekey", "credential", "dsn"}
+
+type secretKeys []string
+
+func newSecretKeys(keys []string) secretKeys {
+	secrets := make(secretKeys, 0, len(keys))
+	for _, key := range keys {
+		secrets = append(secrets, normalizeKey(key))
+	}
+	return secrets
+}
+
+func (s secretKeys) isSecret(key string) bool {
+	key = normalizeKey(key)
+	for _, secret := range s {
+		if strings.Contains(key, secret) {
+			return true
+		}
+	}
+	return false
+}
+
+// redact returns a copy of the JSON document with the values of the secret keys replaced by Redacted,
+// unless they are empty
+func (s secretKeys) redact(document interface{}) interface{} {
+	switch value := document.(type) {
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			if s.isSecret(key) && item != nil && item != "" {
+				redacted[key] = Redacted
+			} else {
+				redacted[key] = s.redact(item)
+			}
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(value))
+		for i, item := range value {
+			redacted[i] = s.redact(item)
+		}
+		return redacted
+	default:
+		return document
+	}
+}
+
+// unredact returns the JSON document with the Redacted values of the secret keys replaced by the ones in current
+func (s secretKeys) unredact(document, current interface{}) interface{} {
+	switch value := document.(type) {
+	case map[string]interface{}:
+		currentMap, _ := current.(map[string]interface{})
+		unredacted := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			if item == Redacted && s.isSecret(key) {
+				unredacted[key] = currentMap[key]
+			} else {
+				unredacted[key] = s.unredact(item, currentMap[key])
+			}
+		}
+		return unredacted
+	case []interface{}:
+		currentSlice, _ := current.([]interface{})
+		unredacted := make([]interface{}, len(value))
+		for i, item := range value {
+			var currentItem interface{}
+			if i < len(currentSlice) {
+				currentItem = currentSlice[i]
+			}
+			unredacted[i] = s.unredact(item, currentItem)
+		}
+		return unredacted
+	default:
+		return document
+	}
+}
+
+// mergePatch applies a JSON merge patch (RFC 7396) to document: objects are merged, null removes a key
+// and any other value replaces the current one
+func mergePatch(document, patch interface{}) interface{} {
+	patchMap, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	documentMap, _ := document.(map[string]interface{})
+	merged := make(map[string]interface{}, len(documentMap)+len(patchMap))
+	for key, value := range documentMap {
+		merged[key] = value
+	}
+	for key, value := range patchMap {
+		if value == nil {
+			delete(merged, key)
+		} else {
+			merged[key] = mergePatch(merged[key], value)
+		}
+	}
+	return merged
+}
+
+func normalizeKey(key string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(key))
+}
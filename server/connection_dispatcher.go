@@ -0,0 +1,204 @@
+package server
+
+import (
+	"errors"
+	"net"
+	"sync"
+)
+
+type (
+	// connectionDispatcher accepts the connections of a net.Listener and hands them to the
+	// dispatchedListener created last, so a new server can take over the address of a running one
+	connectionDispatcher struct {
+		listener net.Listener
+		target   *dispatchedListener
+		changed  chan struct{}
+		limiter  *connectionLimiter
+		addr     string
+		once     sync.Once
+		mutex    sync.Mutex
+		closed   bool
+		// quit is closed with the dispatcher, so the servers accepting from it get net.ErrClosed
+		quit chan struct{}
+		// persistent listeners can not be bound again, so they stay open when their
+		// dispatchedListener is closed or the listener stops, until close is called
+		persistent bool
+	}
+
+	// dispatchedListener is the net.Listener a server accepts from. Closing it closes the
+	// underlying listener only when no other dispatchedListener has replaced it
+	dispatchedListener struct {
+		dispatcher *connectionDispatcher
+		tracker    *connectionTracker
+		accepted   chan acceptResult
+		done       chan struct{}
+		once       sync.Once
+	}
+
+	acceptResult struct {
+		conn net.Conn
+		err  error
+	}
+)
+
+func newConnectionDispatcher(listener net.Listener, addr string, persistent bool) *connectionDispatcher {
+	return &connectionDispatcher{
+		listener:   listener,
+		addr:       addr,
+		persistent: persistent,
+		changed:    make(chan struct{}),
+		quit:       make(chan struct{}),
+		limiter:    newConnectionLimiter(),
+	}
+}
+
+// accepts reports whether the dispatcher is open on the socket the ServerSetter asks for
+func (d *connectionDispatcher) accepts(serverSetter *ServerSetter) bool {
+	if d == nil {
+		return false
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.closed {
+		return false
+	}
+	if serverSetter.Listener != nil {
+		return serverSetter.Listener == d.listener
+	}
+	return serverSetter.Addr == d.addr
+}
+
+// newListener returns a tracked listener that receives every connection accepted from now on
+func (d *connectionDispatcher) newListener() *connectionTracker {
+	dl := &dispatchedListener{dispatcher: d, accepted: make(chan acceptResult), done: make(chan struct{})}
+	dl.tracker = newConnectionTracker(dl)
+	d.mutex.Lock()
+	d.target = dl
+	close(d.changed)
+	d.changed = make(chan struct{})
+	d.mutex.Unlock()
+	d.once.Do(func() { go d.acceptLoop() })
+	return dl.tracker
+}
+
+func (d *connectionDispatcher) acceptLoop() {
+	for {
+		if !d.limiter.acquire() {
+			return
+		}
+		conn, err := d.listener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			// closed by close or by the owner of a persistent listener
+			d.limiter.release()
+			d.close()
+			return
+		}
+		if err != nil {
+			d.limiter.release()
+		} else {
+			conn = d.limiter.wrap(conn)
+		}
+		if !d.deliver(acceptResult{conn: conn, err: err}) {
+			if conn != nil {
+				_ = conn.Close() //nolint:errcheck // nobody accepts connections anymore
+			}
+			return
+		}
+	}
+}
+
+// deliver hands the result to the current target, following it when it is replaced.
+// The connection is tracked before the handoff, so a server being replaced never misses
+// a connection that is on its way to it
+func (d *connectionDispatcher) deliver(result acceptResult) bool {
+	for {
+		d.mutex.Lock()
+		target, changed, closed := d.target, d.changed, d.closed
+		d.mutex.Unlock()
+		if closed {
+			return false
+		}
+		handed := result
+		var tracked *trackedConnection
+		if result.conn != nil {
+			tracked = target.tracker.add(result.conn)
+			handed.conn = tracked
+		}
+		select {
+		case target.accepted <- handed:
+			return true
+		case <-target.done:
+			target.tracker.forget(tracked)
+			// the target is being released or replaced, both signal changed
+			<-changed
+		case <-changed:
+			target.tracker.forget(tracked)
+		}
+	}
+}
+
+func (d *connectionDispatcher) release(dl *dispatchedListener) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.target != dl || d.persistent {
+		return nil
+	}
+	return d.closeLocked()
+}
+
+// stop closes the underlying listener unless it is persistent. Sockets passed by systemd and the listeners
+// of the caller stay open for the next start, their owner closes them
+func (d *connectionDispatcher) stop() {
+	if d != nil && !d.persistent {
+		d.close()
+	}
+}
+
+// close closes the underlying listener, persistent or not
+func (d *connectionDispatcher) close() {
+	if d == nil {
+		return
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	_ = d.closeLocked() //nolint:errcheck // the listener is being discarded
+}
+
+func (d *connectionDispatcher) closeLocked() error {
+	if d.closed {
+		return nil
+	}
+	d.closed = true
+	close(d.quit)
+	close(d.changed)
+	d.changed = make(chan struct{})
+	d.limiter.close()
+	return d.listener.Close()
+}
+
+// Accept waits for and returns the next connection dispatched to the listener
+func (dl *dispatchedListener) Accept() (net.Conn, error) {
+	select {
+	case result := <-dl.accepted:
+		return result.conn, result.err
+	case <-dl.done:
+		return nil, net.ErrClosed
+	case <-dl.dispatcher.quit:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close stops the listener, closing the underlying one if it is the current target
+func (dl *dispatchedListener) Close() error {
+	err := net.ErrClosed
+	dl.once.Do(func() {
+		close(dl.done)
+		err = dl.dispatcher.release(dl)
+	})
+	return err
+}
+
+// Addr returns the address of the underlying listener
+func (dl *dispatchedListener) Addr() net.Addr {
+	return dl.dispatcher.listener.Addr()
+}
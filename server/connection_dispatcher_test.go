@@ -0,0 +1,112 @@
+package server
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnectionDispatcher_NewListener_WhenReplaced_ThenNewListenerReceivesConnections(t *testing.T) {
+	// Arrange
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	dispatcher := newConnectionDispatcher(lis, lis.Addr().String(), false)
+	old := dispatcher.newListener()
+	current := dispatcher.newListener()
+	defer func() { _ = current.Close() }()
+
+	// Act
+	require.NoError(t, old.Close())
+	client, err := net.Dial("tcp", lis.Addr().String())
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+	conn, err := current.Accept()
+
+	// Assert
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	assert.Equal(t, 1, current.Open())
+	assert.Equal(t, 0, old.Open())
+}
+
+func TestConnectionDispatcher_Close_WhenCurrentListenerCloses_ThenClosesUnderlyingListener(t *testing.T) {
+	// Arrange
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	dispatcher := newConnectionDispatcher(lis, lis.Addr().String(), false)
+	current := dispatcher.newListener()
+
+	// Act
+	require.NoError(t, current.Close())
+	_, acceptErr := current.Accept()
+	_, dialErr := net.Dial("tcp", lis.Addr().String())
+
+	// Assert
+	assert.ErrorIs(t, acceptErr, net.ErrClosed)
+	assert.Error(t, dialErr)
+}
+
+func TestConnectionDispatcher_Close_WhenPersistent_ThenStaysOpenUntilClosed(t *testing.T) {
+	// Arrange
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	dispatcher := newConnectionDispatcher(lis, lis.Addr().String(), true)
+	stopped := dispatcher.newListener()
+
+	// Act
+	require.NoError(t, stopped.Close())
+	accepts := dispatcher.accepts(&ServerSetter{Addr: lis.Addr().String()})
+	restarted := dispatcher.newListener()
+	client, err := net.Dial("tcp", lis.Addr().String())
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+	conn, acceptErr := restarted.Accept()
+	dispatcher.close()
+	_, dialErr := net.Dial("tcp", lis.Addr().String())
+
+	// Assert
+	assert.True(t, accepts)
+	require.NoError(t, acceptErr)
+	_ = conn.Close()
+	assert.Error(t, dialErr)
+	assert.False(t, dispatcher.accepts(&ServerSetter{Addr: lis.Addr().String()}))
+}
+
+func TestConnectionDispatcher_Stop_WhenPersistent_ThenLeavesTheListenerOpen(t *testing.T) {
+	// Arrange
+	persistentLis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = persistentLis.Close() }()
+	boundLis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	persistent := newConnectionDispatcher(persistentLis, persistentLis.Addr().String(), true)
+	bound := newConnectionDispatcher(boundLis, boundLis.Addr().String(), false)
+
+	// Act
+	persistent.stop()
+	bound.stop()
+
+	// Assert
+	assert.True(t, persistent.accepts(&ServerSetter{Listener: persistentLis}))
+	assert.False(t, bound.accepts(&ServerSetter{Addr: boundLis.Addr().String()}))
+	_, dialErr := net.Dial("tcp", boundLis.Addr().String())
+	assert.Error(t, dialErr)
+}
+
+func TestConnectionDispatcher_WhenOwnerClosesPersistentListener_ThenServersGetErrClosed(t *testing.T) {
+	// Arrange
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	dispatcher := newConnectionDispatcher(lis, lis.Addr().String(), true)
+	current := dispatcher.newListener()
+
+	// Act
+	require.NoError(t, lis.Close())
+	_, acceptErr := current.Accept()
+
+	// Assert
+	assert.ErrorIs(t, acceptErr, net.ErrClosed)
+	assert.False(t, dispatcher.accepts(&ServerSetter{Listener: lis}))
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"net"
+	"sync"
+)
+
+type (
+	// connectionLimiter bounds the connections open at once, the accept loop waits for one to
+	// close when the limit is reached
+	connectionLimiter struct {
+		cond   *sync.Cond
+		mutex  sync.Mutex
+		limit  int
+		open   int
+		closed bool
+	}
+
+	// limitedConn frees its slot in the limiter when it is closed
+	limitedConn struct {
+		net.Conn
+		limiter *connectionLimiter
+		once    sync.Once
+	}
+)
+
+func newConnectionLimiter() *connectionLimiter {
+	cl := &connectionLimiter{}
+	cl.cond = sync.NewCond(&cl.mutex)
+	return cl
+}
+
+// setLimit changes the limit, zero or negative meaning no limit
+func (cl *connectionLimiter) setLimit(limit int) {
+	cl.mutex.Lock()
+	cl.limit = limit
+	cl.mutex.Unlock()
+	cl.cond.Broadcast()
+}
+
+// acquire waits for a free slot, it returns false when the limiter is closed
+func (cl *connectionLimiter) acquire() bool {
+	cl.mutex.Lock()
+	defer cl.mutex.Unlock()
+	for !cl.closed && cl.limit > 0 && cl.open >= cl.limit {
+		cl.cond.Wait()
+	}
+	if cl.closed {
+		return false
+	}
+	cl.open++
+	return true
+}
+
+func (cl *connectionLimiter) release() {
+	cl.mutex.Lock()
+	cl.open--
+	cl.mutex.Unlock()
+	cl.cond.Broadcast()
+}
+
+func (cl *connectionLimiter) close() {
+	cl.mutex.Lock()
+	cl.closed = true
+	cl.mutex.Unlock()
+	cl.cond.Broadcast()
+}
+
+func (cl *connectionLimiter) wrap(conn net.Conn) net.Conn {
+	return &limitedConn{Conn: conn, limiter: cl}
+}
+
+// Close closes the connection and frees its slot
+func (lc *limitedConn) Close() error {
+	lc.once.Do(lc.limiter.release)
+	return lc.Conn.Close()
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type (
+	// connectionTracker is a net.Listener that keeps the connections it accepted until they are closed
+	connectionTracker struct {
+		net.Listener
+		connections map[*trackedConnection]struct{}
+		mutex       sync.Mutex
+	}
+
+	trackedConnection struct {
+		net.Conn
+		tracker *connectionTracker
+		once    sync.Once
+		// pending is set until the http.Server reads the first request
+		pending bool
+	}
+)
+
+func newConnectionTracker(listener net.Listener) *connectionTracker {
+	return &connectionTracker{Listener: listener, connections: make(map[*trackedConnection]struct{})}
+}
+
+// Accept waits for and returns the next connection, keeping track of it
+func (ct *connectionTracker) Accept() (net.Conn, error) {
+	conn, err := ct.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	if tracked, ok := conn.(*trackedConnection); ok && tracked.tracker == ct {
+		// the dispatcher already tracked it
+		return tracked, nil
+	}
+	return ct.add(conn), nil
+}
+
+func (ct *connectionTracker) add(conn net.Conn) *trackedConnection {
+	tracked := &trackedConnection{Conn: conn, tracker: ct, pending: true}
+	ct.mutex.Lock()
+	ct.connections[tracked] = struct{}{}
+	ct.mutex.Unlock()
+	return tracked
+}
+
+// forget stops tracking a connection that was never handed to the server
+func (ct *connectionTracker) forget(conn *trackedConnection) {
+	if conn != nil {
+		ct.remove(conn)
+	}
+}
+
+// Open returns the number of accepted connections that are still open
+func (ct *connectionTracker) Open() int {
+	ct.mutex.Lock()
+	defer ct.mutex.Unlock()
+	return len(ct.connections)
+}
+
+// CloseConnections closes every connection still open and returns how many were closed
+func (ct *connectionTracker) CloseConnections() int {
+	ct.mutex.Lock()
+	connections := make([]*trackedConnection, 0, len(ct.connections))
+	for conn := range ct.connections {
+		connections = append(connections, conn)
+	}
+	ct.mutex.Unlock()
+
+	for _, conn := range connections {
+		_ = conn.Close() //nolint:errcheck // the connection is being discarded
+	}
+	return len(connections)
+}
+
+// ConnState is the http.Server hook that tells which connections have not sent a request yet
+func (ct *connectionTracker) ConnState(conn net.Conn, state http.ConnState) {
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		conn = tlsConn.NetConn()
+	}
+	if tracked, ok := conn.(*trackedConnection); ok && state != http.StateNew {
+		ct.mutex.Lock()
+		tracked.pending = false
+		ct.mutex.Unlock()
+	}
+}
+
+// WaitPending waits until every connection has sent its first request or closed, or ctx is done.
+// http.Server.Shutdown closes the connections that have not sent a request without serving them.
+func (ct *connectionTracker) WaitPending(ctx context.Context) {
+	ticker := time.NewTicker(5 * time.Millisecond)
+	defer ticker.Stop()
+	for ct.pending() > 0 {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (ct *connectionTracker) pending() int {
+	ct.mutex.Lock()
+	defer ct.mutex.Unlock()
+	pending := 0
+	for conn := range ct.connections {
+		if conn.pending {
+			pending++
+		}
+	}
+	return pending
+}
+
+func (ct *connectionTracker) remove(conn *trackedConnection) {
+	ct.mutex.Lock()
+	delete(ct.connections, conn)
+	ct.mutex.Unlock()
+}
+
+// Close closes the connection and stops tracking it
+func (tc *trackedConnection) Close() error {
+	tc.once.Do(func() { tc.tracker.remove(tc) })
+	return tc.Conn.Close()
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnectionTracker_Accept_WhenConnectionsOpenAndClose_ThenTracksOpenConnections(t *testing.T) {
+	// Arrange
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	tracker := newConnectionTracker(lis)
+	defer func() { _ = tracker.Close() }()
+
+	client, err := net.Dial("tcp", lis.Addr().String())
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+
+	// Act
+	conn, err := tracker.Accept()
+	require.NoError(t, err)
+	openBeforeClose := tracker.Open()
+	_ = conn.Close()
+	_ = conn.Close()
+
+	// Assert
+	assert.Equal(t, 1, openBeforeClose)
+	assert.Equal(t, 0, tracker.Open())
+}
+
+func TestConnectionTracker_CloseConnections_WhenConnectionsOpen_ThenClosesThem(t *testing.T) {
+	// Arrange
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	tracker := newConnectionTracker(lis)
+	defer func() { _ = tracker.Close() }()
+
+	client, err := net.Dial("tcp", lis.Addr().String())
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+	_, err = tracker.Accept()
+	require.NoError(t, err)
+
+	// Act
+	closed := tracker.CloseConnections()
+
+	// Assert
+	assert.Equal(t, 1, closed)
+	assert.Equal(t, 0, tracker.Open())
+	_ = client.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = client.Read(make([]byte, 1))
+	assert.Error(t, err)
+}
@@ -0,0 +1,178 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/janmbaco/go-infrastructure/v2/logs"
+)
+
+type (
+	// ProxyRule forwards the requests whose path starts with Prefix to Target
+	ProxyRule struct {
+		// Prefix is matched on path segments: /api matches /api and /api/users, but not /apis
+		Prefix string `json:"prefix"`
+		// Target is the backend URL, like http://localhost:9000; ws and wss are taken as http and https
+		Target string `json:"target"`
+		// StripPrefix removes Prefix from the path sent to Target
+		StripPrefix bool `json:"strip_prefix,omitempty"`
+		// PreserveHost sends the Host of the request instead of the one of Target
+		PreserveHost bool `json:"preserve_host,omitempty"`
+		// RequestHeaders are set in the requests sent to Target, an empty value removes the header
+		RequestHeaders map[string]string `json:"request_headers,omitempty"`
+		// ResponseHeaders are set in the responses of Target, an empty value removes the header
+		ResponseHeaders map[string]string `json:"response_headers,omitempty"`
+	}
+
+	// DevProxy forwards API paths to backends while a single page app is developed, websocket upgrades included.
+	// The rules can be replaced with Set while it serves.
+	DevProxy struct {
+		logger logs.Logger
+		routes atomic.Pointer[[]proxyRoute]
+	}
+
+	proxyRoute struct {
+		prefix string
+		proxy  *httputil.ReverseProxy
+	}
+)
+
+// NewDevProxy returns a DevProxy without rules. The backends that fail are logged with logger, unless it is nil
+func NewDevProxy(logger logs.Logger) *DevProxy {
+	proxy := &DevProxy{logger: logger}
+	proxy.routes.Store(&[]proxyRoute{})
+	return proxy
+}
+
+// Set replaces the rules; when a rule is invalid the current ones are kept
+func (p *DevProxy) Set(rules []ProxyRule) error {
+	routes := make([]proxyRoute, 0, len(rules))
+	for _, rule := range rules {
+		route, err := p.newRoute(rule)
+		if err != nil {
+			return err
+		}
+		routes = append(routes, route)
+	}
+	// the longest prefix wins
+	sort.SliceStable(routes, func(i, j int) bool { return len(routes[i].prefix) > len(routes[j].prefix) })
+	p.routes.Store(&routes)
+	return nil
+}
+
+// Middleware forwards the requests matched by a rule and passes the rest to next
+func (p *DevProxy) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, route := range *p.routes.Load() {
+			if matchesPrefix(r.URL.Path, route.prefix) {
+				route.proxy.ServeHTTP(w, r)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (p *DevProxy) newRoute(rule ProxyRule) (proxyRoute, error) {
+	if !strings.HasPrefix(rule.Prefix, "/") {
+		return proxyRoute{}, newDevProxyError(InvalidProxyPrefix, "the proxy prefix `"+rule.Prefix+"` does not start with /", nil)
+	}
+	target, err := url.Parse(rule.Target)
+	if err != nil {
+		return proxyRoute{}, newDevProxyError(InvalidProxyTarget, "the proxy target `"+rule.Target+"` is not a valid URL", err)
+	}
+	switch target.Scheme {
+	case "http", "https":
+	case "ws":
+		target.Scheme = "http"
+	case "wss":
+		target.Scheme = "https"
+	default:
+		return proxyRoute{}, newDevProxyError(InvalidProxyTarget, "the proxy target `"+rule.Target+"` is not an http, https, ws or wss URL", nil)
+	}
+	if target.Host == "" {
+		return proxyRoute{}, newDevProxyError(InvalidProxyTarget, "the proxy target `"+rule.Target+"` has no host", nil)
+	}
+
+	prefix := rule.Prefix
+	if prefix != "/" {
+		prefix = strings.TrimRight(prefix, "/")
+	}
+	requestHeaders := copyHeaders(rule.RequestHeaders)
+	responseHeaders := copyHeaders(rule.ResponseHeaders)
+
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			if rule.StripPrefix {
+				pr.Out.URL.Path = trimPathPrefix(pr.Out.URL.Path, prefix)
+				if pr.Out.URL.RawPath != "" {
+					pr.Out.URL.RawPath = trimPathPrefix(pr.Out.URL.RawPath, prefix)
+				}
+			}
+			pr.SetURL(target)
+			pr.SetXForwarded()
+			if rule.PreserveHost {
+				pr.Out.Host = pr.In.Host
+			}
+			for name, value := range requestHeaders {
+				switch {
+				case strings.EqualFold(name, "Host"):
+					pr.Out.Host = value
+				case value == "":
+					pr.Out.Header.Del(name)
+				default:
+					pr.Out.Header.Set(name, value)
+				}
+			}
+		},
+		ModifyResponse: func(response *http.Response) error {
+			for name, value := range responseHeaders {
+				if value == "" {
+					response.Header.Del(name)
+				} else {
+					response.Header.Set(name, value)
+				}
+			}
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			if p.logger != nil {
+				p.logger.Warningf("devproxy - %v %v to %v: %v", r.Method, r.URL.Path, target, err)
+			}
+			w.WriteHeader(http.StatusBadGateway)
+		},
+	}
+	return proxyRoute{prefix: prefix, proxy: proxy}, nil
+}
+
+// matchesPrefix reports whether urlPath is prefix or is below it
+func matchesPrefix(urlPath, prefix string) bool {
+	if prefix == "/" || urlPath == prefix {
+		return true
+	}
+	return strings.HasPrefix(urlPath, prefix+"/")
+}
+
+// trimPathPrefix removes prefix from urlPath, keeping it absolute
+func trimPathPrefix(urlPath, prefix string) string {
+	if prefix == "/" {
+		return urlPath
+	}
+	trimmed := strings.TrimPrefix(urlPath, prefix)
+	if !strings.HasPrefix(trimmed, "/") {
+		trimmed = "/" + trimmed
+	}
+	return trimmed
+}
+
+func copyHeaders(headers map[string]string) map[string]string {
+	copied := make(map[string]string, len(headers))
+	for name, value := range headers {
+		copied[name] = value
+	}
+	return copied
+}
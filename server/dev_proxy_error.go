@@ -0,0 +1,38 @@
+package server
+
+import (
+	"github.com/janmbaco/go-infrastructure/v2/errors"
+)
+
+// DevProxyError is the errors of DevProxy
+type DevProxyError interface {
+	errors.CustomError
+	GetErrorType() DevProxyErrorType
+}
+
+type devProxyError struct {
+	errors.CustomizableError
+	ErrorType DevProxyErrorType
+}
+
+func newDevProxyError(errorType DevProxyErrorType, message string, internalError error) DevProxyError {
+	return &devProxyError{
+		CustomizableError: errors.CustomizableError{
+			Message:       message,
+			InternalError: internalError,
+		},
+		ErrorType: errorType,
+	}
+}
+
+func (e *devProxyError) GetErrorType() DevProxyErrorType {
+	return e.ErrorType
+}
+
+type DevProxyErrorType uint8
+
+const (
+	UnexpectedDevProxyError DevProxyErrorType = iota
+	InvalidProxyPrefix
+	InvalidProxyTarget
+)
@@ -0,0 +1,223 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/janmbaco/go-infrastructure/v2/logs"
+)
+
+type proxyLogger struct {
+	logs.Logger
+	mutex   sync.Mutex
+	entries []string
+}
+
+func (l *proxyLogger) Warningf(format string, a ...interface{}) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.entries = append(l.entries, fmt.Sprintf(format, a...))
+}
+
+func newEchoBackend(t *testing.T, name string) *httptest.Server {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Backend", name)
+		w.Header().Set("X-Powered-By", "backend")
+		_, _ = fmt.Fprintf(w, "%v %v host=%v auth=%v cookie=%v forwarded=%v", name, r.URL.RequestURI(), r.Host, r.Header.Get("Authorization"), r.Header.Get("Cookie"), r.Header.Get("X-Forwarded-Host"))
+	}))
+	t.Cleanup(backend.Close)
+	return backend
+}
+
+func proxyGet(handler http.Handler, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", target, nil)
+	req.Header.Set("Cookie", "session=1")
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestDevProxy_Middleware_WhenPathMatchesRule_ThenForwardsIt(t *testing.T) {
+	// Arrange
+	api := newEchoBackend(t, "api")
+	proxy := NewDevProxy(&proxyLogger{})
+	require.NoError(t, proxy.Set([]ProxyRule{{
+		Prefix:          "/api/",
+		Target:          api.URL + "/v1",
+		StripPrefix:     true,
+		RequestHeaders:  map[string]string{"Authorization": "Bearer dev", "Cookie": ""},
+		ResponseHeaders: map[string]string{"Access-Control-Allow-Origin": "*", "X-Powered-By": ""},
+	}}))
+	handler := proxy.Middleware(http.NotFoundHandler())
+
+	// Act
+	w := proxyGet(handler, "/api/users?page=2")
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "api /v1/users?page=2 host="+strings.TrimPrefix(api.URL, "http://")+" auth=Bearer dev cookie= forwarded=example.com", w.Body.String())
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, w.Header().Get("X-Powered-By"))
+}
+
+func TestDevProxy_Middleware_WhenRulesOverlap_ThenLongestPrefixOnSegmentsWins(t *testing.T) {
+	// Arrange
+	api, auth := newEchoBackend(t, "api"), newEchoBackend(t, "auth")
+	proxy := NewDevProxy(&proxyLogger{})
+	require.NoError(t, proxy.Set([]ProxyRule{
+		{Prefix: "/api", Target: api.URL},
+		{Prefix: "/api/auth", Target: auth.URL, StripPrefix: true, PreserveHost: true},
+	}))
+	handler := proxy.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "spa")
+	}))
+	cases := map[string]string{
+		"/api":            "api /api host=",
+		"/api/users":      "api /api/users host=",
+		"/api/auth":       "auth / host=example.com",
+		"/api/auth/login": "auth /login host=example.com",
+		"/apis":           "spa",
+		"/":               "spa",
+	}
+
+	for target, expected := range cases {
+		// Act
+		w := proxyGet(handler, target)
+
+		// Assert
+		assert.True(t, strings.HasPrefix(w.Body.String(), expected), "%v: %v", target, w.Body.String())
+	}
+}
+
+func TestDevProxy_Set_WhenRulesChange_ThenForwardsToNewTarget(t *testing.T) {
+	// Arrange
+	first, second := newEchoBackend(t, "first"), newEchoBackend(t, "second")
+	proxy := NewDevProxy(&proxyLogger{})
+	require.NoError(t, proxy.Set([]ProxyRule{{Prefix: "/api", Target: first.URL}}))
+	handler := proxy.Middleware(http.NotFoundHandler())
+	before := proxyGet(handler, "/api/ping").Body.String()
+
+	// Act
+	require.NoError(t, proxy.Set([]ProxyRule{{Prefix: "/api", Target: second.URL}}))
+	after := proxyGet(handler, "/api/ping").Body.String()
+	require.NoError(t, proxy.Set(nil))
+	removed := proxyGet(handler, "/api/ping")
+
+	// Assert
+	assert.True(t, strings.HasPrefix(before, "first /api/ping"), before)
+	assert.True(t, strings.HasPrefix(after, "second /api/ping"), after)
+	assert.Equal(t, http.StatusNotFound, removed.Code)
+}
+
+func TestDevProxy_Set_WhenRuleIsInvalid_ThenKeepsCurrentRules(t *testing.T) {
+	// Arrange
+	api := newEchoBackend(t, "api")
+	proxy := NewDevProxy(&proxyLogger{})
+	require.NoError(t, proxy.Set([]ProxyRule{{Prefix: "/api", Target: api.URL}}))
+	cases := []struct {
+		rule      ProxyRule
+		errorType DevProxyErrorType
+	}{
+		{ProxyRule{Prefix: "api", Target: api.URL}, InvalidProxyPrefix},
+		{ProxyRule{Prefix: "/api", Target: "localhost:9000"}, InvalidProxyTarget},
+		{ProxyRule{Prefix: "/api", Target: "ftp://host"}, InvalidProxyTarget},
+		{ProxyRule{Prefix: "/api", Target: "http://"}, InvalidProxyTarget},
+		{ProxyRule{Prefix: "/api", Target: "http://%zz"}, InvalidProxyTarget},
+	}
+
+	for _, c := range cases {
+		// Act
+		err := proxy.Set([]ProxyRule{{Prefix: "/other", Target: api.URL}, c.rule})
+
+		// Assert
+		var proxyErr DevProxyError
+		require.ErrorAs(t, err, &proxyErr, c.rule.Target)
+		assert.Equal(t, c.errorType, proxyErr.GetErrorType(), c.rule.Target)
+	}
+	assert.Equal(t, http.StatusOK, proxyGet(proxy.Middleware(http.NotFoundHandler()), "/api").Code)
+	assert.Equal(t, http.StatusNotFound, proxyGet(proxy.Middleware(http.NotFoundHandler()), "/other").Code)
+}
+
+func TestDevProxy_Middleware_WhenBackendIsDown_ThenReturnsBadGateway(t *testing.T) {
+	// Arrange
+	backend := httptest.NewServer(http.NotFoundHandler())
+	backend.Close()
+	logger := &proxyLogger{}
+	proxy := NewDevProxy(logger)
+	require.NoError(t, proxy.Set([]ProxyRule{{Prefix: "/api", Target: backend.URL}}))
+
+	// Act
+	w := proxyGet(proxy.Middleware(http.NotFoundHandler()), "/api/users")
+
+	// Assert
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	require.Len(t, logger.entries, 1)
+	assert.Contains(t, logger.entries[0], "devproxy - GET /api/users to "+backend.URL)
+}
+
+func TestDevProxy_Middleware_WhenBackendIsDownWithoutLogger_ThenReturnsBadGateway(t *testing.T) {
+	// Arrange
+	backend := httptest.NewServer(http.NotFoundHandler())
+	backend.Close()
+	proxy := NewDevProxy(nil)
+	require.NoError(t, proxy.Set([]ProxyRule{{Prefix: "/api", Target: backend.URL}}))
+
+	// Act
+	w := proxyGet(proxy.Middleware(http.NotFoundHandler()), "/api/users")
+
+	// Assert
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+}
+
+func TestDevProxy_Middleware_WhenWebsocketUpgrade_ThenPassesConnectionThrough(t *testing.T) {
+	// Arrange
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "websocket" || r.URL.Path != "/socket" {
+			http.Error(w, "not an upgrade", http.StatusBadRequest)
+			return
+		}
+		conn, rw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n")
+		_ = rw.Flush()
+		line, _ := rw.ReadString('\n')
+		_, _ = rw.WriteString("echo " + line)
+		_ = rw.Flush()
+	}))
+	defer backend.Close()
+	proxy := NewDevProxy(&proxyLogger{})
+	require.NoError(t, proxy.Set([]ProxyRule{{Prefix: "/ws", Target: strings.Replace(backend.URL, "http", "ws", 1), StripPrefix: true}}))
+	front := httptest.NewServer(proxy.Middleware(http.NotFoundHandler()))
+	defer front.Close()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(front.URL, "http://"))
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+
+	// Act
+	_, err = io.WriteString(conn, "GET /ws/socket HTTP/1.1\r\nHost: localhost\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n")
+	require.NoError(t, err)
+	reader := bufio.NewReader(conn)
+	response, err := http.ReadResponse(reader, nil)
+	require.NoError(t, err)
+	_, err = io.WriteString(conn, "hello\n")
+	require.NoError(t, err)
+	echo, err := reader.ReadString('\n')
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusSwitchingProtocols, response.StatusCode)
+	assert.Equal(t, "echo hello\n", echo)
+}
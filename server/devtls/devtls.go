@@ -0,0 +1,280 @@
+// Package devtls generates certificates for development and tests.
+//
+// An Authority is a local certificate authority cached in a directory. It issues
+// leaf certificates for hostnames and IP addresses, cached next to it and renewed
+// when they are about to expire, and builds ready to use server, client and
+// mutual TLS configurations.
+//
+// Basic usage:
+//
+//	tlsConfig, err := devtls.ServerTLSConfig("", "localhost", "127.0.0.1")
+//	if err != nil {
+//		return err
+//	}
+//	serverSetter.TLSConfig = tlsConfig
+//
+// Clients trust the certificates by adding the CA file, Authority.CertFile, to
+// their trust store or through Authority.CertPool.
+package devtls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// AuthorityValidity is how long a new certificate authority is valid
+	AuthorityValidity = 10 * 365 * 24 * time.Hour
+	// CertificateValidity is how long a new leaf certificate is valid
+	CertificateValidity = 397 * 24 * time.Hour
+	// RenewBefore is how long before expiring a cached certificate is issued again
+	RenewBefore = 30 * 24 * time.Hour
+
+	authorityName = "ca"
+)
+
+// Authority is a local certificate authority that issues development certificates
+type Authority struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+	dir         string
+	mutex       sync.Mutex
+}
+
+// DefaultDir returns the directory where certificates are cached when no directory is given
+func DefaultDir() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", newAuthorityError(UnexpectedError, "failed to find the user cache directory", err)
+	}
+	return filepath.Join(cacheDir, "go-infrastructure", "devtls"), nil
+}
+
+// LoadOrCreateAuthority loads the authority cached in dir, creating it when it does not exist or
+// is about to expire. DefaultDir is used when dir is empty.
+func LoadOrCreateAuthority(dir string) (*Authority, error) {
+	if dir == "" {
+		var err error
+		if dir, err = DefaultDir(); err != nil {
+			return nil, err
+		}
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, newAuthorityError(CreateAuthorityError, "failed to create "+dir, err)
+	}
+	a := &Authority{dir: dir}
+	certificate, key, err := readKeyPair(a.CertFile(), a.keyFile(authorityName))
+	if err == nil && certificate.IsCA && time.Until(certificate.NotAfter) > RenewBefore {
+		a.certificate, a.key = certificate, key
+		return a, nil
+	}
+	if err := a.create(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// CertFile returns the PEM file of the authority certificate, the file clients must trust
+func (a *Authority) CertFile() string {
+	return filepath.Join(a.dir, authorityName+".crt")
+}
+
+// CertPool returns a pool that trusts the authority
+func (a *Authority) CertPool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(a.certificate)
+	return pool
+}
+
+// IssueCertificate returns a certificate for the hostnames and IP addresses, valid for server and
+// client authentication. It is reused from the cache while it is valid for longer than RenewBefore.
+func (a *Authority) IssueCertificate(hosts ...string) (*tls.Certificate, error) {
+	if len(hosts) == 0 {
+		return nil, newAuthorityError(IssueCertificateError, "at least one host is needed", nil)
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	name := leafName(hosts)
+	certFile, keyFile := filepath.Join(a.dir, name+".crt"), a.keyFile(name)
+	if certificate, key, err := readKeyPair(certFile, keyFile); err == nil && a.isValidLeaf(certificate) {
+		return &tls.Certificate{Certificate: [][]byte{certificate.Raw}, PrivateKey: key, Leaf: certificate}, nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, newAuthorityError(IssueCertificateError, "failed to generate a key", err)
+	}
+	template, err := newTemplate(hosts[0], CertificateValidity)
+	if err != nil {
+		return nil, newAuthorityError(IssueCertificateError, "failed to create a serial number", err)
+	}
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, a.certificate, &key.PublicKey, a.key)
+	if err != nil {
+		return nil, newAuthorityError(IssueCertificateError, "failed to sign the certificate for "+strings.Join(hosts, ", "), err)
+	}
+	if err := writeKeyPair(certFile, keyFile, der, key); err != nil {
+		return nil, err
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, newAuthorityError(IssueCertificateError, "failed to parse the certificate", err)
+	}
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: certificate}, nil
+}
+
+// ServerTLSConfig returns a server configuration that presents a certificate for the hosts
+func (a *Authority) ServerTLSConfig(hosts ...string) (*tls.Config, error) {
+	certificate, err := a.IssueCertificate(hosts...)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{*certificate},
+	}, nil
+}
+
+// ClientTLSConfig returns a client configuration that trusts the authority and presents a
+// certificate for name, for servers that require client certificates
+func (a *Authority) ClientTLSConfig(name string) (*tls.Config, error) {
+	certificate, err := a.IssueCertificate(name)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		RootCAs:      a.CertPool(),
+		Certificates: []tls.Certificate{*certificate},
+	}, nil
+}
+
+// ServerTLSConfig loads or creates the authority cached in dir and returns a server configuration
+// that presents a certificate for the hosts
+func ServerTLSConfig(dir string, hosts ...string) (*tls.Config, error) {
+	authority, err := LoadOrCreateAuthority(dir)
+	if err != nil {
+		return nil, err
+	}
+	return authority.ServerTLSConfig(hosts...)
+}
+
+func (a *Authority) create() error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return newAuthorityError(CreateAuthorityError, "failed to generate a key", err)
+	}
+	template, err := newTemplate("go-infrastructure development CA", AuthorityValidity)
+	if err != nil {
+		return newAuthorityError(CreateAuthorityError, "failed to create a serial number", err)
+	}
+	template.IsCA = true
+	template.BasicConstraintsValid = true
+	template.MaxPathLenZero = true
+	template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return newAuthorityError(CreateAuthorityError, "failed to sign the authority certificate", err)
+	}
+	if err := writeKeyPair(a.CertFile(), a.keyFile(authorityName), der, key); err != nil {
+		return err
+	}
+	if a.certificate, err = x509.ParseCertificate(der); err != nil {
+		return newAuthorityError(CreateAuthorityError, "failed to parse the authority certificate", err)
+	}
+	a.key = key
+	return nil
+}
+
+// isValidLeaf reports whether a cached certificate was signed by the authority and is not about to expire
+func (a *Authority) isValidLeaf(certificate *x509.Certificate) bool {
+	return certificate.CheckSignatureFrom(a.certificate) == nil && time.Until(certificate.NotAfter) > RenewBefore
+}
+
+func (a *Authority) keyFile(name string) string {
+	return filepath.Join(a.dir, name+".key")
+}
+
+// leafName names the cached files of a certificate after its hosts, in any order
+func leafName(hosts []string) string {
+	sorted := append([]string{}, hosts...)
+	sort.Strings(sorted)
+	sum := sha256.Sum256([]byte(strings.Join(sorted, "\n")))
+	return "leaf-" + hex.EncodeToString(sum[:8])
+}
+
+func newTemplate(commonName string, validity time.Duration) (*x509.Certificate, error) {
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject:      pkix.Name{CommonName: commonName, Organization: []string{"go-infrastructure development"}},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(validity),
+	}, nil
+}
+
+func readKeyPair(certFile, keyFile string) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	certPEM, err := os.ReadFile(certFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyPEM, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, ok := pair.PrivateKey.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, nil, newAuthorityError(UnexpectedError, keyFile+" is not an ECDSA key", nil)
+	}
+	certificate, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	return certificate, key, nil
+}
+
+func writeKeyPair(certFile, keyFile string, der []byte, key *ecdsa.PrivateKey) error {
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return newAuthorityError(UnexpectedError, "failed to marshal the key", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0o600); err != nil {
+		return newAuthorityError(UnexpectedError, "failed to write "+keyFile, err)
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644); err != nil {
+		return newAuthorityError(UnexpectedError, "failed to write "+certFile, err)
+	}
+	return nil
+}
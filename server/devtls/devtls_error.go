@@ -0,0 +1,39 @@
+package devtls
+
+import (
+	"github.com/janmbaco/go-infrastructure/v2/errors"
+)
+
+// AuthorityError is the errors of Authority
+type AuthorityError interface {
+	errors.CustomError
+	GetErrorType() AuthorityErrorType
+}
+
+type authorityError struct {
+	errors.CustomizableError
+	ErrorType AuthorityErrorType
+}
+
+func newAuthorityError(errorType AuthorityErrorType, message string, internalError error) AuthorityError {
+	return &authorityError{
+		CustomizableError: errors.CustomizableError{
+			Message:       message,
+			InternalError: internalError,
+		},
+		ErrorType: errorType,
+	}
+}
+
+func (e *authorityError) GetErrorType() AuthorityErrorType {
+	return e.ErrorType
+}
+
+type AuthorityErrorType uint8
+
+const (
+	UnexpectedError AuthorityErrorType = iota
+	CreateAuthorityError
+	IssueCertificateError
+	LoadCABundleError
+)
@@ -0,0 +1,112 @@
+package devtls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadOrCreateAuthority_WhenCached_ThenReusesAuthority(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	created, err := LoadOrCreateAuthority(dir)
+	require.NoError(t, err)
+
+	// Act
+	loaded, err := LoadOrCreateAuthority(dir)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, created.certificate.SerialNumber, loaded.certificate.SerialNumber)
+	assert.True(t, loaded.certificate.IsCA)
+	assert.FileExists(t, loaded.CertFile())
+	info, err := os.Stat(filepath.Join(dir, "ca.key"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
+
+func TestAuthority_IssueCertificate_WhenHostsGiven_ThenCoversHostsAndIsCached(t *testing.T) {
+	// Arrange
+	authority, err := LoadOrCreateAuthority(t.TempDir())
+	require.NoError(t, err)
+
+	// Act
+	issued, err := authority.IssueCertificate("localhost", "127.0.0.1")
+	require.NoError(t, err)
+	cached, err := authority.IssueCertificate("127.0.0.1", "localhost")
+	require.NoError(t, err)
+
+	// Assert
+	assert.Equal(t, issued.Certificate, cached.Certificate)
+	assert.Equal(t, []string{"localhost"}, issued.Leaf.DNSNames)
+	require.Len(t, issued.Leaf.IPAddresses, 1)
+	assert.True(t, issued.Leaf.IPAddresses[0].Equal(net.ParseIP("127.0.0.1")))
+	_, err = issued.Leaf.Verify(x509.VerifyOptions{DNSName: "localhost", Roots: authority.CertPool()})
+	assert.NoError(t, err)
+}
+
+func TestAuthority_IssueCertificate_WhenNoHosts_ThenReturnsIssueCertificateError(t *testing.T) {
+	// Arrange
+	authority, err := LoadOrCreateAuthority(t.TempDir())
+	require.NoError(t, err)
+
+	// Act
+	_, err = authority.IssueCertificate()
+
+	// Assert
+	var authorityErr AuthorityError
+	require.ErrorAs(t, err, &authorityErr)
+	assert.Equal(t, IssueCertificateError, authorityErr.GetErrorType())
+}
+
+func TestMutualTLSConfig_WhenClientPresentsCertificate_ThenHandshakeSucceeds(t *testing.T) {
+	// Arrange
+	authority, err := LoadOrCreateAuthority(t.TempDir())
+	require.NoError(t, err)
+	serverConfig, err := authority.ServerTLSConfig("127.0.0.1")
+	require.NoError(t, err)
+	mutualConfig, err := MutualTLSConfig(serverConfig, authority.CertFile())
+	require.NoError(t, err)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	srv.TLS = mutualConfig
+	srv.StartTLS()
+	defer srv.Close()
+	clientConfig, err := authority.ClientTLSConfig("client")
+	require.NoError(t, err)
+	anonymous := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{MinVersion: tls.VersionTLS12, RootCAs: authority.CertPool()}}}
+
+	// Act
+	response, err := (&http.Client{Transport: &http.Transport{TLSClientConfig: clientConfig}}).Get(srv.URL)
+	_, anonymousErr := anonymous.Get(srv.URL)
+
+	// Assert
+	require.NoError(t, err)
+	defer func() { _ = response.Body.Close() }()
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Error(t, anonymousErr)
+	assert.Nil(t, serverConfig.ClientCAs)
+}
+
+func TestLoadCertPool_WhenBundleHasNoCertificates_ThenReturnsLoadCABundleError(t *testing.T) {
+	// Arrange
+	bundle := filepath.Join(t.TempDir(), "bundle.pem")
+	require.NoError(t, os.WriteFile(bundle, []byte("not a certificate"), 0o600))
+
+	// Act
+	_, err := LoadCertPool(bundle)
+
+	// Assert
+	var authorityErr AuthorityError
+	require.ErrorAs(t, err, &authorityErr)
+	assert.Equal(t, LoadCABundleError, authorityErr.GetErrorType())
+}
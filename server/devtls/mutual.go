@@ -0,0 +1,33 @@
+package devtls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+)
+
+// LoadCertPool reads a PEM bundle with one or more CA certificates
+func LoadCertPool(caBundleFile string) (*x509.CertPool, error) {
+	bundle, err := os.ReadFile(caBundleFile)
+	if err != nil {
+		return nil, newAuthorityError(LoadCABundleError, "failed to read "+caBundleFile, err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(bundle) {
+		return nil, newAuthorityError(LoadCABundleError, caBundleFile+" has no PEM certificates", nil)
+	}
+	return pool, nil
+}
+
+// MutualTLSConfig returns a copy of the server configuration that requires client certificates
+// signed by a CA of the bundle
+func MutualTLSConfig(serverConfig *tls.Config, caBundleFile string) (*tls.Config, error) {
+	pool, err := LoadCertPool(caBundleFile)
+	if err != nil {
+		return nil, err
+	}
+	config := serverConfig.Clone()
+	config.ClientCAs = pool
+	config.ClientAuth = tls.RequireAndVerifyClientCert
+	return config, nil
+}
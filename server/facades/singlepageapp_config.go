@@ -0,0 +1,250 @@
+package facades
+
+import (
+	"compress/flate"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/janmbaco/go-infrastructure/v2/logs"
+	"github.com/janmbaco/go-infrastructure/v2/server"
+)
+
+const (
+	// FingerprintedCachePolicy sends fingerprinted assets as immutable and revalidates the index
+	FingerprintedCachePolicy = "fingerprinted"
+	// RevalidateCachePolicy revalidates every file with its ETag
+	RevalidateCachePolicy = "revalidate"
+)
+
+type (
+	// SinglePageAppConfig is the configuration file of the single page app facade.
+	// Changes to Env, Proxies and RateLimit are applied while serving, any other change restarts the listener.
+	SinglePageAppConfig struct {
+		Port       string `json:"port"`
+		StaticPath string `json:"static_path,omitempty"`
+		Index      string `json:"index"`
+		// BasePath mounts the app below a path, like /app
+		BasePath string `json:"base_path,omitempty"`
+		// server timeouts and connection limits, like "read_timeout": "30s"
+		Limits server.Limits      `json:"limits"`
+		TLS    SinglePageAppTLS   `json:"tls"`
+		Log    SinglePageAppLog   `json:"log"`
+		Cache  SinglePageAppCache `json:"cache"`
+		// Compression of the responses
+		Compression     SinglePageAppCompression     `json:"compression"`
+		SecurityHeaders SinglePageAppSecurityHeaders `json:"security_headers"`
+		Health          SinglePageAppHealth          `json:"health"`
+		// settings exposed to the app as window.__ENV__, served in /env.js and injected in the index
+		// unless there is a Content-Security-Policy. Changes are applied without restarting the listener
+		Env map[string]interface{} `json:"env,omitempty"`
+		// paths forwarded to backends before serving static files, like
+		// {"prefix": "/api", "target": "http://localhost:9000", "strip_prefix": true}.
+		// Changes are applied without restarting the listener
+		Proxies []server.ProxyRule `json:"proxies,omitempty"`
+		// requests per second by client IP, header or route and requests served at once, like
+		// {"rules": [{"key": "ip", "rate": 20, "burst": 40}], "max_in_flight": 500}.
+		// Changes are applied without restarting the listener
+		RateLimit server.RateLimits `json:"rate_limit"`
+	}
+
+	// SinglePageAppTLS are the certificate files served over TLS, reloaded when they change on disk
+	SinglePageAppTLS struct {
+		CertFile string `json:"cert_file,omitempty"`
+		KeyFile  string `json:"key_file,omitempty"`
+	}
+
+	// SinglePageAppLog configures the logger
+	SinglePageAppLog struct {
+		// Dir is where the log files are written, none when empty
+		Dir string `json:"dir,omitempty"`
+		// Level is trace, info, warning, error or fatal
+		Level string `json:"level,omitempty"`
+	}
+
+	// SinglePageAppCache is the caching policy of the files
+	SinglePageAppCache struct {
+		// Policy is fingerprinted or revalidate
+		Policy string `json:"policy,omitempty"`
+		// AssetsMaxAge is the max-age of the assets that are neither the index nor fingerprinted, none when zero
+		AssetsMaxAge server.Duration `json:"assets_max_age,omitempty"`
+	}
+
+	// SinglePageAppCompression configures the compression of the responses
+	SinglePageAppCompression struct {
+		// Dynamic compresses with gzip or deflate the responses without a precompressed sibling
+		Dynamic bool `json:"dynamic,omitempty"`
+		// Level is the compress/flate level of Dynamic, flate.DefaultCompression when zero
+		Level int `json:"level,omitempty"`
+		// Precompressed are the encodings of the served siblings in order of preference, like ["br", "gzip"].
+		// server.DefaultPrecompressedEncodings when missing, none when empty
+		Precompressed []string `json:"precompressed"`
+	}
+
+	// SinglePageAppSecurityHeaders are the security headers of the responses
+	SinglePageAppSecurityHeaders struct {
+		Enabled               bool            `json:"enabled"`
+		ContentSecurityPolicy string          `json:"content_security_policy,omitempty"`
+		HSTSMaxAge            server.Duration `json:"hsts_max_age,omitempty"`
+		HSTSIncludeSubdomains bool            `json:"hsts_include_subdomains,omitempty"`
+		HSTSPreload           bool            `json:"hsts_preload,omitempty"`
+	}
+
+	// SinglePageAppHealth exposes the liveness and readiness of the listener
+	SinglePageAppHealth struct {
+		Enabled       bool   `json:"enabled"`
+		LivenessPath  string `json:"liveness_path,omitempty"`
+		ReadinessPath string `json:"readiness_path,omitempty"`
+	}
+)
+
+var logLevels = map[string]logs.LogLevel{
+	"trace":   logs.Trace,
+	"info":    logs.Info,
+	"warning": logs.Warning,
+	"error":   logs.Error,
+	"fatal":   logs.Fatal,
+}
+
+// DefaultSinglePageAppConfig returns the configuration written to a new config file. The security headers and
+// the health endpoints are off, so the app can be framed and owns every path
+func DefaultSinglePageAppConfig() SinglePageAppConfig {
+	return SinglePageAppConfig{
+		Port:       ":8080",
+		StaticPath: "./static",
+		Index:      "index.html",
+		Log:        SinglePageAppLog{Level: "info"},
+		Cache:      SinglePageAppCache{Policy: FingerprintedCachePolicy},
+		Compression: SinglePageAppCompression{
+			Precompressed: append([]string(nil), server.DefaultPrecompressedEncodings...),
+		},
+	}
+}
+
+// LoadSinglePageAppConfig returns the configuration in configFile, or defaults when it does not exist,
+// as SinglePageAppRun would apply it, without creating the file
+func LoadSinglePageAppConfig(configFile string, defaults SinglePageAppConfig) (SinglePageAppConfig, error) {
+	content, err := os.ReadFile(configFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return defaults, nil
+	}
+	if err != nil {
+		return defaults, err
+	}
+	var config SinglePageAppConfig
+	if err := json.Unmarshal(content, &config); err != nil {
+		return defaults, fmt.Errorf("%v: %w", configFile, err)
+	}
+	return config, nil
+}
+
+// Validate reports every invalid value of the configuration, joined
+func (c *SinglePageAppConfig) Validate() error {
+	var errs []error
+	invalid := func(format string, a ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, a...))
+	}
+
+	if c.Port == "" {
+		invalid("port is required")
+	}
+	if c.Index == "" {
+		invalid("index is required")
+	}
+	if c.StaticPath != "" {
+		if info, err := os.Stat(c.StaticPath); err != nil {
+			invalid("static_path: %v", err)
+		} else if !info.IsDir() {
+			invalid("static_path: %v is not a directory", c.StaticPath)
+		}
+	}
+	if c.BasePath != "" && (!strings.HasPrefix(c.BasePath, "/") || strings.HasSuffix(c.BasePath, "/")) {
+		invalid("base_path %q must start with / and not end with /", c.BasePath)
+	}
+	if err := c.TLS.validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.Log.validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.Cache.validate("cache"); err != nil {
+		errs = append(errs, err)
+	}
+	if c.Compression.Level < flate.HuffmanOnly || c.Compression.Level > flate.BestCompression {
+		invalid("compression.level %v must be between %v and %v", c.Compression.Level, flate.HuffmanOnly, flate.BestCompression)
+	}
+	for _, encoding := range c.Compression.Precompressed {
+		if encoding != "br" && encoding != "gzip" && encoding != "zstd" {
+			invalid("compression.precompressed %q must be br, gzip or zstd", encoding)
+		}
+	}
+	if c.SecurityHeaders.HSTSMaxAge < 0 {
+		invalid("security_headers.hsts_max_age must not be negative")
+	}
+	if err := c.Health.validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := server.NewDevProxy(nil).Set(c.Proxies); err != nil {
+		invalid("proxies: %v", err)
+	}
+	if err := server.NewRateLimiter().Set(c.RateLimit); err != nil {
+		invalid("rate_limit: %v", err)
+	}
+	return errors.Join(errs...)
+}
+
+func (t SinglePageAppTLS) validate() error {
+	switch {
+	case (t.CertFile == "") != (t.KeyFile == ""):
+		return errors.New("tls: cert_file and key_file must be set together")
+	case t.CertFile != "":
+		if _, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile); err != nil {
+			return fmt.Errorf("tls: %w", err)
+		}
+	}
+	return nil
+}
+
+func (l SinglePageAppLog) validate() error {
+	if _, ok := logLevels[strings.ToLower(l.Level)]; l.Level != "" && !ok {
+		return fmt.Errorf("log.level %q must be trace, info, warning, error or fatal", l.Level)
+	}
+	return nil
+}
+
+// level returns the level of the logger, Info when it is not set
+func (l SinglePageAppLog) level() logs.LogLevel {
+	if level, ok := logLevels[strings.ToLower(l.Level)]; ok {
+		return level
+	}
+	return logs.Info
+}
+
+// validate reports the invalid values of the cache section at key
+func (c SinglePageAppCache) validate(key string) error {
+	var errs []error
+	if c.Policy != "" && c.Policy != FingerprintedCachePolicy && c.Policy != RevalidateCachePolicy {
+		errs = append(errs, fmt.Errorf("%v.policy %q must be %v or %v", key, c.Policy, FingerprintedCachePolicy, RevalidateCachePolicy))
+	}
+	if c.AssetsMaxAge < 0 {
+		errs = append(errs, fmt.Errorf("%v.assets_max_age must not be negative", key))
+	}
+	return errors.Join(errs...)
+}
+
+func (h SinglePageAppHealth) validate() error {
+	if !h.Enabled {
+		return nil
+	}
+	var errs []error
+	if !strings.HasPrefix(h.LivenessPath, "/") {
+		errs = append(errs, fmt.Errorf("health.liveness_path %q must start with /", h.LivenessPath))
+	}
+	if !strings.HasPrefix(h.ReadinessPath, "/") {
+		errs = append(errs, fmt.Errorf("health.readiness_path %q must start with /", h.ReadinessPath))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,107 @@
+package facades
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/janmbaco/go-infrastructure/v2/server"
+)
+
+func TestDefaultSinglePageAppConfig_WhenStaticPathExists_ThenIsValid(t *testing.T) {
+	// Arrange
+	config := DefaultSinglePageAppConfig()
+	config.StaticPath = t.TempDir()
+
+	// Act
+	err := config.Validate()
+
+	// Assert
+	assert.NoError(t, err)
+}
+
+func TestDefaultSinglePageAppConfig_ThenLeavesSecurityHeadersAndHealthOff(t *testing.T) {
+	// Act
+	config := DefaultSinglePageAppConfig()
+
+	// Assert
+	assert.False(t, config.SecurityHeaders.Enabled)
+	assert.Equal(t, SinglePageAppHealth{}, config.Health)
+}
+
+func TestSinglePageAppConfig_Validate_WhenValuesAreInvalid_ThenReportsEveryOne(t *testing.T) {
+	// Arrange
+	config := DefaultSinglePageAppConfig()
+	config.Port = ""
+	config.StaticPath = filepath.Join(t.TempDir(), "missing")
+	config.BasePath = "app/"
+	config.TLS.CertFile = "cert.pem"
+	config.Log.Level = "verbose"
+	config.Cache.Policy = "forever"
+	config.Compression.Level = 10
+	config.Compression.Precompressed = []string{"br", "lzma"}
+	config.Health = SinglePageAppHealth{Enabled: true, LivenessPath: "healthz", ReadinessPath: "/readyz"}
+	config.Proxies = []server.ProxyRule{{Prefix: "/api", Target: "localhost:9000"}}
+	config.RateLimit.Rules = []server.RateLimitRule{{Key: server.RateLimitByIP}}
+
+	// Act
+	err := config.Validate()
+
+	// Assert
+	require.Error(t, err)
+	for _, expected := range []string{"port is required", "static_path", "base_path", "tls: cert_file and key_file", "log.level", "cache.policy", "compression.level", `"lzma"`, "health.liveness_path", "proxies", "rate_limit: rule 0"} {
+		assert.Contains(t, err.Error(), expected)
+	}
+	assert.NotContains(t, err.Error(), "readiness_path")
+}
+
+func TestLoadSinglePageAppConfig_WhenFileDoesNotExist_ThenReturnsDefaultsWithoutCreatingIt(t *testing.T) {
+	// Arrange
+	configFile := filepath.Join(t.TempDir(), "spa.json")
+	defaults := DefaultSinglePageAppConfig()
+
+	// Act
+	config, err := LoadSinglePageAppConfig(configFile, defaults)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, defaults, config)
+	assert.NoFileExists(t, configFile)
+}
+
+func TestSinglePageAppOptions_Config_WhenFileExists_ThenAppliesOverridesOnIt(t *testing.T) {
+	// Arrange
+	configFile := filepath.Join(t.TempDir(), "spa.json")
+	require.NoError(t, os.WriteFile(configFile, []byte(`{"port": ":9000", "static_path": "./dist", "index": "app.html", "compression": {"precompressed": []}}`), 0o644))
+	options := SinglePageAppOptions{
+		ConfigFile: configFile,
+		Defaults:   DefaultSinglePageAppConfig(),
+		Override:   func(config *SinglePageAppConfig) { config.Port = ":7000" },
+	}
+
+	// Act
+	config, err := options.Config()
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, ":7000", config.Port)
+	assert.Equal(t, "./dist", config.StaticPath)
+	assert.Equal(t, "app.html", config.Index)
+	assert.Equal(t, []string{}, config.Compression.Precompressed)
+	assert.Empty(t, config.Cache.Policy)
+}
+
+func TestLoadSinglePageAppConfig_WhenFileIsNotJSON_ThenReturnsError(t *testing.T) {
+	// Arrange
+	configFile := filepath.Join(t.TempDir(), "spa.json")
+	require.NoError(t, os.WriteFile(configFile, []byte(`{"port": `), 0o644))
+
+	// Act
+	_, err := LoadSinglePageAppConfig(configFile, DefaultSinglePageAppConfig())
+
+	// Assert
+	assert.ErrorContains(t, err, configFile)
+}
@@ -1,26 +1,94 @@
 package facades
 
 import (
+	"compress/flate"
+	"context"
+	"crypto/tls"
 	"fmt"
+	"io/fs"
+	"net/http"
 	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	configResolver "github.com/janmbaco/go-infrastructure/v2/configuration/fileconfig/ioc/resolver"
 	"github.com/janmbaco/go-infrastructure/v2/dependencyinjection"
+	"github.com/janmbaco/go-infrastructure/v2/logs"
+	logsResolver "github.com/janmbaco/go-infrastructure/v2/logs/ioc/resolver"
 	"github.com/janmbaco/go-infrastructure/v2/server"
+	"github.com/janmbaco/go-infrastructure/v2/server/health"
 	serverIoc "github.com/janmbaco/go-infrastructure/v2/server/ioc"
 	serverResolver "github.com/janmbaco/go-infrastructure/v2/server/ioc/resolver"
+	"github.com/janmbaco/go-infrastructure/v2/server/middleware"
+	"github.com/janmbaco/go-infrastructure/v2/server/tlsreload"
 )
 
 func SinglePageAppStart(port, staticPath, index string) {
+	SinglePageAppStartTLS(port, staticPath, index, nil)
+}
 
-	// all servers need a configuration.
-	// The configuration is monitored to
-	// restart the server in case it changes
-	type conf struct {
-		Port       string `json:"port"`
-		StaticPath string `json:"static_path"`
-		Index      string `json:"index"`
+// SinglePageAppStartTLS serves the single page app over TLS when tlsConfig is not nil
+func SinglePageAppStartTLS(port, staticPath, index string, tlsConfig *tls.Config) {
+	singlePageAppStart(port, staticPath, index, nil, tlsConfig)
+}
+
+// SinglePageAppStartFS serves the single page app from fsys, like an embed.FS, instead of a static path.
+// It is served over TLS when tlsConfig is not nil
+func SinglePageAppStartFS(port string, fsys fs.FS, index string, tlsConfig *tls.Config) {
+	singlePageAppStart(port, "", index, fsys, tlsConfig)
+}
+
+func singlePageAppStart(port, staticPath, index string, fsys fs.FS, tlsConfig *tls.Config) {
+	defaults := DefaultSinglePageAppConfig()
+	defaults.Port, defaults.StaticPath, defaults.Index = port, staticPath, index
+	options := SinglePageAppOptions{ConfigFile: os.Args[0] + ".json", Defaults: defaults, FS: fsys, TLSConfig: tlsConfig}
+	if err := SinglePageAppRun(context.Background(), options); err != nil {
+		logs.NewLogger().Error(err.Error())
+	}
+}
+
+// SinglePageAppOptions configures SinglePageAppRun
+type SinglePageAppOptions struct {
+	// ConfigFile is the watched configuration, created with Defaults when it does not exist
+	ConfigFile string
+	Defaults   SinglePageAppConfig
+	// Override changes the configuration read from ConfigFile every time it is applied, like command line flags do
+	Override func(config *SinglePageAppConfig)
+	// FS, like an embed.FS, is served instead of static_path when it is not nil
+	FS fs.FS
+	// TLSConfig replaces the tls section when it is not nil
+	TLSConfig *tls.Config
+}
+
+// Config returns the configuration SinglePageAppRun would apply, without creating the config file
+func (o SinglePageAppOptions) Config() (SinglePageAppConfig, error) {
+	config, err := LoadSinglePageAppConfig(o.ConfigFile, o.Defaults)
+	if err != nil {
+		return config, err
+	}
+	return o.effective(&config), nil
+}
+
+// effective copies config, which the config handler modifies in place, with the overrides
+func (o SinglePageAppOptions) effective(config *SinglePageAppConfig) SinglePageAppConfig {
+	current := *config
+	if o.Override != nil {
+		o.Override(&current)
+	}
+	if o.FS != nil {
+		current.StaticPath = ""
 	}
+	return current
+}
+
+// SinglePageAppRun serves the single page app configured by options until ctx is done or SIGINT or SIGTERM
+// is received; SIGHUP forces a refresh of the configuration
+func SinglePageAppRun(ctx context.Context, options SinglePageAppOptions) error {
+	fsys, tlsConfig := options.FS, options.TLSConfig
+	defaults := options.Defaults
 
 	// Build container with required modules
 	container := dependencyinjection.NewBuilder().
@@ -28,37 +96,241 @@ func SinglePageAppStart(port, staticPath, index string) {
 		MustBuild()
 
 	resolver := container.Resolver()
+	logger := logsResolver.GetLogger(resolver)
 
-	listener, err := serverResolver.GetListenerBuilder(
-		resolver,
-		configResolver.GetFileConfigHandler(
-			resolver,
-			os.Args[0]+".json",
-			&conf{
-				Port:       port,
-				StaticPath: staticPath,
-				Index:      index,
-			},
-		),
-	).
+	// all servers need a configuration.
+	// The configuration is monitored to
+	// restart the server in case it changes
+	configHandler := configResolver.GetFileConfigHandler(resolver, options.ConfigFile, &defaults)
+
+	validate := func(config interface{}) (SinglePageAppConfig, error) {
+		conf, ok := config.(*SinglePageAppConfig)
+		if !ok {
+			return SinglePageAppConfig{}, fmt.Errorf("invalid config type")
+		}
+		current := options.effective(conf)
+		return current, current.Validate()
+	}
+	if _, err := validate(configHandler.GetConfig()); err != nil {
+		return fmt.Errorf("%v: %w", options.ConfigFile, err)
+	}
+
+	// env, proxies and rate limits outlive the restarts of the listener, applied holds the rest of the configuration in use
+	env := server.NewRuntimeConfig(server.DefaultRuntimeConfigGlobal)
+	proxies := server.NewDevProxy(logger)
+	rateLimiter := server.NewRateLimiter()
+	registry := health.NewRegistry()
+	certificates := &certificateWatch{}
+	defer certificates.close()
+	var applied SinglePageAppConfig
+	var mutex sync.Mutex
+
+	listener, err := serverResolver.GetListenerBuilder(resolver, configHandler).
 
 		// the bootstraper function is performed
 		// every time the configuration is modified
 		// hence the data is retrieved from the configuration again.
 		SetBootstrapper(func(config interface{}, serverSetter *server.ServerSetter) error {
-			conf, ok := config.(*conf)
-			if !ok {
-				return fmt.Errorf("invalid config type")
+			conf, err := validate(config)
+			if err != nil {
+				return err
+			}
+			applyLog(logger, conf.Log)
+			if err := proxies.Set(conf.Proxies); err != nil {
+				return err
+			}
+			if err := env.Set(conf.Env); err != nil {
+				return err
+			}
+			if err := rateLimiter.Set(conf.RateLimit); err != nil {
+				return err
+			}
+
+			mutex.Lock()
+			applied = conf
+			applied.Env, applied.Proxies, applied.RateLimit = nil, nil, server.RateLimits{}
+			var certificate *tlsreload.Certificate
+			if tlsConfig == nil {
+				certificate, err = certificates.watch(conf.TLS, logger)
+			}
+			mutex.Unlock()
+			if err != nil {
+				return err
+			}
+
+			var handler http.Handler
+			if fsys != nil {
+				logger.Infof("Serving the embedded single page app, index: %v", conf.Index)
+				handler = server.NewSinglePageAppFS(fsys, conf.Index, singlePageAppOptions(conf, env)...)
+			} else {
+				logger.Infof("Serving the single page app from %v, index: %v", conf.StaticPath, conf.Index)
+				handler = server.NewSinglePageApp(conf.StaticPath, conf.Index, singlePageAppOptions(conf, env)...)
+			}
+			if conf.BasePath != "" {
+				handler = mountAt(conf.BasePath, handler)
+			}
+			if conf.Health.Enabled {
+				handler = withHealth(conf.Health, registry, handler)
+			}
+			serverSetter.Handler = handler
+
+			serverSetter.Use(rateLimiter.Middleware)
+			if conf.SecurityHeaders.Enabled {
+				serverSetter.Use(middleware.SecurityHeaders(middleware.SecurityHeadersOptions{
+					ContentSecurityPolicy: conf.SecurityHeaders.ContentSecurityPolicy,
+					HSTSMaxAge:            time.Duration(conf.SecurityHeaders.HSTSMaxAge),
+					HSTSIncludeSubdomains: conf.SecurityHeaders.HSTSIncludeSubdomains,
+					HSTSPreload:           conf.SecurityHeaders.HSTSPreload,
+				}))
+			}
+			if conf.Compression.Dynamic {
+				level := conf.Compression.Level
+				if level == 0 {
+					level = flate.DefaultCompression
+				}
+				serverSetter.Use(middleware.Compress(level))
 			}
-			serverSetter.Handler = server.NewSinglePageApp(conf.StaticPath, conf.Index)
+			serverSetter.Use(proxies.Middleware)
+
 			serverSetter.Addr = conf.Port
+			serverSetter.TLSConfig = tlsConfig
+			if certificate != nil {
+				serverSetter.TLSConfig = certificate.TLSConfig()
+			}
+			serverSetter.Limits = conf.Limits
+			return nil
+		}).
+
+		// invalid changes are not applied
+		SetConfigValidatorFunc(func(config interface{}) (bool, error) {
+			if _, err := validate(config); err != nil {
+				return false, err
+			}
+			return true, nil
+		}).
+
+		// the env, proxies and rate_limit sections are applied to the running handler,
+		// any other change restarts the listener
+		SetConfigApplicatorFunc(func(config interface{}, configApplication *server.ConfigApplication) error {
+			conf, err := validate(config)
+			if err != nil {
+				return err
+			}
+			if err := proxies.Set(conf.Proxies); err != nil {
+				return err
+			}
+			if err := env.Set(conf.Env); err != nil {
+				return err
+			}
+			if err := rateLimiter.Set(conf.RateLimit); err != nil {
+				return err
+			}
+			conf.Env, conf.Proxies, conf.RateLimit = nil, nil, server.RateLimits{}
+			mutex.Lock()
+			*configApplication.NeedsRestart = !reflect.DeepEqual(conf, applied)
+			mutex.Unlock()
 			return nil
 		}).
 		GetListener()
 
 	if err != nil {
-		panic(err)
+		return err
+	}
+	if err := registry.Register(health.Check{Name: "listener", Func: health.ListenerCheck(listener)}); err != nil {
+		return err
+	}
+
+	// runs until SIGINT or SIGTERM, SIGHUP forces a refresh of the configuration
+	return server.RunWithConfigReload(ctx, configHandler, listener)
+}
+
+func singlePageAppOptions(conf SinglePageAppConfig, env *server.RuntimeConfig) []server.SinglePageAppOption {
+	opts := []server.SinglePageAppOption{server.WithRuntimeConfigScript(env, server.DefaultRuntimeConfigPath)}
+	// a Content-Security-Policy would block the inline script, the app loads /env.js instead
+	if !conf.SecurityHeaders.Enabled || conf.SecurityHeaders.ContentSecurityPolicy == "" {
+		opts = append(opts, server.WithRuntimeConfigInjection(env))
+	}
+	if conf.Compression.Precompressed != nil {
+		opts = append(opts, server.WithPrecompressedEncodings(conf.Compression.Precompressed...))
 	}
+	return append(opts, cacheOptions(conf.Cache)...)
+}
+
+func cacheOptions(cache SinglePageAppCache) []server.SinglePageAppOption {
+	if cache.Policy == RevalidateCachePolicy {
+		return []server.SinglePageAppOption{server.WithImmutableAssets(nil), server.WithAssetCacheControl(server.NoCacheControl)}
+	}
+	if cache.AssetsMaxAge > 0 {
+		return []server.SinglePageAppOption{server.WithAssetCacheControl("public, max-age=" + strconv.FormatInt(int64(time.Duration(cache.AssetsMaxAge).Seconds()), 10))}
+	}
+	return nil
+}
+
+func applyLog(logger logs.Logger, conf SinglePageAppLog) {
+	logger.SetConsoleLevel(conf.level())
+	logger.SetFileLogLevel(conf.level())
+	logger.SetDir(conf.Dir)
+}
+
+// certificateWatch keeps the certificate of the TLS files in use, closing the one it replaces
+type certificateWatch struct {
+	files       SinglePageAppTLS
+	certificate *tlsreload.Certificate
+}
+
+// watch returns the certificate of files, reusing the current one when they did not change; nil without TLS files
+func (w *certificateWatch) watch(files SinglePageAppTLS, logger logs.Logger) (*tlsreload.Certificate, error) {
+	if w.certificate != nil && files == w.files {
+		return w.certificate, nil
+	}
+	var certificate *tlsreload.Certificate
+	if files.CertFile != "" {
+		var err error
+		if certificate, err = tlsreload.Watch(files.CertFile, files.KeyFile, logger); err != nil {
+			return nil, err
+		}
+	}
+	w.close()
+	w.files, w.certificate = files, certificate
+	return certificate, nil
+}
+
+func (w *certificateWatch) close() {
+	if w.certificate != nil {
+		_ = w.certificate.Close() //nolint:errcheck // the certificate is no longer served
+	}
+}
+
+// mountAt serves handler below basePath, redirecting basePath to basePath/
+func mountAt(basePath string, handler http.Handler) http.Handler {
+	stripped := http.StripPrefix(basePath, handler)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == basePath:
+			target := basePath + "/"
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
+		case strings.HasPrefix(r.URL.Path, basePath+"/"):
+			stripped.ServeHTTP(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
 
-	<-listener.Start()
+// withHealth answers the liveness and readiness paths before handler
+func withHealth(conf SinglePageAppHealth, registry *health.Registry, handler http.Handler) http.Handler {
+	liveness, readiness := registry.LivenessHandler(), registry.ReadinessHandler()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case conf.LivenessPath:
+			liveness.ServeHTTP(w, r)
+		case conf.ReadinessPath:
+			readiness.ServeHTTP(w, r)
+		default:
+			handler.ServeHTTP(w, r)
+		}
+	})
 }
@@ -0,0 +1,100 @@
+package facades
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/janmbaco/go-infrastructure/v2/logs"
+	"github.com/janmbaco/go-infrastructure/v2/server/devtls"
+	"github.com/janmbaco/go-infrastructure/v2/server/health"
+)
+
+func TestMountAt_WhenRequestIsBelowBasePath_ThenServesItWithoutPrefix(t *testing.T) {
+	// Arrange
+	handler := mountAt("/app", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.URL.Path)
+	}))
+	cases := []struct {
+		target, body, location string
+		status                 int
+	}{
+		{"/app/", "/", "", http.StatusOK},
+		{"/app/users/42", "/users/42", "", http.StatusOK},
+		{"/app?tab=1", "", "/app/?tab=1", http.StatusMovedPermanently},
+		{"/apps", "", "", http.StatusNotFound},
+		{"/", "", "", http.StatusNotFound},
+	}
+
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+
+		// Act
+		handler.ServeHTTP(w, httptest.NewRequest("GET", c.target, nil))
+
+		// Assert
+		assert.Equal(t, c.status, w.Code, c.target)
+		assert.Equal(t, c.location, w.Header().Get("Location"), c.target)
+		if c.status == http.StatusOK {
+			assert.Equal(t, c.body, w.Body.String(), c.target)
+		}
+	}
+}
+
+func TestWithHealth_WhenHealthPathRequested_ThenReportsIt(t *testing.T) {
+	// Arrange
+	registry := health.NewRegistry()
+	handler := withHealth(SinglePageAppHealth{Enabled: true, LivenessPath: "/livez", ReadinessPath: "/readyz"}, registry, http.NotFoundHandler())
+	cases := map[string]int{"/livez": http.StatusOK, "/readyz": http.StatusOK, "/healthz": http.StatusNotFound}
+
+	for target, status := range cases {
+		w := httptest.NewRecorder()
+
+		// Act
+		handler.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+
+		// Assert
+		assert.Equal(t, status, w.Code, target)
+	}
+}
+
+// authorityFiles returns the certificate and key files of a development authority, a valid pair to watch
+func authorityFiles(t *testing.T, dir string) SinglePageAppTLS {
+	t.Helper()
+	authority, err := devtls.LoadOrCreateAuthority(filepath.Join(t.TempDir(), dir))
+	require.NoError(t, err)
+	return SinglePageAppTLS{CertFile: authority.CertFile(), KeyFile: strings.TrimSuffix(authority.CertFile(), ".crt") + ".key"}
+}
+
+func TestCertificateWatch_WhenTLSFilesChange_ThenReplacesTheCertificate(t *testing.T) {
+	// Arrange
+	logger := logs.NewLogger()
+	first, second := authorityFiles(t, "first"), authorityFiles(t, "second")
+	certificates := &certificateWatch{}
+	defer certificates.close()
+
+	// Act
+	watched, err := certificates.watch(first, logger)
+	require.NoError(t, err)
+	reused, err := certificates.watch(first, logger)
+	require.NoError(t, err)
+	replaced, err := certificates.watch(second, logger)
+	require.NoError(t, err)
+	_, invalidErr := certificates.watch(SinglePageAppTLS{CertFile: first.CertFile, KeyFile: second.KeyFile}, logger)
+	kept := certificates.certificate
+	disabled, err := certificates.watch(SinglePageAppTLS{}, logger)
+	require.NoError(t, err)
+
+	// Assert
+	assert.Same(t, watched, reused)
+	assert.NotSame(t, watched, replaced)
+	assert.Error(t, invalidErr)
+	assert.Same(t, replaced, kept)
+	assert.Nil(t, disabled)
+}
@@ -0,0 +1,144 @@
+package facades
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/janmbaco/go-infrastructure/v2/server"
+)
+
+type (
+	// VirtualHostsConfig is the configuration file of the virtual hosts facade.
+	// Changes to Sites and RateLimit are applied while serving, any other change restarts the listener.
+	VirtualHostsConfig struct {
+		Port string `json:"port"`
+		// server timeouts and connection limits, like "read_timeout": "30s"
+		Limits server.Limits    `json:"limits"`
+		TLS    SinglePageAppTLS `json:"tls"`
+		Log    SinglePageAppLog `json:"log"`
+		// Health paths are answered for every host
+		Health SinglePageAppHealth `json:"health"`
+		// RateLimit limits the requests of every site, applied without restarting the listener
+		RateLimit server.RateLimits `json:"rate_limit"`
+		// Sites are matched by host first and then by the longest path prefix.
+		// Sites can be added, changed or removed without restarting the listener
+		Sites []SiteConfig `json:"sites"`
+	}
+
+	// SiteConfig is a single page app or a static site served by the virtual hosts facade
+	SiteConfig struct {
+		// Hosts are names like example.com or wildcards like *.example.com; any host when empty
+		Hosts []string `json:"hosts,omitempty"`
+		// PathPrefix mounts the site below a path, like /docs; /docs redirects to /docs/
+		PathPrefix string `json:"path_prefix,omitempty"`
+		// Root is the directory of the files of the site
+		Root string `json:"root"`
+		// Index is the index file of the directories, index.html when empty
+		Index string `json:"index,omitempty"`
+		// SPA falls back to Index for the navigation requests to missing paths
+		SPA   bool               `json:"spa,omitempty"`
+		Cache SinglePageAppCache `json:"cache"`
+		// Headers are set in every response of the site, like {"X-Frame-Options": "DENY"}
+		Headers map[string]string `json:"headers,omitempty"`
+	}
+)
+
+// DefaultVirtualHostsConfig returns the configuration written to a new config file, without sites
+func DefaultVirtualHostsConfig() VirtualHostsConfig {
+	return VirtualHostsConfig{
+		Port:   ":8080",
+		Log:    SinglePageAppLog{Level: "info"},
+		Health: SinglePageAppHealth{LivenessPath: "/healthz", ReadinessPath: "/readyz"},
+		Sites:  []SiteConfig{},
+	}
+}
+
+// LoadVirtualHostsConfig returns the configuration in configFile, or defaults when it does not exist,
+// without creating the file
+func LoadVirtualHostsConfig(configFile string, defaults VirtualHostsConfig) (VirtualHostsConfig, error) {
+	content, err := os.ReadFile(configFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return defaults, nil
+	}
+	if err != nil {
+		return defaults, err
+	}
+	var config VirtualHostsConfig
+	if err := json.Unmarshal(content, &config); err != nil {
+		return defaults, fmt.Errorf("%v: %w", configFile, err)
+	}
+	return config, nil
+}
+
+// Validate reports every invalid value of the configuration, joined
+func (c *VirtualHostsConfig) Validate() error {
+	var errs []error
+	if c.Port == "" {
+		errs = append(errs, errors.New("port is required"))
+	}
+	errs = append(errs, c.TLS.validate(), c.Log.validate(), c.Health.validate())
+	for i, site := range c.Sites {
+		errs = append(errs, site.validate(fmt.Sprintf("sites[%v]", i)))
+	}
+	// the hosts are checked together to find the repeated ones
+	if err := server.NewVirtualHostRouter().Set(virtualHosts(c.Sites, func(SiteConfig) http.Handler { return http.NotFoundHandler() })); err != nil {
+		errs = append(errs, fmt.Errorf("sites: %w", err))
+	}
+	if err := server.NewRateLimiter().Set(c.RateLimit); err != nil {
+		errs = append(errs, fmt.Errorf("rate_limit: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// validate reports the invalid values of the site at key
+func (s SiteConfig) validate(key string) error {
+	var errs []error
+	if s.Root == "" {
+		errs = append(errs, fmt.Errorf("%v.root is required", key))
+	} else if info, err := os.Stat(s.Root); err != nil {
+		errs = append(errs, fmt.Errorf("%v.root: %w", key, err))
+	} else if !info.IsDir() {
+		errs = append(errs, fmt.Errorf("%v.root: %v is not a directory", key, s.Root))
+	}
+	if strings.Contains(s.Index, "/") {
+		errs = append(errs, fmt.Errorf("%v.index %q must be a file name", key, s.Index))
+	}
+	if s.PathPrefix != "" && (!strings.HasPrefix(s.PathPrefix, "/") || strings.HasSuffix(s.PathPrefix, "/")) {
+		errs = append(errs, fmt.Errorf("%v.path_prefix %q must start with / and not end with /", key, s.PathPrefix))
+	}
+	errs = append(errs, s.Cache.validate(key+".cache"))
+	for name := range s.Headers {
+		if name == "" || strings.ContainsAny(name, " :\r\n") {
+			errs = append(errs, fmt.Errorf("%v.headers %q is not a header name", key, name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// index returns the index file of the site, index.html when it is not set
+func (s SiteConfig) index() string {
+	if s.Index == "" {
+		return "index.html"
+	}
+	return s.Index
+}
+
+// virtualHosts returns a server.VirtualHost for every host of every site, with the handler of the site
+func virtualHosts(sites []SiteConfig, handler func(SiteConfig) http.Handler) []server.VirtualHost {
+	var hosts []server.VirtualHost
+	for _, site := range sites {
+		siteHandler := handler(site)
+		names := site.Hosts
+		if len(names) == 0 {
+			names = []string{""}
+		}
+		for _, name := range names {
+			hosts = append(hosts, server.VirtualHost{Host: name, PathPrefix: site.PathPrefix, Handler: siteHandler})
+		}
+	}
+	return hosts
+}
@@ -0,0 +1,118 @@
+package facades
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/janmbaco/go-infrastructure/v2/server"
+)
+
+func newSiteRoot(t *testing.T, files map[string]string) string {
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	return root
+}
+
+func TestVirtualHostsConfig_Validate_WhenSitesAreValid_ThenReturnsNil(t *testing.T) {
+	// Arrange
+	config := DefaultVirtualHostsConfig()
+	config.Sites = []SiteConfig{
+		{Hosts: []string{"example.com", "www.example.com"}, Root: t.TempDir(), SPA: true},
+		{Hosts: []string{"example.com"}, PathPrefix: "/docs", Root: t.TempDir()},
+		{Root: t.TempDir(), Cache: SinglePageAppCache{Policy: RevalidateCachePolicy}},
+	}
+
+	// Act
+	err := config.Validate()
+
+	// Assert
+	assert.NoError(t, err)
+}
+
+func TestVirtualHostsConfig_Validate_WhenValuesAreInvalid_ThenReportsEveryOne(t *testing.T) {
+	// Arrange
+	config := DefaultVirtualHostsConfig()
+	config.Port = ""
+	config.Log.Level = "verbose"
+	config.Sites = []SiteConfig{
+		{Hosts: []string{"example.com"}, Root: filepath.Join(t.TempDir(), "missing"), Index: "app/index.html"},
+		{Hosts: []string{"example.com"}, PathPrefix: "/docs/", Root: t.TempDir(), Cache: SinglePageAppCache{Policy: "forever"}},
+		{Hosts: []string{"EXAMPLE.com"}, Root: t.TempDir(), Headers: map[string]string{"X Frame": "DENY"}},
+	}
+	config.RateLimit.MaxInFlight = -1
+
+	// Act
+	err := config.Validate()
+
+	// Assert
+	require.Error(t, err)
+	for _, expected := range []string{"port is required", "log.level", "sites[0].root", "sites[0].index", `sites[1].path_prefix "/docs/"`, "sites[1].cache.policy", `sites[2].headers "X Frame"`, "sites: the host `EXAMPLE.com`", "rate_limit: the max in flight"} {
+		assert.Contains(t, err.Error(), expected)
+	}
+}
+
+func TestLoadVirtualHostsConfig_WhenFileHasSites_ThenReadsThem(t *testing.T) {
+	// Arrange
+	configFile := filepath.Join(t.TempDir(), "sites.json")
+	require.NoError(t, os.WriteFile(configFile, []byte(`{"port": ":9090", "sites": [{"hosts": ["example.com"], "root": "./www", "spa": true, "headers": {"X-Frame-Options": "DENY"}}]}`), 0o644))
+
+	// Act
+	config, err := LoadVirtualHostsConfig(configFile, DefaultVirtualHostsConfig())
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, ":9090", config.Port)
+	assert.Equal(t, []SiteConfig{{Hosts: []string{"example.com"}, Root: "./www", SPA: true, Headers: map[string]string{"X-Frame-Options": "DENY"}}}, config.Sites)
+}
+
+func TestVirtualHosts_WhenSitesAreRouted_ThenEachServesItsRootIndexAndHeaders(t *testing.T) {
+	// Arrange
+	app := newSiteRoot(t, map[string]string{"main.html": "app", "assets/app.js": "js"})
+	docs := newSiteRoot(t, map[string]string{"index.html": "docs", "guide/index.html": "guide"})
+	sites := []SiteConfig{
+		{Hosts: []string{"app.example.com"}, Root: app, Index: "main.html", SPA: true, Headers: map[string]string{"X-Site": "app"}},
+		{Hosts: []string{"app.example.com", "*.docs.example.com"}, PathPrefix: "/docs", Root: docs},
+	}
+	router := server.NewVirtualHostRouter()
+	require.NoError(t, router.Set(virtualHosts(sites, newSiteHandler)))
+
+	cases := []struct {
+		host, target   string
+		status         int
+		body, siteName string
+	}{
+		{"app.example.com", "/users/42", http.StatusOK, "app", "app"},
+		{"app.example.com", "/assets/app.js", http.StatusOK, "js", "app"},
+		{"app.example.com", "/docs/guide/", http.StatusOK, "guide", ""},
+		{"v2.docs.example.com", "/docs/", http.StatusOK, "docs", ""},
+		{"app.example.com", "/docs/missing", http.StatusNotFound, "", ""},
+		{"docs.example.com", "/docs/", http.StatusNotFound, "", ""},
+	}
+
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", c.target, nil)
+		req.Host = c.host
+		req.Header.Set("Accept", "text/html")
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, c.status, w.Code, c.host+c.target)
+		if c.body != "" {
+			assert.Equal(t, c.body, w.Body.String(), c.host+c.target)
+		}
+		assert.Equal(t, c.siteName, w.Header().Get("X-Site"), c.host+c.target)
+	}
+}
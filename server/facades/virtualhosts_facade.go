@@ -0,0 +1,227 @@
+package facades
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+
+	configResolver "github.com/janmbaco/go-infrastructure/v2/configuration/fileconfig/ioc/resolver"
+	"github.com/janmbaco/go-infrastructure/v2/dependencyinjection"
+	logsResolver "github.com/janmbaco/go-infrastructure/v2/logs/ioc/resolver"
+	"github.com/janmbaco/go-infrastructure/v2/server"
+	"github.com/janmbaco/go-infrastructure/v2/server/health"
+	serverIoc "github.com/janmbaco/go-infrastructure/v2/server/ioc"
+	serverResolver "github.com/janmbaco/go-infrastructure/v2/server/ioc/resolver"
+	"github.com/janmbaco/go-infrastructure/v2/server/tlsreload"
+)
+
+// VirtualHostsOptions configures VirtualHostsRun
+type VirtualHostsOptions struct {
+	// ConfigFile is the watched configuration, created with Defaults when it does not exist
+	ConfigFile string
+	Defaults   VirtualHostsConfig
+	// TLSConfig replaces the tls section when it is not nil
+	TLSConfig *tls.Config
+}
+
+// Config returns the configuration VirtualHostsRun would apply, without creating the config file
+func (o VirtualHostsOptions) Config() (VirtualHostsConfig, error) {
+	return LoadVirtualHostsConfig(o.ConfigFile, o.Defaults)
+}
+
+// VirtualHostsRun serves the sites configured by options from one listener until ctx is done or SIGINT or SIGTERM
+// is received; SIGHUP forces a refresh of the configuration
+func VirtualHostsRun(ctx context.Context, options VirtualHostsOptions) error {
+	tlsConfig := options.TLSConfig
+	defaults := options.Defaults
+
+	container := dependencyinjection.NewBuilder().
+		AddModules(serverIoc.ConfigureServerModules()...).
+		MustBuild()
+
+	resolver := container.Resolver()
+	logger := logsResolver.GetLogger(resolver)
+	configHandler := configResolver.GetFileConfigHandler(resolver, options.ConfigFile, &defaults)
+
+	// the config handler modifies the configuration in place, so it is copied
+	validate := func(config interface{}) (VirtualHostsConfig, error) {
+		conf, ok := config.(*VirtualHostsConfig)
+		if !ok {
+			return VirtualHostsConfig{}, fmt.Errorf("invalid config type")
+		}
+		current := *conf
+		current.Sites = append([]SiteConfig(nil), conf.Sites...)
+		return current, current.Validate()
+	}
+	if _, err := validate(configHandler.GetConfig()); err != nil {
+		return fmt.Errorf("%v: %w", options.ConfigFile, err)
+	}
+
+	// the sites and rate limits outlive the restarts of the listener, applied holds the rest of the configuration in use
+	router := server.NewVirtualHostRouter()
+	rateLimiter := server.NewRateLimiter()
+	handlers := &siteHandlers{}
+	setSites := func(sites []SiteConfig) error {
+		if err := router.Set(handlers.virtualHosts(sites)); err != nil {
+			return err
+		}
+		logger.Infof("Serving %v sites", len(sites))
+		return nil
+	}
+	registry := health.NewRegistry()
+	certificates := &certificateWatch{}
+	defer certificates.close()
+	var applied VirtualHostsConfig
+	var mutex sync.Mutex
+
+	listener, err := serverResolver.GetListenerBuilder(resolver, configHandler).
+		SetBootstrapper(func(config interface{}, serverSetter *server.ServerSetter) error {
+			conf, err := validate(config)
+			if err != nil {
+				return err
+			}
+			applyLog(logger, conf.Log)
+			if err := setSites(conf.Sites); err != nil {
+				return err
+			}
+			if err := rateLimiter.Set(conf.RateLimit); err != nil {
+				return err
+			}
+
+			mutex.Lock()
+			applied = conf
+			applied.Sites, applied.RateLimit = nil, server.RateLimits{}
+			var certificate *tlsreload.Certificate
+			if tlsConfig == nil {
+				certificate, err = certificates.watch(conf.TLS, logger)
+			}
+			mutex.Unlock()
+			if err != nil {
+				return err
+			}
+
+			var handler http.Handler = router
+			if conf.Health.Enabled {
+				handler = withHealth(conf.Health, registry, handler)
+			}
+			serverSetter.Handler = handler
+			serverSetter.Use(rateLimiter.Middleware)
+			serverSetter.Addr = conf.Port
+			serverSetter.TLSConfig = tlsConfig
+			if certificate != nil {
+				serverSetter.TLSConfig = certificate.TLSConfig()
+			}
+			serverSetter.Limits = conf.Limits
+			return nil
+		}).
+
+		// invalid changes are not applied
+		SetConfigValidatorFunc(func(config interface{}) (bool, error) {
+			if _, err := validate(config); err != nil {
+				return false, err
+			}
+			return true, nil
+		}).
+
+		// the sites and rate limits are replaced while serving, any other change restarts the listener
+		SetConfigApplicatorFunc(func(config interface{}, configApplication *server.ConfigApplication) error {
+			conf, err := validate(config)
+			if err != nil {
+				return err
+			}
+			if err := setSites(conf.Sites); err != nil {
+				return err
+			}
+			if err := rateLimiter.Set(conf.RateLimit); err != nil {
+				return err
+			}
+			conf.Sites, conf.RateLimit = nil, server.RateLimits{}
+			mutex.Lock()
+			*configApplication.NeedsRestart = !reflect.DeepEqual(conf, applied)
+			mutex.Unlock()
+			return nil
+		}).
+		GetListener()
+
+	if err != nil {
+		return err
+	}
+	if err := registry.Register(health.Check{Name: "listener", Func: health.ListenerCheck(listener)}); err != nil {
+		return err
+	}
+
+	return server.RunWithConfigReload(ctx, configHandler, listener)
+}
+
+type (
+	// siteHandlers keeps the handlers of the sites in use, so a config change only rebuilds the sites it changes
+	siteHandlers struct {
+		mutex sync.Mutex
+		sites []siteHandler
+	}
+
+	siteHandler struct {
+		config  SiteConfig
+		handler http.Handler
+	}
+)
+
+// virtualHosts returns the virtual hosts of sites, reusing the handlers of the sites whose config did not change
+func (h *siteHandlers) virtualHosts(sites []SiteConfig) []server.VirtualHost {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	built := make([]siteHandler, 0, len(sites))
+	hosts := virtualHosts(sites, func(site SiteConfig) http.Handler {
+		handler := h.find(site)
+		if handler == nil {
+			handler = newSiteHandler(site)
+		}
+		built = append(built, siteHandler{config: site, handler: handler})
+		return handler
+	})
+	h.sites = built
+	return hosts
+}
+
+func (h *siteHandlers) find(site SiteConfig) http.Handler {
+	for _, built := range h.sites {
+		if reflect.DeepEqual(built.config, site) {
+			return built.handler
+		}
+	}
+	return nil
+}
+
+// newSiteHandler serves the files of site, falling back to its index when it is a single page app
+func newSiteHandler(site SiteConfig) http.Handler {
+	opts := cacheOptions(site.Cache)
+	if !site.SPA {
+		opts = append(opts, server.WithFallback(nil))
+	}
+	var handler http.Handler = server.NewSinglePageApp(site.Root, site.index(), opts...)
+	if len(site.Headers) > 0 {
+		handler = withHeaders(copyHeaders(site.Headers), handler)
+	}
+	return handler
+}
+
+// withHeaders sets headers in the responses of handler
+func withHeaders(headers map[string]string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for name, value := range headers {
+			w.Header().Set(name, value)
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func copyHeaders(headers map[string]string) map[string]string {
+	copied := make(map[string]string, len(headers))
+	for name, value := range headers {
+		copied[name] = value
+	}
+	return copied
+}
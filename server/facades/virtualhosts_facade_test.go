@@ -0,0 +1,29 @@
+package facades
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSiteHandlers_VirtualHosts_WhenSitesChange_ThenRebuildsOnlyTheChangedOnes(t *testing.T) {
+	// Arrange
+	app := newSiteRoot(t, map[string]string{"index.html": "app"})
+	docs := newSiteRoot(t, map[string]string{"index.html": "docs"})
+	appSite := SiteConfig{Hosts: []string{"app.example.com"}, Root: app, SPA: true}
+	docsSite := SiteConfig{Hosts: []string{"docs.example.com"}, Root: docs}
+	handlers := &siteHandlers{}
+	before := handlers.virtualHosts([]SiteConfig{appSite, docsSite})
+	docsSite.SPA = true
+
+	// Act
+	after := handlers.virtualHosts([]SiteConfig{appSite, docsSite})
+
+	// Assert
+	require.Len(t, before, 2)
+	require.Len(t, after, 2)
+	assert.Same(t, before[0].Handler, after[0].Handler)
+	assert.NotSame(t, before[1].Handler, after[1].Handler)
+	assert.Len(t, handlers.sites, 2)
+}
@@ -0,0 +1,27 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+)
+
+// newGrpcMultiplexer returns a handler that sends gRPC requests (HTTP/2 with application/grpc content type)
+// to the gRPC server and every other request to the http handler
+func newGrpcMultiplexer(grpcServer *grpc.Server, handler http.Handler) http.Handler {
+	if handler == nil {
+		handler = http.DefaultServeMux
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isGrpcRequest(r) {
+			grpcServer.ServeHTTP(w, r)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func isGrpcRequest(r *http.Request) bool {
+	return r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc")
+}
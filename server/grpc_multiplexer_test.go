@@ -0,0 +1,46 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsGrpcRequest_WhenRequestVaries_ThenDetectsGrpc(t *testing.T) {
+	tests := []struct {
+		name        string
+		protoMajor  int
+		contentType string
+		expected    bool
+	}{
+		{"http2 grpc", 2, "application/grpc", true},
+		{"http2 grpc proto", 2, "application/grpc+proto", true},
+		{"http1 grpc", 1, "application/grpc", false},
+		{"http2 json", 2, "application/json", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			req.ProtoMajor = tt.protoMajor
+			req.Header.Set("Content-Type", tt.contentType)
+			assert.Equal(t, tt.expected, isGrpcRequest(req))
+		})
+	}
+}
+
+func TestNewGrpcMultiplexer_WhenRegularRequest_ThenServesHTTPHandler(t *testing.T) {
+	// Arrange
+	handler := newGrpcMultiplexer(nil, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("http"))
+	}))
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	// Assert
+	assert.Equal(t, "http", w.Body.String())
+}
@@ -0,0 +1,59 @@
+package health
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/janmbaco/go-infrastructure/v2/configuration"
+	"github.com/janmbaco/go-infrastructure/v2/server"
+)
+
+// DatabaseCheck returns a check that pings the database behind db
+func DatabaseCheck(db *gorm.DB) CheckFunc {
+	return func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}
+}
+
+// ListenerCheck returns a check that is healthy only while the listener is serving, so readiness
+// flips to not-ready during Stop() and config-triggered restarts. A ZeroDowntimeRestart keeps it healthy,
+// because the running server serves until the new one takes over
+func ListenerCheck(listener server.Listener) CheckFunc {
+	return func(context.Context) error {
+		state := listener.State()
+		if reporter, ok := listener.(server.ServingReporter); ok && state == server.Restarting && reporter.Serving() {
+			return nil
+		}
+		if state != server.Listening {
+			return fmt.Errorf("listener is %v", state)
+		}
+		return nil
+	}
+}
+
+// ConfigCheck returns a check that validates the config currently applied by configHandler
+func ConfigCheck(configHandler configuration.ConfigHandler, validatorFunc server.ConfigValidatorFunc) CheckFunc {
+	return func(context.Context) error {
+		config := configHandler.GetConfig()
+		if config == nil {
+			return fmt.Errorf("config is not loaded")
+		}
+		if validatorFunc == nil {
+			return nil
+		}
+		valid, err := validatorFunc(config)
+		if err != nil {
+			return err
+		}
+		if !valid {
+			return fmt.Errorf("config is not valid")
+		}
+		return nil
+	}
+}
@@ -0,0 +1,109 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/janmbaco/go-infrastructure/v2/configuration"
+	"github.com/janmbaco/go-infrastructure/v2/server"
+)
+
+type stateListener struct {
+	server.Listener
+	state server.State
+}
+
+func (l *stateListener) State() server.State {
+	return l.state
+}
+
+type staticConfigHandler struct {
+	configuration.ConfigHandler
+	config interface{}
+}
+
+func (h *staticConfigHandler) GetConfig() interface{} {
+	return h.config
+}
+
+func TestListenerCheck_WhenListenerStates_ThenOnlyListeningIsHealthy(t *testing.T) {
+	tests := []struct {
+		state   server.State
+		healthy bool
+	}{
+		{server.Starting, false},
+		{server.Listening, true},
+		{server.Restarting, false},
+		{server.Stopping, false},
+		{server.Stopped, false},
+		{server.Failed, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.state.String(), func(t *testing.T) {
+			err := ListenerCheck(&stateListener{state: tt.state})(context.Background())
+			assert.Equal(t, tt.healthy, err == nil)
+		})
+	}
+}
+
+type servingListener struct {
+	stateListener
+	serving bool
+}
+
+func (l *servingListener) Serving() bool {
+	return l.serving
+}
+
+func TestListenerCheck_WhenListenerRestartsWithoutDowntime_ThenIsHealthy(t *testing.T) {
+	// Arrange
+	zeroDowntime := &servingListener{stateListener: stateListener{state: server.Restarting}, serving: true}
+	stopFirst := &servingListener{stateListener: stateListener{state: server.Restarting}}
+
+	// Act
+	zeroDowntimeErr := ListenerCheck(zeroDowntime)(context.Background())
+	stopFirstErr := ListenerCheck(stopFirst)(context.Background())
+
+	// Assert
+	assert.NoError(t, zeroDowntimeErr)
+	assert.Error(t, stopFirstErr)
+}
+
+func TestConfigCheck_WhenValidatorRejectsConfig_ThenReturnsError(t *testing.T) {
+	// Arrange
+	check := ConfigCheck(&staticConfigHandler{config: &struct{}{}}, func(interface{}) (bool, error) {
+		return false, nil
+	})
+
+	// Act
+	err := check(context.Background())
+
+	// Assert
+	assert.EqualError(t, err, "config is not valid")
+}
+
+func TestConfigCheck_WhenValidatorFails_ThenReturnsItsError(t *testing.T) {
+	// Arrange
+	validationErr := errors.New("port is required")
+	check := ConfigCheck(&staticConfigHandler{config: &struct{}{}}, func(interface{}) (bool, error) {
+		return false, validationErr
+	})
+
+	// Act
+	err := check(context.Background())
+
+	// Assert
+	assert.ErrorIs(t, err, validationErr)
+}
+
+func TestConfigCheck_WhenConfigIsLoadedAndNoValidator_ThenIsHealthy(t *testing.T) {
+	// Act
+	err := ConfigCheck(&staticConfigHandler{config: &struct{}{}}, nil)(context.Background())
+
+	// Assert
+	assert.NoError(t, err)
+}
@@ -0,0 +1,103 @@
+package health
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// LivenessService is the gRPC health service name that reports the liveness checks.
+// The empty service name reports every check and any other name reports the check with that name.
+const LivenessService = "liveness"
+
+// DefaultWatchInterval is the time between evaluations of the checks watched through gRPC
+const DefaultWatchInterval = 5 * time.Second
+
+type grpcHealthServer struct {
+	healthpb.UnimplementedHealthServer
+	registry      *Registry
+	watchInterval time.Duration
+}
+
+// RegisterGrpcHealthServer registers the standard gRPC health service backed by registry
+func RegisterGrpcHealthServer(grpcServer grpc.ServiceRegistrar, registry *Registry) {
+	healthpb.RegisterHealthServer(grpcServer, NewGrpcHealthServer(registry, DefaultWatchInterval))
+}
+
+// NewGrpcHealthServer returns a gRPC health service that evaluates watched checks every watchInterval
+func NewGrpcHealthServer(registry *Registry, watchInterval time.Duration) healthpb.HealthServer {
+	if watchInterval <= 0 {
+		watchInterval = DefaultWatchInterval
+	}
+	return &grpcHealthServer{registry: registry, watchInterval: watchInterval}
+}
+
+// Check gets the health of the requested service
+func (s *grpcHealthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	servingStatus, known := s.servingStatus(ctx, req.GetService())
+	if !known {
+		return nil, status.Errorf(codes.NotFound, "unknown service %q", req.GetService())
+	}
+	return &healthpb.HealthCheckResponse{Status: servingStatus}, nil
+}
+
+// List gets the health of every registered check
+func (s *grpcHealthServer) List(ctx context.Context, _ *healthpb.HealthListRequest) (*healthpb.HealthListResponse, error) {
+	report := s.registry.Readiness(ctx)
+	statuses := make(map[string]*healthpb.HealthCheckResponse, len(report.Checks)+1)
+	statuses[""] = &healthpb.HealthCheckResponse{Status: toServingStatus(report.Status)}
+	for name, result := range report.Checks {
+		statuses[name] = &healthpb.HealthCheckResponse{Status: toServingStatus(result.Status)}
+	}
+	return &healthpb.HealthListResponse{Statuses: statuses}, nil
+}
+
+// Watch sends the health of the requested service every time it changes
+func (s *grpcHealthServer) Watch(req *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	ticker := time.NewTicker(s.watchInterval)
+	defer ticker.Stop()
+
+	last := healthpb.HealthCheckResponse_UNKNOWN
+	first := true
+	for {
+		servingStatus, known := s.servingStatus(stream.Context(), req.GetService())
+		if !known {
+			servingStatus = healthpb.HealthCheckResponse_SERVICE_UNKNOWN
+		}
+		if first || servingStatus != last {
+			if err := stream.Send(&healthpb.HealthCheckResponse{Status: servingStatus}); err != nil {
+				return err
+			}
+			first = false
+			last = servingStatus
+		}
+		select {
+		case <-stream.Context().Done():
+			return status.FromContextError(stream.Context().Err()).Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *grpcHealthServer) servingStatus(ctx context.Context, service string) (healthpb.HealthCheckResponse_ServingStatus, bool) {
+	switch service {
+	case "":
+		return toServingStatus(s.registry.Readiness(ctx).Status), true
+	case LivenessService:
+		return toServingStatus(s.registry.Liveness(ctx).Status), true
+	default:
+		result, known := s.registry.RunCheck(ctx, service)
+		return toServingStatus(result.Status), known
+	}
+}
+
+func toServingStatus(status Status) healthpb.HealthCheckResponse_ServingStatus {
+	if status == StatusUp {
+		return healthpb.HealthCheckResponse_SERVING
+	}
+	return healthpb.HealthCheckResponse_NOT_SERVING
+}
@@ -0,0 +1,98 @@
+package health
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+type fakeWatchServer struct {
+	grpc.ServerStream
+	ctx       context.Context
+	responses chan *healthpb.HealthCheckResponse
+}
+
+func (s *fakeWatchServer) Context() context.Context { return s.ctx }
+
+func (s *fakeWatchServer) Send(response *healthpb.HealthCheckResponse) error {
+	s.responses <- response
+	return nil
+}
+
+func TestGrpcHealthServer_Check_WhenServiceVaries_ThenReportsStatus(t *testing.T) {
+	// Arrange
+	registry := NewRegistry()
+	require.NoError(t, registry.Register(Check{Name: "process", Func: healthy, Liveness: true}))
+	require.NoError(t, registry.Register(Check{Name: "db", Func: failing}))
+	healthServer := NewGrpcHealthServer(registry, time.Second)
+	ctx := context.Background()
+
+	// Act
+	overall, overallErr := healthServer.Check(ctx, &healthpb.HealthCheckRequest{})
+	liveness, livenessErr := healthServer.Check(ctx, &healthpb.HealthCheckRequest{Service: LivenessService})
+	db, dbErr := healthServer.Check(ctx, &healthpb.HealthCheckRequest{Service: "db"})
+	_, unknownErr := healthServer.Check(ctx, &healthpb.HealthCheckRequest{Service: "unknown"})
+
+	// Assert
+	require.NoError(t, overallErr)
+	require.NoError(t, livenessErr)
+	require.NoError(t, dbErr)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, overall.GetStatus())
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, liveness.GetStatus())
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, db.GetStatus())
+	assert.Equal(t, codes.NotFound, status.Code(unknownErr))
+}
+
+func TestGrpcHealthServer_List_WhenChecksRegistered_ThenReturnsEveryStatus(t *testing.T) {
+	// Arrange
+	registry := NewRegistry()
+	require.NoError(t, registry.Register(Check{Name: "db", Func: healthy}))
+	healthServer := NewGrpcHealthServer(registry, time.Second)
+
+	// Act
+	response, err := healthServer.List(context.Background(), &healthpb.HealthListRequest{})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, response.GetStatuses()[""].GetStatus())
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, response.GetStatuses()["db"].GetStatus())
+}
+
+func TestGrpcHealthServer_Watch_WhenStatusChanges_ThenSendsNewStatus(t *testing.T) {
+	// Arrange
+	registry := NewRegistry()
+	up := make(chan bool, 1)
+	up <- true
+	require.NoError(t, registry.Register(Check{Name: "db", Func: func(context.Context) error {
+		select {
+		case <-up:
+			return nil
+		default:
+			return failing(context.Background())
+		}
+	}}))
+	healthServer := NewGrpcHealthServer(registry, 10*time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := &fakeWatchServer{ctx: ctx, responses: make(chan *healthpb.HealthCheckResponse, 10)}
+	done := make(chan error, 1)
+
+	// Act
+	go func() {
+		done <- healthServer.Watch(&healthpb.HealthCheckRequest{Service: "db"}, stream)
+	}()
+	first := <-stream.responses
+	second := <-stream.responses
+	cancel()
+
+	// Assert
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, first.GetStatus())
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, second.GetStatus())
+	assert.Equal(t, codes.Canceled, status.Code(<-done))
+}
@@ -0,0 +1,193 @@
+// Package health provides liveness and readiness checks for listeners.
+//
+// Components register checks with a timeout in a Registry, which exposes them
+// as /healthz and /readyz HTTP handlers with JSON detail and as the standard
+// gRPC health service.
+//
+// Basic usage:
+//
+//	registry := health.NewRegistry()
+//	_ = registry.Register(health.Check{Name: "db", Func: health.DatabaseCheck(db)})
+//	_ = registry.Register(health.Check{Name: "api", Func: health.ListenerCheck(listener)})
+//
+//	mux := http.NewServeMux()
+//	registry.Mount(mux)
+package health
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+type (
+	// CheckFunc reports an error when the checked component is not healthy
+	CheckFunc func(ctx context.Context) error
+
+	// Check is a named health check
+	Check struct {
+		Func CheckFunc
+		Name string
+		// Timeout bounds the check, DefaultTimeout when zero
+		Timeout time.Duration
+		// Liveness includes the check in /healthz; every check is part of /readyz
+		Liveness bool
+	}
+
+	// Status is the status of a check or a report
+	Status string
+
+	// CheckResult is the result of running a check
+	CheckResult struct {
+		Status   Status `json:"status"`
+		Error    string `json:"error,omitempty"`
+		Duration string `json:"duration"`
+	}
+
+	// Report is the result of running a set of checks
+	Report struct {
+		Checks map[string]CheckResult `json:"checks"`
+		Status Status                 `json:"status"`
+	}
+
+	// Registry stores the health checks of an application
+	Registry struct {
+		checks map[string]Check
+		mutex  sync.RWMutex
+	}
+)
+
+const (
+	// StatusUp is the status of a healthy check
+	StatusUp Status = "up"
+	// StatusDown is the status of a failed check
+	StatusDown Status = "down"
+)
+
+// DefaultTimeout is the time a check can run when Check.Timeout is not set
+const DefaultTimeout = 5 * time.Second
+
+// NewRegistry returns an empty Registry
+func NewRegistry() *Registry {
+	return &Registry{checks: make(map[string]Check)}
+}
+
+// Register adds a check to the registry
+func (r *Registry) Register(check Check) error {
+	if check.Name == "" || check.Func == nil {
+		return newRegistryError(InvalidCheckError, "check name and function are required", nil)
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, exists := r.checks[check.Name]; exists {
+		return newRegistryError(DuplicatedCheckError, "check "+check.Name+" is already registered", nil)
+	}
+	r.checks[check.Name] = check
+	return nil
+}
+
+// Unregister removes a check from the registry
+func (r *Registry) Unregister(name string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.checks, name)
+}
+
+// Names returns the names of the registered checks in alphabetical order
+func (r *Registry) Names() []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	names := make([]string, 0, len(r.checks))
+	for name := range r.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Liveness runs the liveness checks
+func (r *Registry) Liveness(ctx context.Context) Report {
+	return r.run(ctx, func(check Check) bool { return check.Liveness })
+}
+
+// Readiness runs every check
+func (r *Registry) Readiness(ctx context.Context) Report {
+	return r.run(ctx, func(Check) bool { return true })
+}
+
+// RunCheck runs a single check by name, reporting false when it is not registered
+func (r *Registry) RunCheck(ctx context.Context, name string) (CheckResult, bool) {
+	r.mutex.RLock()
+	check, exists := r.checks[name]
+	r.mutex.RUnlock()
+	if !exists {
+		return CheckResult{}, false
+	}
+	return runCheck(ctx, check), true
+}
+
+func (r *Registry) run(ctx context.Context, filter func(Check) bool) Report {
+	r.mutex.RLock()
+	checks := make([]Check, 0, len(r.checks))
+	for _, check := range r.checks {
+		if filter(check) {
+			checks = append(checks, check)
+		}
+	}
+	r.mutex.RUnlock()
+
+	report := Report{Status: StatusUp, Checks: make(map[string]CheckResult, len(checks))}
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = runCheck(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	for i, check := range checks {
+		report.Checks[check.Name] = results[i]
+		if results[i].Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
+
+func runCheck(ctx context.Context, check Check) (result CheckResult) {
+	timeout := check.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- newRegistryError(PanicInCheckError, "panic in check", nil)
+			}
+		}()
+		done <- check.Func(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result = CheckResult{Status: StatusUp, Duration: time.Since(start).String()}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
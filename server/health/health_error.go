@@ -0,0 +1,39 @@
+package health
+
+import (
+	"github.com/janmbaco/go-infrastructure/v2/errors"
+)
+
+// RegistryError is the errors of Registry
+type RegistryError interface {
+	errors.CustomError
+	GetErrorType() RegistryErrorType
+}
+
+type registryError struct {
+	errors.CustomizableError
+	ErrorType RegistryErrorType
+}
+
+func newRegistryError(errorType RegistryErrorType, message string, internalError error) RegistryError {
+	return &registryError{
+		CustomizableError: errors.CustomizableError{
+			Message:       message,
+			InternalError: internalError,
+		},
+		ErrorType: errorType,
+	}
+}
+
+func (e *registryError) GetErrorType() RegistryErrorType {
+	return e.ErrorType
+}
+
+type RegistryErrorType uint8
+
+const (
+	UnexpectedError RegistryErrorType = iota
+	InvalidCheckError
+	DuplicatedCheckError
+	PanicInCheckError
+)
@@ -0,0 +1,144 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func healthy(context.Context) error { return nil }
+
+func failing(context.Context) error { return errors.New("unavailable") }
+
+func TestRegistry_Register_WhenNameIsDuplicated_ThenReturnsError(t *testing.T) {
+	// Arrange
+	registry := NewRegistry()
+	require.NoError(t, registry.Register(Check{Name: "db", Func: healthy}))
+
+	// Act
+	err := registry.Register(Check{Name: "db", Func: healthy})
+
+	// Assert
+	var registryErr RegistryError
+	require.ErrorAs(t, err, &registryErr)
+	assert.Equal(t, DuplicatedCheckError, registryErr.GetErrorType())
+}
+
+func TestRegistry_Register_WhenCheckIsIncomplete_ThenReturnsError(t *testing.T) {
+	// Arrange
+	registry := NewRegistry()
+
+	// Act
+	err := registry.Register(Check{Name: "db"})
+
+	// Assert
+	var registryErr RegistryError
+	require.ErrorAs(t, err, &registryErr)
+	assert.Equal(t, InvalidCheckError, registryErr.GetErrorType())
+}
+
+func TestRegistry_Readiness_WhenOneCheckFails_ThenReportIsDown(t *testing.T) {
+	// Arrange
+	registry := NewRegistry()
+	require.NoError(t, registry.Register(Check{Name: "db", Func: healthy}))
+	require.NoError(t, registry.Register(Check{Name: "cache", Func: failing}))
+
+	// Act
+	report := registry.Readiness(context.Background())
+
+	// Assert
+	assert.Equal(t, StatusDown, report.Status)
+	assert.Equal(t, StatusUp, report.Checks["db"].Status)
+	assert.Equal(t, StatusDown, report.Checks["cache"].Status)
+	assert.Equal(t, "unavailable", report.Checks["cache"].Error)
+}
+
+func TestRegistry_Liveness_WhenCheckIsNotLiveness_ThenIgnoresIt(t *testing.T) {
+	// Arrange
+	registry := NewRegistry()
+	require.NoError(t, registry.Register(Check{Name: "process", Func: healthy, Liveness: true}))
+	require.NoError(t, registry.Register(Check{Name: "db", Func: failing}))
+
+	// Act
+	report := registry.Liveness(context.Background())
+
+	// Assert
+	assert.Equal(t, StatusUp, report.Status)
+	assert.Len(t, report.Checks, 1)
+	assert.Contains(t, report.Checks, "process")
+}
+
+func TestRegistry_Readiness_WhenCheckExceedsTimeout_ThenReportsDown(t *testing.T) {
+	// Arrange
+	registry := NewRegistry()
+	require.NoError(t, registry.Register(Check{Name: "slow", Timeout: 10 * time.Millisecond, Func: func(ctx context.Context) error {
+		<-ctx.Done()
+		time.Sleep(50 * time.Millisecond)
+		return nil
+	}}))
+
+	// Act
+	report := registry.Readiness(context.Background())
+
+	// Assert
+	assert.Equal(t, StatusDown, report.Status)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks["slow"].Error)
+}
+
+func TestRegistry_Readiness_WhenCheckPanics_ThenReportsDown(t *testing.T) {
+	// Arrange
+	registry := NewRegistry()
+	require.NoError(t, registry.Register(Check{Name: "broken", Func: func(context.Context) error {
+		panic("boom")
+	}}))
+
+	// Act
+	report := registry.Readiness(context.Background())
+
+	// Assert
+	assert.Equal(t, StatusDown, report.Status)
+}
+
+func TestRegistry_Unregister_WhenRegistered_ThenRemovesCheck(t *testing.T) {
+	// Arrange
+	registry := NewRegistry()
+	require.NoError(t, registry.Register(Check{Name: "db", Func: failing}))
+
+	// Act
+	registry.Unregister("db")
+
+	// Assert
+	assert.Empty(t, registry.Names())
+	assert.Equal(t, StatusUp, registry.Readiness(context.Background()).Status)
+}
+
+func TestRegistry_Mount_WhenChecksFail_ThenReadyzReturnsServiceUnavailable(t *testing.T) {
+	// Arrange
+	registry := NewRegistry()
+	require.NoError(t, registry.Register(Check{Name: "process", Func: healthy, Liveness: true}))
+	require.NoError(t, registry.Register(Check{Name: "db", Func: failing}))
+	mux := http.NewServeMux()
+	registry.Mount(mux)
+
+	// Act
+	healthz := httptest.NewRecorder()
+	mux.ServeHTTP(healthz, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	readyz := httptest.NewRecorder()
+	mux.ServeHTTP(readyz, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	// Assert
+	assert.Equal(t, http.StatusOK, healthz.Code)
+	assert.Equal(t, http.StatusServiceUnavailable, readyz.Code)
+	assert.Equal(t, "application/json", readyz.Header().Get("Content-Type"))
+	var report Report
+	require.NoError(t, json.Unmarshal(readyz.Body.Bytes(), &report))
+	assert.Equal(t, StatusDown, report.Status)
+	assert.Equal(t, "unavailable", report.Checks["db"].Error)
+}
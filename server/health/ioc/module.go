@@ -0,0 +1,21 @@
+package ioc
+
+import (
+	"github.com/janmbaco/go-infrastructure/v2/dependencyinjection"
+	"github.com/janmbaco/go-infrastructure/v2/server/health"
+)
+
+// HealthModule implements Module for health services
+type HealthModule struct{}
+
+// NewHealthModule creates a new health module
+func NewHealthModule() *HealthModule {
+	return &HealthModule{}
+}
+
+// RegisterServices registers all health services
+func (m *HealthModule) RegisterServices(register dependencyinjection.Register) error {
+	dependencyinjection.RegisterSingleton[*health.Registry](register, health.NewRegistry)
+
+	return nil
+}
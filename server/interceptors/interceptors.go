@@ -0,0 +1,118 @@
+// Package interceptors provides gRPC server interceptors for the servers built by
+// server.Listener: panic recovery, logging and mapping of errors.CustomError to gRPC
+// status codes. They are meant for ServerSetter.UnaryInterceptors and StreamInterceptors.
+package interceptors
+
+import (
+	"context"
+	stdErrors "errors"
+	"runtime/debug"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/janmbaco/go-infrastructure/v2/errors"
+	"github.com/janmbaco/go-infrastructure/v2/logs"
+)
+
+// ErrorCodeFunc chooses the gRPC status code of a CustomError
+type ErrorCodeFunc func(err errors.CustomError) codes.Code
+
+// UnaryRecovery recovers from panics in the handlers, logs them with their stack trace
+// and returns an Internal status
+func UnaryRecovery(logger logs.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer recoverPanic(logger, info.FullMethod, &err)
+		return handler(ctx, req)
+	}
+}
+
+// StreamRecovery recovers from panics in the stream handlers, logs them with their stack trace
+// and returns an Internal status
+func StreamRecovery(logger logs.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer recoverPanic(logger, info.FullMethod, &err)
+		return handler(srv, stream)
+	}
+}
+
+func recoverPanic(logger logs.Logger, method string, err *error) {
+	if r := recover(); r != nil {
+		logger.Errorf("panic serving %v: %v\n%s", method, r, debug.Stack())
+		*err = status.Error(codes.Internal, "internal error")
+	}
+}
+
+// UnaryLogging logs every call once it has been served with its method, status code and duration
+func UnaryLogging(logger logs.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logCall(logger, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// StreamLogging logs every stream once it has been served with its method, status code and duration
+func StreamLogging(logger logs.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, stream)
+		logCall(logger, info.FullMethod, start, err)
+		return err
+	}
+}
+
+func logCall(logger logs.Logger, method string, start time.Time, err error) {
+	code := status.Code(err)
+	if err != nil && code != codes.Canceled {
+		logger.Warningf("%v %v %v: %v", method, code, time.Since(start), err)
+		return
+	}
+	logger.Infof("%v %v %v", method, code, time.Since(start))
+}
+
+// UnaryErrorStatus converts the errors.CustomError returned by the handlers into gRPC status
+// errors with the code chosen by codeFunc and the message of the error, so internal errors are
+// not sent to clients. Status errors are returned as they are and context errors become
+// Canceled or DeadlineExceeded. A nil codeFunc maps every CustomError to Unknown.
+func UnaryErrorStatus(codeFunc ErrorCodeFunc) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		return resp, ToStatusError(err, codeFunc)
+	}
+}
+
+// StreamErrorStatus converts the errors returned by the stream handlers like UnaryErrorStatus
+func StreamErrorStatus(codeFunc ErrorCodeFunc) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return ToStatusError(handler(srv, stream), codeFunc)
+	}
+}
+
+// ToStatusError converts err into a gRPC status error as UnaryErrorStatus does
+func ToStatusError(err error, codeFunc ErrorCodeFunc) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	switch {
+	case stdErrors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case stdErrors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+	var customError errors.CustomError
+	if !stdErrors.As(err, &customError) {
+		return err
+	}
+	code := codes.Unknown
+	if codeFunc != nil {
+		code = codeFunc(customError)
+	}
+	return status.Error(code, customError.GetMessage())
+}
@@ -0,0 +1,143 @@
+package interceptors
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/janmbaco/go-infrastructure/v2/errors"
+	"github.com/janmbaco/go-infrastructure/v2/logs"
+)
+
+type recordingLogger struct {
+	logs.Logger
+	mutex   sync.Mutex
+	entries []string
+}
+
+func (l *recordingLogger) Infof(format string, a ...interface{}) {
+	l.record("INFO: "+format, a...)
+}
+
+func (l *recordingLogger) Warningf(format string, a ...interface{}) {
+	l.record("WARNING: "+format, a...)
+}
+
+func (l *recordingLogger) Errorf(format string, a ...interface{}) {
+	l.record("ERROR: "+format, a...)
+}
+
+func (l *recordingLogger) record(format string, a ...interface{}) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.entries = append(l.entries, fmt.Sprintf(format, a...))
+}
+
+var unaryInfo = &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+
+var streamInfo = &grpc.StreamServerInfo{FullMethod: "/test.Service/Stream"}
+
+func TestUnaryRecovery_WhenHandlerPanics_ThenReturnsInternalAndLogs(t *testing.T) {
+	// Arrange
+	logger := &recordingLogger{}
+	interceptor := UnaryRecovery(logger)
+
+	// Act
+	_, err := interceptor(context.Background(), nil, unaryInfo, func(context.Context, interface{}) (interface{}, error) {
+		panic("boom")
+	})
+
+	// Assert
+	assert.Equal(t, codes.Internal, status.Code(err))
+	require.Len(t, logger.entries, 1)
+	assert.True(t, strings.HasPrefix(logger.entries[0], "ERROR: panic serving /test.Service/Method: boom"))
+}
+
+func TestStreamRecovery_WhenHandlerPanics_ThenReturnsInternal(t *testing.T) {
+	// Arrange
+	interceptor := StreamRecovery(&recordingLogger{})
+
+	// Act
+	err := interceptor(nil, nil, streamInfo, func(interface{}, grpc.ServerStream) error {
+		panic("boom")
+	})
+
+	// Assert
+	assert.Equal(t, codes.Internal, status.Code(err))
+}
+
+func TestUnaryLogging_WhenCallsServed_ThenLogsMethodAndCode(t *testing.T) {
+	// Arrange
+	logger := &recordingLogger{}
+	interceptor := UnaryLogging(logger)
+
+	// Act
+	_, _ = interceptor(context.Background(), nil, unaryInfo, func(context.Context, interface{}) (interface{}, error) {
+		return "ok", nil
+	})
+	_, _ = interceptor(context.Background(), nil, unaryInfo, func(context.Context, interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "missing")
+	})
+
+	// Assert
+	require.Len(t, logger.entries, 2)
+	assert.True(t, strings.HasPrefix(logger.entries[0], "INFO: /test.Service/Method OK "))
+	assert.True(t, strings.HasPrefix(logger.entries[1], "WARNING: /test.Service/Method NotFound "))
+}
+
+func TestToStatusError_WhenErrorsVary_ThenMapsToStatusCodes(t *testing.T) {
+	codeFunc := func(err errors.CustomError) codes.Code {
+		if err.GetMessage() == "not found" {
+			return codes.NotFound
+		}
+		return codes.Internal
+	}
+	custom := &errors.CustomizableError{Message: "not found", InternalError: fmt.Errorf("sql: no rows")}
+	tests := []struct {
+		name     string
+		err      error
+		codeFunc ErrorCodeFunc
+		code     codes.Code
+		message  string
+	}{
+		{"nil", nil, codeFunc, codes.OK, ""},
+		{"custom", custom, codeFunc, codes.NotFound, "not found"},
+		{"wrapped custom", fmt.Errorf("repository: %w", custom), codeFunc, codes.NotFound, "not found"},
+		{"custom without code func", custom, nil, codes.Unknown, "not found"},
+		{"status", status.Error(codes.PermissionDenied, "denied"), codeFunc, codes.PermissionDenied, "denied"},
+		{"deadline", context.DeadlineExceeded, codeFunc, codes.DeadlineExceeded, context.DeadlineExceeded.Error()},
+		{"plain", fmt.Errorf("plain"), codeFunc, codes.Unknown, "plain"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ToStatusError(tt.err, tt.codeFunc)
+
+			st := status.Convert(err)
+			assert.Equal(t, tt.code, st.Code())
+			assert.Equal(t, tt.message, st.Message())
+		})
+	}
+}
+
+func TestStreamErrorStatus_WhenHandlerReturnsCustomError_ThenReturnsStatus(t *testing.T) {
+	// Arrange
+	interceptor := StreamErrorStatus(func(errors.CustomError) codes.Code { return codes.FailedPrecondition })
+
+	// Act
+	err := interceptor(nil, nil, streamInfo, func(interface{}, grpc.ServerStream) error {
+		return &errors.CustomizableError{Message: "config is frozen"}
+	})
+
+	// Assert
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	assert.Equal(t, "config is frozen", status.Convert(err).Message())
+}
@@ -0,0 +1,43 @@
+package interceptors
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+
+	"github.com/janmbaco/go-infrastructure/v2/metrics"
+)
+
+// UnaryMetrics counts the calls in registry by method and status code, with their duration in seconds:
+// grpc_server_handled_total and grpc_server_handling_seconds
+func UnaryMetrics(registry *metrics.Registry) grpc.UnaryServerInterceptor {
+	observe := callMetrics(registry)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		observe(info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// StreamMetrics counts the streams in registry like UnaryMetrics, with the duration of the whole stream
+func StreamMetrics(registry *metrics.Registry) grpc.StreamServerInterceptor {
+	observe := callMetrics(registry)
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, stream)
+		observe(info.FullMethod, start, err)
+		return err
+	}
+}
+
+func callMetrics(registry *metrics.Registry) func(method string, start time.Time, err error) {
+	handled := registry.MustCounter("grpc_server_handled_total", "gRPC calls handled by method and status code.", "method", "code")
+	durations := registry.MustHistogram("grpc_server_handling_seconds", "Duration of the gRPC calls in seconds by method.", metrics.DefaultBuckets, "method")
+	return func(method string, start time.Time, err error) {
+		handled.Inc(method, status.Code(err).String())
+		durations.Observe(time.Since(start).Seconds(), method)
+	}
+}
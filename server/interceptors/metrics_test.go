@@ -0,0 +1,37 @@
+package interceptors
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/janmbaco/go-infrastructure/v2/metrics"
+)
+
+func TestUnaryAndStreamMetrics_WhenCallsAreHandled_ThenCountsThemByMethodAndCode(t *testing.T) {
+	// Arrange
+	registry := metrics.NewRegistry()
+	unary := UnaryMetrics(registry)
+	stream := StreamMetrics(registry)
+
+	// Act
+	_, _ = unary(context.Background(), nil, unaryInfo, func(context.Context, interface{}) (interface{}, error) { return "ok", nil })
+	_, _ = unary(context.Background(), nil, unaryInfo, func(context.Context, interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "missing")
+	})
+	_ = stream(nil, nil, streamInfo, func(interface{}, grpc.ServerStream) error { return nil })
+
+	// Assert
+	var out strings.Builder
+	require.NoError(t, registry.Write(&out))
+	assert.Contains(t, out.String(), `grpc_server_handled_total{method="/test.Service/Method",code="OK"} 1`)
+	assert.Contains(t, out.String(), `grpc_server_handled_total{method="/test.Service/Method",code="NotFound"} 1`)
+	assert.Contains(t, out.String(), `grpc_server_handled_total{method="/test.Service/Stream",code="OK"} 1`)
+	assert.Contains(t, out.String(), `grpc_server_handling_seconds_count{method="/test.Service/Method"} 2`)
+}
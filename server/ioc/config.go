@@ -8,6 +8,9 @@ import (
 	errorsIoc "github.com/janmbaco/go-infrastructure/v2/errors/ioc"
 	eventsIoc "github.com/janmbaco/go-infrastructure/v2/eventsmanager/ioc"
 	logsIoc "github.com/janmbaco/go-infrastructure/v2/logs/ioc"
+	metricsIoc "github.com/janmbaco/go-infrastructure/v2/metrics/ioc"
+	healthIoc "github.com/janmbaco/go-infrastructure/v2/server/health/ioc"
+	tlsreloadIoc "github.com/janmbaco/go-infrastructure/v2/server/tlsreload/ioc"
 )
 
 // ConfigureServerModules returns all base modules needed for server functionality
@@ -19,6 +22,9 @@ func ConfigureServerModules() []dependencyinjection.Module {
 		diskIoc.NewDiskModule(),
 		ioc.NewConfigurationModule(),
 		cryptoIoc.NewCryptoModule(),
+		metricsIoc.NewMetricsModule(),
 		NewServerModule(),
+		healthIoc.NewHealthModule(),
+		tlsreloadIoc.NewTLSReloadModule(),
 	}
 }
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/janmbaco/go-infrastructure/v2/disk/fdlimit"
+)
+
+const (
+	// DefaultReadHeaderTimeout is the time allowed to read request headers when Limits.ReadHeaderTimeout is not set
+	DefaultReadHeaderTimeout = 10 * time.Second
+	// DefaultConnectionHeadroom is the number of file descriptors left for other uses when the maximum
+	// number of connections is derived from the file descriptor limit with Limits.AutoMaxConnections
+	DefaultConnectionHeadroom = 64
+)
+
+var durationType = reflect.TypeOf(Duration(0))
+
+type (
+	// Duration is a time.Duration read from JSON as a string like "30s" or a number of nanoseconds
+	Duration time.Duration
+
+	// Limits are the timeouts and limits of a server. Configs can embed it to bind them from JSON,
+	// and the bootstrapper copies it to the ServerSetter
+	Limits struct {
+		// ReadTimeout, ReadHeaderTimeout, WriteTimeout, IdleTimeout and MaxHeaderBytes set the
+		// http.Server fields of the same name. ReadHeaderTimeout is DefaultReadHeaderTimeout when zero
+		ReadTimeout       Duration `json:"read_timeout,omitempty"`
+		ReadHeaderTimeout Duration `json:"read_header_timeout,omitempty"`
+		WriteTimeout      Duration `json:"write_timeout,omitempty"`
+		IdleTimeout       Duration `json:"idle_timeout,omitempty"`
+		MaxHeaderBytes    int      `json:"max_header_bytes,omitempty"`
+		// MaxConnections is the number of connections open at once, further connections wait in the
+		// accept queue. There is no limit when it is zero or negative, unless AutoMaxConnections is set
+		MaxConnections int `json:"max_connections,omitempty"`
+		// ConnectionHeadroom is DefaultConnectionHeadroom when zero
+		ConnectionHeadroom int `json:"connection_headroom,omitempty"`
+		// AutoMaxConnections derives the limit, when MaxConnections is zero, from the file descriptor
+		// limit minus ConnectionHeadroom
+		AutoMaxConnections bool `json:"auto_max_connections,omitempty"`
+	}
+)
+
+// MarshalJSON writes the duration as a string like "1m30s"
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON reads a string parsed by time.ParseDuration or a number of nanoseconds
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case float64:
+		*d = Duration(v)
+	case string:
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*d = Duration(parsed)
+	default:
+		return &json.UnmarshalTypeError{Value: string(data), Type: durationType}
+	}
+	return nil
+}
+
+// maxConnections returns the connection limit, zero or negative meaning no limit
+func (limits Limits) maxConnections() int {
+	if limits.MaxConnections != 0 || !limits.AutoMaxConnections {
+		return limits.MaxConnections
+	}
+	fds, err := fdlimit.Get()
+	if err != nil {
+		return 0
+	}
+	headroom := limits.ConnectionHeadroom
+	if headroom == 0 {
+		headroom = DefaultConnectionHeadroom
+	}
+	return fds - headroom
+}
@@ -0,0 +1,135 @@
+package server
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/janmbaco/go-infrastructure/v2/disk/fdlimit"
+	"github.com/janmbaco/go-infrastructure/v2/logs"
+)
+
+func TestLimits_WhenBoundFromJSON_ThenParsesDurations(t *testing.T) {
+	// Arrange
+	type config struct {
+		Addr   string `json:"addr"`
+		Limits Limits `json:"limits"`
+	}
+	content := `{"addr": ":8080", "limits": {"read_timeout": "5s", "write_timeout": 1000000000, "idle_timeout": "2m", "max_header_bytes": 4096, "max_connections": 100}}`
+	var cfg config
+
+	// Act
+	err := json.Unmarshal([]byte(content), &cfg)
+	written, marshalErr := json.Marshal(cfg.Limits)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, Duration(5*time.Second), cfg.Limits.ReadTimeout)
+	assert.Equal(t, Duration(time.Second), cfg.Limits.WriteTimeout)
+	assert.Equal(t, Duration(2*time.Minute), cfg.Limits.IdleTimeout)
+	assert.Equal(t, 4096, cfg.Limits.MaxHeaderBytes)
+	assert.Equal(t, 100, cfg.Limits.MaxConnections)
+	require.NoError(t, marshalErr)
+	assert.JSONEq(t, `{"read_timeout": "5s", "write_timeout": "1s", "idle_timeout": "2m0s", "max_header_bytes": 4096, "max_connections": 100}`, string(written))
+}
+
+func TestDuration_UnmarshalJSON_WhenInvalid_ThenReturnsError(t *testing.T) {
+	for _, content := range []string{`"five seconds"`, `true`} {
+		// Arrange
+		var d Duration
+
+		// Act
+		err := json.Unmarshal([]byte(content), &d)
+
+		// Assert
+		assert.Error(t, err, content)
+	}
+}
+
+func TestLimits_maxConnections_WhenAutoMaxConnections_ThenDerivesFromFileDescriptorLimit(t *testing.T) {
+	// Arrange
+	fds, err := fdlimit.Get()
+	require.NoError(t, err)
+
+	// Act
+	notSet := Limits{}.maxConnections()
+	derived := Limits{AutoMaxConnections: true}.maxConnections()
+	withHeadroom := Limits{AutoMaxConnections: true, ConnectionHeadroom: 10}.maxConnections()
+	explicit := Limits{MaxConnections: 5, AutoMaxConnections: true, ConnectionHeadroom: 10}.maxConnections()
+	unlimited := Limits{MaxConnections: -1, AutoMaxConnections: true}.maxConnections()
+
+	// Assert
+	assert.Zero(t, notSet)
+	assert.Equal(t, fds-DefaultConnectionHeadroom, derived)
+	assert.Equal(t, fds-10, withHeadroom)
+	assert.Equal(t, 5, explicit)
+	assert.Equal(t, -1, unlimited)
+}
+
+func TestListener_newHTTPServer_WhenLimitsSet_ThenConfiguresServer(t *testing.T) {
+	// Arrange
+	l := &listener{logger: logs.NewLogger()}
+	serverSetter := &ServerSetter{Addr: ":8080", Limits: Limits{
+		ReadTimeout:    Duration(time.Second),
+		WriteTimeout:   Duration(2 * time.Second),
+		IdleTimeout:    Duration(3 * time.Second),
+		MaxHeaderBytes: 2048,
+	}}
+
+	// Act
+	httpServer := l.newHTTPServer(serverSetter, http.NotFoundHandler())
+
+	// Assert
+	assert.Equal(t, time.Second, httpServer.ReadTimeout)
+	assert.Equal(t, DefaultReadHeaderTimeout, httpServer.ReadHeaderTimeout)
+	assert.Equal(t, 2*time.Second, httpServer.WriteTimeout)
+	assert.Equal(t, 3*time.Second, httpServer.IdleTimeout)
+	assert.Equal(t, 2048, httpServer.MaxHeaderBytes)
+}
+
+func TestConnectionDispatcher_WhenMaxConnectionsReached_ThenWaitsForAConnectionToClose(t *testing.T) {
+	// Arrange
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	dispatcher := newConnectionDispatcher(lis, lis.Addr().String(), false)
+	dispatcher.limiter.setLimit(1)
+	tracker := dispatcher.newListener()
+	defer func() { _ = tracker.Close() }()
+	first, err := net.Dial("tcp", lis.Addr().String())
+	require.NoError(t, err)
+	defer func() { _ = first.Close() }()
+	accepted, err := tracker.Accept()
+	require.NoError(t, err)
+	second, err := net.Dial("tcp", lis.Addr().String())
+	require.NoError(t, err)
+	defer func() { _ = second.Close() }()
+	next := make(chan net.Conn, 1)
+	go func() {
+		conn, _ := tracker.Accept()
+		next <- conn
+	}()
+
+	// Act
+	var waiting bool
+	select {
+	case <-next:
+	case <-time.After(100 * time.Millisecond):
+		waiting = true
+	}
+	require.NoError(t, accepted.Close())
+
+	// Assert
+	assert.True(t, waiting)
+	select {
+	case conn := <-next:
+		require.NotNil(t, conn)
+		_ = conn.Close()
+	case <-time.After(time.Second):
+		t.Fatal("the second connection was not accepted after the first one closed")
+	}
+}
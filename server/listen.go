@@ -0,0 +1,144 @@
+package server
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// UnixAddrPrefix makes ServerSetter.Addr the path of a unix domain socket, like unix:/run/app.sock
+	UnixAddrPrefix = "unix:"
+	// SystemdAddrPrefix makes ServerSetter.Addr a socket passed by systemd socket activation (LISTEN_FDS):
+	// "systemd:" is the first one, "systemd:2" the third one and "systemd:web" the one named web in LISTEN_FDNAMES
+	SystemdAddrPrefix = "systemd:"
+)
+
+type (
+	// inheritedListeners are the sockets passed by systemd, read once from the environment
+	inheritedListeners struct {
+		listeners []net.Listener
+		names     []string
+		err       error
+		once      sync.Once
+	}
+)
+
+// listenFdsStart is the first file descriptor passed by systemd
+var listenFdsStart = 3
+
+var inherited = &inheritedListeners{}
+
+// listen returns the net.Listener to serve for the ServerSetter and whether it must be kept open across
+// restarts, because it can not be bound again
+func listen(serverSetter *ServerSetter) (net.Listener, bool, error) {
+	switch {
+	case serverSetter.Listener != nil:
+		return serverSetter.Listener, true, nil
+	case strings.HasPrefix(serverSetter.Addr, SystemdAddrPrefix):
+		lis, err := inherited.get(strings.TrimPrefix(serverSetter.Addr, SystemdAddrPrefix))
+		return lis, true, err
+	case strings.HasPrefix(serverSetter.Addr, UnixAddrPrefix):
+		lis, err := listenUnix(strings.TrimPrefix(serverSetter.Addr, UnixAddrPrefix), serverSetter.SocketMode)
+		return lis, false, err
+	default:
+		lis, err := net.Listen("tcp", serverSetter.Addr)
+		return lis, false, err
+	}
+}
+
+func listenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+	lis, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if mode != 0 {
+		if err := os.Chmod(path, mode); err != nil {
+			_ = lis.Close() //nolint:errcheck // the listener is being discarded
+			return nil, newListenerError(ListenFailed, "failed to set the permissions of "+path, err)
+		}
+	}
+	return lis, nil
+}
+
+// removeStaleSocket removes a socket file left by a process that did not close it,
+// a socket that still accepts connections is in use
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return newListenerError(ListenFailed, "failed to stat "+path, err)
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return newListenerError(ListenFailed, path+" exists and is not a socket", nil)
+	}
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		_ = conn.Close() //nolint:errcheck // the connection only probes the socket
+		return newListenerError(ListenFailed, path+" is in use", nil)
+	}
+	if err := os.Remove(path); err != nil {
+		return newListenerError(ListenFailed, "failed to remove the stale socket "+path, err)
+	}
+	return nil
+}
+
+// get returns the inherited socket by name or index, the first one when spec is empty
+func (il *inheritedListeners) get(spec string) (net.Listener, error) {
+	il.once.Do(il.load)
+	if il.err != nil {
+		return nil, il.err
+	}
+	if len(il.listeners) == 0 {
+		return nil, newListenerError(ListenFailed, "no sockets were passed by systemd (LISTEN_FDS)", nil)
+	}
+	if spec == "" {
+		spec = "0"
+	}
+	for i, name := range il.names {
+		if name == spec && il.listeners[i] != nil {
+			return il.listeners[i], nil
+		}
+	}
+	if index, err := strconv.Atoi(spec); err == nil && index >= 0 && index < len(il.listeners) && il.listeners[index] != nil {
+		return il.listeners[index], nil
+	}
+	return nil, newListenerError(ListenFailed, "systemd did not pass the socket "+spec, nil)
+}
+
+// load reads LISTEN_PID, LISTEN_FDS and LISTEN_FDNAMES and unsets them, so child processes do not inherit them
+func (il *inheritedListeners) load() {
+	pid, fds := os.Getenv("LISTEN_PID"), os.Getenv("LISTEN_FDS")
+	names := os.Getenv("LISTEN_FDNAMES")
+	_ = os.Unsetenv("LISTEN_PID")     //nolint:errcheck // unsetting can not fail
+	_ = os.Unsetenv("LISTEN_FDS")     //nolint:errcheck // unsetting can not fail
+	_ = os.Unsetenv("LISTEN_FDNAMES") //nolint:errcheck // unsetting can not fail
+	if fds == "" || pid != strconv.Itoa(os.Getpid()) {
+		return
+	}
+	count, err := strconv.Atoi(fds)
+	if err != nil || count < 0 {
+		il.err = newListenerError(ListenFailed, "invalid LISTEN_FDS "+fds, err)
+		return
+	}
+	if names != "" {
+		il.names = strings.Split(names, ":")
+	}
+	for i := 0; i < count; i++ {
+		file := os.NewFile(uintptr(listenFdsStart+i), "LISTEN_FD_"+strconv.Itoa(listenFdsStart+i))
+		// datagram sockets and other descriptors are not listeners, they are left nil
+		lis, err := net.FileListener(file)
+		_ = file.Close() //nolint:errcheck // FileListener keeps its own copy of the descriptor
+		if err != nil {
+			lis = nil
+		}
+		il.listeners = append(il.listeners, lis)
+	}
+}
@@ -0,0 +1,114 @@
+//go:build !windows
+
+package server
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListen_WhenUnixAddressWithSocketMode_ThenSetsPermissions(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "app.sock")
+
+	// Act
+	lis, persistent, err := listen(&ServerSetter{Addr: UnixAddrPrefix + path, SocketMode: 0o600})
+
+	// Assert
+	require.NoError(t, err)
+	defer func() { _ = lis.Close() }()
+	assert.False(t, persistent)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
+
+func TestListen_WhenUnixSocketIsStale_ThenRemovesIt(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "app.sock")
+	stale, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, stale.Close())
+
+	// Act
+	lis, _, err := listen(&ServerSetter{Addr: UnixAddrPrefix + path})
+
+	// Assert
+	require.NoError(t, err)
+	defer func() { _ = lis.Close() }()
+	conn, err := net.Dial("unix", path)
+	require.NoError(t, err)
+	_ = conn.Close()
+}
+
+func TestListen_WhenUnixSocketIsInUseOrNotASocket_ThenReturnsListenFailed(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	inUse := filepath.Join(dir, "in-use.sock")
+	running, err := net.Listen("unix", inUse)
+	require.NoError(t, err)
+	defer func() { _ = running.Close() }()
+	regular := filepath.Join(dir, "regular.sock")
+	require.NoError(t, os.WriteFile(regular, nil, 0o600))
+
+	for _, path := range []string{inUse, regular} {
+		// Act
+		_, _, err := listen(&ServerSetter{Addr: UnixAddrPrefix + path})
+
+		// Assert
+		var listenerErr ListenerError
+		require.ErrorAs(t, err, &listenerErr, path)
+		assert.Equal(t, ListenFailed, listenerErr.GetErrorType(), path)
+	}
+	_, statErr := os.Stat(regular)
+	assert.NoError(t, statErr)
+}
+
+func TestListen_WhenSystemdPassesSockets_ThenReturnsThemByNameAndIndex(t *testing.T) {
+	// Arrange
+	tcp, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = tcp.Close() }()
+	file, err := tcp.(*net.TCPListener).File()
+	require.NoError(t, err)
+	// the descriptor systemd would pass, closed by the listener when it reads it
+	fd, err := syscall.Dup(int(file.Fd()))
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+	previousStart := listenFdsStart
+	listenFdsStart = fd
+	inherited = &inheritedListeners{}
+	defer func() {
+		listenFdsStart = previousStart
+		inherited = &inheritedListeners{}
+	}()
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+	t.Setenv("LISTEN_FDS", "1")
+	t.Setenv("LISTEN_FDNAMES", "web")
+
+	// Act
+	byName, persistent, err := listen(&ServerSetter{Addr: SystemdAddrPrefix + "web"})
+	require.NoError(t, err)
+	first, _, firstErr := listen(&ServerSetter{Addr: SystemdAddrPrefix})
+	_, _, missingErr := listen(&ServerSetter{Addr: SystemdAddrPrefix + "1"})
+
+	// Assert
+	defer func() { _ = byName.Close() }()
+	assert.True(t, persistent)
+	assert.Equal(t, tcp.Addr().String(), byName.Addr().String())
+	require.NoError(t, firstErr)
+	assert.Same(t, byName, first)
+	var listenerErr ListenerError
+	require.ErrorAs(t, missingErr, &listenerErr)
+	assert.Equal(t, ListenFailed, listenerErr.GetErrorType())
+	_, set := os.LookupEnv("LISTEN_FDS")
+	assert.False(t, set)
+}
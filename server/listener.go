@@ -3,22 +3,38 @@ package server
 import (
 	"context"
 	"crypto/tls"
+	"fmt"
 	"net"
 	"net/http"
+	"os"
 	"reflect"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/janmbaco/go-infrastructure/v2/configuration"
 	"github.com/janmbaco/go-infrastructure/v2/errors"
+	"github.com/janmbaco/go-infrastructure/v2/eventsmanager"
 	"github.com/janmbaco/go-infrastructure/v2/logs"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/reflection"
 )
 
 type (
 	Listener interface {
 		Start() chan ListenerError
+		// Stop stops the server waiting for in-flight requests up to ServerSetter.ShutdownTimeout
 		Stop()
+		// StopCtx stops the server waiting for in-flight requests until ctx is done,
+		// then closes the connections still open and reports them in the returned ListenerError
+		StopCtx(ctx context.Context) error
+		// State returns the current state of the listener
+		State() State
+		// StateChangedSubscribe subscribes a function to the state transitions of the listener
+		StateChangedSubscribe(subscribeFunc func(StateChangedEvent)) error
+		// StateChangedUnsubscribe removes a subscription to the state transitions of the listener
+		StateChangedUnsubscribe(subscribeFunc func(StateChangedEvent)) error
 	}
 
 	GrpcDefinitionsFunc func(grpcServer *grpc.Server)
@@ -32,13 +48,37 @@ type (
 		Handler      http.Handler
 		TLSConfig    *tls.Config
 		TLSNextProto map[string]func(*http.Server, *tls.Conn, http.Handler)
+		// Middlewares wrap Handler, the first one being the outermost. In HTTPGrpcServer
+		// they apply to the HTTP requests only, gRPC requests are dispatched before them.
+		Middlewares []func(http.Handler) http.Handler
+		// UnaryInterceptors and StreamInterceptors are chained in order on the gRPC server
+		UnaryInterceptors  []grpc.UnaryServerInterceptor
+		StreamInterceptors []grpc.StreamServerInterceptor
+		// GrpcOptions are passed to grpc.NewServer, e.g. keepalive or message size limits
+		GrpcOptions []grpc.ServerOption
+		// Listener is served instead of binding Addr. It is kept open across restarts and when the
+		// listener stops, so it can be started again; the caller closes it. A restart that serves
+		// another socket closes it
+		Listener net.Listener
 
 		// Strings
 		Name string
+		// Addr is a TCP address like :8080, a unix domain socket like unix:/run/app.sock or
+		// a socket passed by systemd like systemd:web, see UnixAddrPrefix and SystemdAddrPrefix
 		Addr string
 
 		// Primitives
-		ServerType ServerType
+		// ShutdownTimeout is the time Stop waits for in-flight requests, DefaultShutdownTimeout when zero
+		ShutdownTimeout time.Duration
+		ServerType      ServerType
+		// RestartMode is how the server restarts when a config change needs it
+		RestartMode RestartMode
+		// Limits are the timeouts and limits of the server
+		Limits
+		// SocketMode is the permission set on a unix domain socket, the umask applies when zero
+		SocketMode os.FileMode
+		// GrpcReflection registers the gRPC server reflection service
+		GrpcReflection bool
 	}
 
 	BootstrapperFunc func(config interface{}, serverSetter *ServerSetter) error
@@ -51,45 +91,89 @@ type (
 
 	ConfigApplicatorFunc func(config interface{}, configApplication *ConfigApplication) error
 
+	RestartMode uint8
+
+	// servers are the servers built from one bootstrap of the ServerSetter
+	servers struct {
+		serverSetter *ServerSetter
+		httpServer   *http.Server
+		grpcServer   *grpc.Server
+		connections  *connectionTracker
+		dispatcher   *connectionDispatcher
+	}
+
 	listener struct {
+		servers
 		configHandler        configuration.ConfigHandler
 		logger               logs.Logger
 		errorCatcher         errors.ErrorCatcher
-		serverSetter         *ServerSetter
-		httpServer           *http.Server
-		grpcServer           *grpc.Server
 		bootstrapperFunc     BootstrapperFunc
 		grpcDefinitionsFunc  GrpcDefinitionsFunc
 		configValidatorFunc  ConfigValidatorFunc
 		configApplicatorFunc ConfigApplicatorFunc
+		restarts             *restartBudget
+		metrics              *listenerMetrics
+		stateSubscriptions   eventsmanager.Subscriptions[StateChangedEvent]
+		statePublisher       eventsmanager.Publisher[StateChangedEvent]
 		start                chan bool
 		started              chan bool
 		stop                 chan bool
 		finish               chan ListenerError
+		served               chan error
 		isBusy               chan bool
-		stopped              bool
+		stateMutex           sync.RWMutex
+		serversMutex         sync.Mutex
+		state                State
+		// restartKeepsServing is set while a ZeroDowntimeRestart keeps the running server
+		restartKeepsServing bool
+		stopped             atomic.Bool
+		// prepared is set when restart has already built and bound the next servers
+		prepared bool
+		// loopDone is closed when the loop of the last start ends
+		loopDone chan struct{}
 	}
 )
 
 const (
 	HTTPServer ServerType = iota
 	GRpcSever
+	// HTTPGrpcServer serves gRPC and regular HTTP on the same address
+	HTTPGrpcServer
+)
+
+const (
+	// StopFirstRestart stops the running server before the new one binds the address
+	StopFirstRestart RestartMode = iota
+	// ZeroDowntimeRestart starts the new server before draining the running one, taking over
+	// its net.Listener when the address is unchanged, and keeps the running server if the new
+	// one can not be bootstrapped or bound
+	ZeroDowntimeRestart
 )
 
-func newListener(configHandler configuration.ConfigHandler, logger logs.Logger, errorCatcher errors.ErrorCatcher, bootstrapperFunc BootstrapperFunc, grpdDefinitionsFunc GrpcDefinitionsFunc, validationFunc ConfigValidatorFunc, applicationFunc ConfigApplicatorFunc) Listener {
+// DefaultShutdownTimeout is the time Stop waits for in-flight requests when ServerSetter.ShutdownTimeout is not set
+const DefaultShutdownTimeout = 30 * time.Second
+
+func newListener(configHandler configuration.ConfigHandler, logger logs.Logger, errorCatcher errors.ErrorCatcher, bootstrapperFunc BootstrapperFunc, grpdDefinitionsFunc GrpcDefinitionsFunc, validationFunc ConfigValidatorFunc, applicationFunc ConfigApplicatorFunc, restartPolicy RestartPolicy, metrics *listenerMetrics) Listener {
+	stateSubscriptions := eventsmanager.NewSubscriptions[StateChangedEvent]()
 	listener := &listener{
 		configHandler:        configHandler,
 		logger:               logger,
 		errorCatcher:         errorCatcher,
-		serverSetter:         &ServerSetter{},
+		servers:              servers{serverSetter: &ServerSetter{}},
 		bootstrapperFunc:     bootstrapperFunc,
 		grpcDefinitionsFunc:  grpdDefinitionsFunc,
 		configValidatorFunc:  validationFunc,
 		configApplicatorFunc: applicationFunc,
+		restarts:             newRestartBudget(restartPolicy),
+		metrics:              metrics,
+		stateSubscriptions:   stateSubscriptions,
+		statePublisher:       eventsmanager.NewPublisher(stateSubscriptions, logger),
+		state:                Stopped,
 		start:                make(chan bool, 1),
 		started:              make(chan bool, 1),
 		stop:                 make(chan bool, 1),
 		finish:               make(chan ListenerError, 1),
+		served:               make(chan error, 1),
 		isBusy:               make(chan bool, 1),
 	}
 	restoredConfig := listener.onRestoredConfig
@@ -100,23 +184,67 @@ func newListener(configHandler configuration.ConfigHandler, logger logs.Logger,
 	return listener
 }
 
+// Use appends middlewares to wrap the Handler, the first one being the outermost
+func (ss *ServerSetter) Use(middlewares ...func(http.Handler) http.Handler) {
+	ss.Middlewares = append(ss.Middlewares, middlewares...)
+}
+
+// address returns the address to log, the one of Listener when it is set
+func (ss *ServerSetter) address() string {
+	if ss.Listener != nil {
+		return ss.Listener.Addr().String()
+	}
+	return ss.Addr
+}
+
+// handler returns the Handler wrapped by the Middlewares
+func (ss *ServerSetter) handler() http.Handler {
+	handler := ss.Handler
+	if handler == nil && len(ss.Middlewares) > 0 {
+		handler = http.DefaultServeMux
+	}
+	for i := len(ss.Middlewares) - 1; i >= 0; i-- {
+		handler = ss.Middlewares[i](handler)
+	}
+	return handler
+}
+
 func (l *listener) Start() chan ListenerError {
-	l.stopped = false
-	go l.startLoop()
+	if l.loopDone != nil {
+		// the loop of the previous start ends right after reporting finish
+		<-l.loopDone
+	}
+	l.loopDone = make(chan struct{})
+	l.stopped.Store(false)
+	l.restarts.reset()
+	l.setState(Starting, nil)
+	go l.startLoop(l.loopDone)
 	l.start <- true
 	<-l.started
 	return l.finish
 }
 
 func (l *listener) Stop() {
+	ctx, cancel := l.shutdownContext()
+	defer cancel()
+	_ = l.StopCtx(ctx) //nolint:errcheck // forced shutdowns are already logged
+}
+
+func (l *listener) StopCtx(ctx context.Context) error {
+	l.restarts.stop()
 	l.isBusy <- true
-	l.logger.Infof("%v - Server Stop", l.serverSetter.Name)
-	_ = l.stopServer() //nolint:errcheck // server shutdown errors are not recoverable
+	running := l.currentServers()
+	l.logger.Infof("%v - Server Stop", running.serverSetter.Name)
+	l.setState(Stopping, nil)
+	err := l.stopServer(ctx, &running)
+	running.dispatcher.stop()
 	l.stop <- true
 	<-l.isBusy
+	return err
 }
 
-func (l *listener) startLoop() {
+func (l *listener) startLoop(done chan struct{}) {
+	defer close(done)
 	defer func() {
 		if r := recover(); r != nil {
 			l.handleRecover(r, true)
@@ -126,131 +254,415 @@ func (l *listener) startLoop() {
 		select {
 		case <-l.start:
 			_ = l.errorCatcher.TryCatchError(func() error { //nolint:errcheck // errors are handled in the error callback
-				if err := l.bootstrapperFunc(l.configHandler.GetConfig(), l.serverSetter); err != nil {
-					return err
-				}
-				if err := l.initializeServer(); err != nil {
-					return err
-				}
-				l.logger.Infof("%v - Listen on %v", l.serverSetter.Name, l.serverSetter.Addr)
-
-				switch l.serverSetter.ServerType {
-				case HTTPServer:
-					select {
-					case l.started <- true:
-					default:
+				if l.prepared {
+					l.prepared = false
+				} else {
+					// the servers are built apart and swapped in, so the restarts read a consistent ServerSetter
+					running := l.currentServers()
+					serverSetter := *running.serverSetter
+					next := servers{serverSetter: &serverSetter}
+					if err := l.bootstrap(next.serverSetter); err != nil {
+						return err
 					}
-					if l.serverSetter.TLSConfig != nil {
-						if err := l.httpServer.ListenAndServeTLS("", ""); err != nil {
-							return err
-						}
-					} else {
-						if err := l.httpServer.ListenAndServe(); err != nil {
-							return err
-						}
+					if err := l.initializeServer(&next); err != nil {
+						return err
 					}
-				case GRpcSever:
+					l.logger.Infof("%v - Listen on %v", next.serverSetter.Name, next.serverSetter.address())
+
 					select {
 					case l.started <- true:
 					default:
 					}
-					lis, err := net.Listen("tcp", l.serverSetter.Addr)
+					dispatcher, err := l.dispatcherFor(running.dispatcher, next.serverSetter)
 					if err != nil {
 						return err
 					}
-					l.grpcDefinitionsFunc(l.grpcServer)
-					if err := l.grpcServer.Serve(lis); err != nil {
-						return err
+					if running.dispatcher != dispatcher {
+						running.dispatcher.close()
 					}
+					next.dispatcher = dispatcher
+					l.track(&next)
+					l.serversMutex.Lock()
+					l.servers = next
+					l.serversMutex.Unlock()
 				}
+				l.setState(Listening, nil)
+				go l.serve(l.currentServers())
 				return nil
 			}, func(err error) {
 				l.handleServerError(err)
 			})
+		case err := <-l.served:
+			l.handleServerError(err)
 		case <-l.stop:
+			l.setState(Stopped, nil)
+			l.stopped.Store(true)
 			l.finish <- nil
-			l.stopped = true
 		}
-		if l.stopped {
+		if l.stopped.Load() {
 			break
 		}
 	}
 }
-func (l *listener) initializeServer() error {
-	if l.serverSetter.Addr == "" {
-		return newListenerError(AddressNotConfigured, "address not configured", nil)
+func (l *listener) bootstrap(serverSetter *ServerSetter) error {
+	// the bootstrapper adds them again on every start
+	serverSetter.Middlewares = nil
+	serverSetter.UnaryInterceptors = nil
+	serverSetter.StreamInterceptors = nil
+	serverSetter.GrpcOptions = nil
+	return l.bootstrapperFunc(l.configHandler.GetConfig(), serverSetter)
+}
+
+// serve runs the servers until they stop, reporting to the loop the errors that stopped them
+func (l *listener) serve(s servers) {
+	defer func() {
+		if r := recover(); r != nil {
+			err := panicError(r)
+			l.logger.Errorf("%v - panic recovered: %v", s.serverSetter.Name, err)
+			l.served <- err
+		}
+	}()
+	if err := l.runServers(&s); err != nil && err != http.ErrServerClosed { //nolint:errorlint // Serve returns ErrServerClosed as is
+		l.served <- err
 	}
-	switch l.serverSetter.ServerType {
+}
+
+func (l *listener) runServers(s *servers) error {
+	switch s.serverSetter.ServerType {
+	case HTTPGrpcServer:
+		l.registerGrpcDefinitions(s)
+		fallthrough
 	case HTTPServer:
-		l.httpServer = &http.Server{
-			ErrorLog:          l.logger.GetErrorLogger(),
-			ReadHeaderTimeout: 10 * time.Second,
-		}
-		l.httpServer.Addr = l.serverSetter.Addr
-		if l.serverSetter.Handler != nil {
-			l.httpServer.Handler = l.serverSetter.Handler
-		}
-		l.httpServer.TLSConfig = l.serverSetter.TLSConfig
-		if l.serverSetter.TLSNextProto != nil {
-			l.httpServer.TLSNextProto = l.serverSetter.TLSNextProto
+		if s.serverSetter.TLSConfig != nil {
+			return s.httpServer.ServeTLS(s.connections, "", "")
 		}
+		return s.httpServer.Serve(s.connections)
+	case GRpcSever:
+		l.registerGrpcDefinitions(s)
+		return s.grpcServer.Serve(s.connections)
+	}
+	return nil
+}
+
+func (l *listener) initializeServer(s *servers) error {
+	if s.serverSetter.Addr == "" && s.serverSetter.Listener == nil {
+		return newListenerError(AddressNotConfigured, "address not configured", nil)
+	}
+	switch s.serverSetter.ServerType {
+	case HTTPServer:
+		s.httpServer = l.newHTTPServer(s.serverSetter, s.serverSetter.handler())
 
 	case GRpcSever:
-		if l.serverSetter.TLSConfig != nil {
-			opts := []grpc.ServerOption{grpc.Creds(credentials.NewTLS(l.serverSetter.TLSConfig))}
-			l.grpcServer = grpc.NewServer(opts...)
-		} else {
-			l.grpcServer = grpc.NewServer()
+		opts := grpcServerOptions(s.serverSetter)
+		if s.serverSetter.TLSConfig != nil {
+			opts = append(opts, grpc.Creds(credentials.NewTLS(s.serverSetter.TLSConfig)))
+		}
+		s.grpcServer = grpc.NewServer(opts...)
+
+	case HTTPGrpcServer:
+		// TLS is terminated by the http.Server, so the gRPC server has no credentials
+		s.grpcServer = grpc.NewServer(grpcServerOptions(s.serverSetter)...)
+		s.httpServer = l.newHTTPServer(s.serverSetter, newGrpcMultiplexer(s.grpcServer, s.serverSetter.handler()))
+		if s.serverSetter.TLSConfig == nil {
+			// h2c: gRPC clients talk HTTP/2 with prior knowledge on plaintext connections
+			protocols := new(http.Protocols)
+			protocols.SetHTTP1(true)
+			protocols.SetUnencryptedHTTP2(true)
+			s.httpServer.Protocols = protocols
 		}
 	}
 	return nil
 }
 
-func (l *listener) stopServer() error {
-	switch l.serverSetter.ServerType {
-	case HTTPServer:
-		if l.httpServer != nil {
-			if err := l.httpServer.Shutdown(context.Background()); err != nil {
-				return err
+func grpcServerOptions(serverSetter *ServerSetter) []grpc.ServerOption {
+	opts := append([]grpc.ServerOption{}, serverSetter.GrpcOptions...)
+	if len(serverSetter.UnaryInterceptors) > 0 {
+		opts = append(opts, grpc.ChainUnaryInterceptor(serverSetter.UnaryInterceptors...))
+	}
+	if len(serverSetter.StreamInterceptors) > 0 {
+		opts = append(opts, grpc.ChainStreamInterceptor(serverSetter.StreamInterceptors...))
+	}
+	return opts
+}
+
+func (l *listener) registerGrpcDefinitions(s *servers) {
+	l.grpcDefinitionsFunc(s.grpcServer)
+	if s.serverSetter.GrpcReflection {
+		reflection.Register(s.grpcServer)
+	}
+}
+
+func (l *listener) newHTTPServer(serverSetter *ServerSetter, handler http.Handler) *http.Server {
+	readHeaderTimeout := time.Duration(serverSetter.ReadHeaderTimeout)
+	if readHeaderTimeout == 0 {
+		readHeaderTimeout = DefaultReadHeaderTimeout
+	}
+	httpServer := &http.Server{
+		ErrorLog:          l.logger.GetErrorLogger(),
+		ReadTimeout:       time.Duration(serverSetter.ReadTimeout),
+		ReadHeaderTimeout: readHeaderTimeout,
+		WriteTimeout:      time.Duration(serverSetter.WriteTimeout),
+		IdleTimeout:       time.Duration(serverSetter.IdleTimeout),
+		MaxHeaderBytes:    serverSetter.MaxHeaderBytes,
+	}
+	httpServer.Addr = serverSetter.Addr
+	if handler != nil {
+		httpServer.Handler = handler
+	}
+	httpServer.TLSConfig = serverSetter.TLSConfig
+	if serverSetter.TLSNextProto != nil {
+		httpServer.TLSNextProto = serverSetter.TLSNextProto
+	}
+	return httpServer
+}
+
+func (l *listener) stopServer(ctx context.Context, s *servers) error {
+	switch s.serverSetter.ServerType {
+	case HTTPServer, HTTPGrpcServer:
+		if s.grpcServer != nil && s.serverSetter.ServerType == HTTPGrpcServer {
+			// gRPC streams are http handlers, the http.Server drains them; Stop releases the gRPC server
+			defer s.grpcServer.Stop()
+		}
+		if s.httpServer != nil {
+			if err := s.httpServer.Shutdown(ctx); err != nil {
+				if ctx.Err() == nil {
+					return err
+				}
+				return l.forceStop(s, err, s.httpServer.Close)
 			}
 		}
 	case GRpcSever:
-		if l.grpcServer != nil {
-			l.grpcServer.GracefulStop()
+		if s.grpcServer != nil {
+			drained := make(chan struct{})
+			go func() {
+				s.grpcServer.GracefulStop()
+				close(drained)
+			}()
+			select {
+			case <-drained:
+			case <-ctx.Done():
+				err := l.forceStop(s, ctx.Err(), func() error {
+					s.grpcServer.Stop()
+					return nil
+				})
+				<-drained
+				return err
+			}
 		}
 	}
 	return nil
 }
 
+func (l *listener) forceStop(s *servers, cause error, closeServer func() error) error {
+	cut := 0
+	if s.connections != nil {
+		cut = s.connections.Open()
+	}
+	_ = closeServer() //nolint:errcheck // the server is being discarded
+	if s.connections != nil {
+		// hijacked connections are not closed by the server
+		s.connections.CloseConnections()
+	}
+	l.logger.Warningf("%v - Shutdown deadline exceeded, %d connections were closed", s.serverSetter.Name, cut)
+	return newListenerError(ShutdownDeadlineExceeded, fmt.Sprintf("shutdown deadline exceeded, %d connections were closed", cut), cause)
+}
+
+func (l *listener) State() State {
+	l.stateMutex.RLock()
+	defer l.stateMutex.RUnlock()
+	return l.state
+}
+
+// Serving reports whether the server accepts connections: while it is Listening or restarting without downtime
+func (l *listener) Serving() bool {
+	l.stateMutex.RLock()
+	defer l.stateMutex.RUnlock()
+	return l.state == Listening || (l.state == Restarting && l.restartKeepsServing)
+}
+
+func (l *listener) StateChangedSubscribe(subscribeFunc func(StateChangedEvent)) error {
+	return l.stateSubscriptions.Add(subscribeFunc)
+}
+
+func (l *listener) StateChangedUnsubscribe(subscribeFunc func(StateChangedEvent)) error {
+	return l.stateSubscriptions.Remove(subscribeFunc)
+}
+
+func (l *listener) setState(state State, err error) {
+	l.stateMutex.Lock()
+	previous := l.state
+	l.state = state
+	l.stateMutex.Unlock()
+	if previous != state {
+		name := l.name()
+		l.metrics.stateChanged(name, state)
+		l.statePublisher.Publish(StateChangedEvent{Name: name, Previous: previous, Current: state, Err: err})
+	}
+}
+
+func (l *listener) shutdownContext() (context.Context, context.CancelFunc) {
+	timeout := l.currentServerSetter().ShutdownTimeout
+	if timeout <= 0 {
+		timeout = DefaultShutdownTimeout
+	}
+	return context.WithTimeout(context.Background(), timeout)
+}
+
 func (l *listener) restart() {
+	if l.restarts.enabled() && !l.stopped.Load() {
+		delay, budgetErr := l.restarts.next(time.Now(), nil)
+		if budgetErr != nil {
+			// the loop stops the listener
+			l.served <- budgetErr
+			return
+		}
+		if delay > 0 {
+			l.logger.Warningf("%v - Restart delayed %v by the restart policy", l.name(), delay)
+			if !l.restarts.wait(delay) {
+				return
+			}
+		}
+	}
 	l.isBusy <- true
-	if !l.stopped {
-		l.logger.Tracef("%v Restart Server", l.serverSetter.Name)
-		_ = l.stopServer() //nolint:errcheck // server shutdown errors are not recoverable
-		l.start <- true
+	var drain func()
+	if !l.stopped.Load() {
+		running := l.currentServers()
+		l.logger.Tracef("%v Restart Server", running.serverSetter.Name)
+		l.metrics.restarted(running.serverSetter.Name, "config")
+		zeroDowntime := running.serverSetter.RestartMode == ZeroDowntimeRestart
+		l.stateMutex.Lock()
+		l.restartKeepsServing = zeroDowntime
+		l.stateMutex.Unlock()
+		l.setState(Restarting, nil)
+		if zeroDowntime {
+			drain = l.restartWithoutDowntime(running)
+		} else {
+			ctx, cancel := l.shutdownContext()
+			_ = l.stopServer(ctx, &running) //nolint:errcheck // forced shutdowns are already logged
+			cancel()
+			l.start <- true
+		}
 	}
 	<-l.isBusy
+	// the running servers are drained once the listener is no longer busy, so Stop does not wait for them
+	if drain != nil {
+		drain()
+	}
+}
+
+// restartWithoutDowntime binds the new servers before draining the running ones, which keep serving
+// if the new ones can not be bootstrapped or bound. It returns the function that drains them, nil when they are kept
+func (l *listener) restartWithoutDowntime(running servers) func() {
+	next, err := l.prepareServers(running)
+	if err != nil {
+		l.logger.Errorf("%v - Restart failed, the running server is kept: %v", running.serverSetter.Name, err)
+		if l.configHandler.CanRestore() {
+			if restoreErr := l.configHandler.Restore(); restoreErr != nil {
+				l.logger.Errorf("%v - Failed to restore config: %v", running.serverSetter.Name, restoreErr)
+			}
+		}
+		l.setState(Listening, nil)
+		return nil
+	}
+	l.serversMutex.Lock()
+	l.servers = next
+	l.prepared = true
+	l.serversMutex.Unlock()
+	l.logger.Infof("%v - Listen on %v", next.serverSetter.Name, next.serverSetter.address())
+	l.start <- true
+
+	return func() {
+		ctx, cancel := l.shutdownContext()
+		defer cancel()
+		if running.httpServer != nil {
+			running.connections.WaitPending(ctx)
+		}
+		_ = l.stopServer(ctx, &running) //nolint:errcheck // forced shutdowns are already logged
+		if running.dispatcher != next.dispatcher {
+			running.dispatcher.close()
+		}
+	}
+}
+
+// currentServers returns the servers in use, synchronized with the restarts that replace them
+func (l *listener) currentServers() servers {
+	l.serversMutex.Lock()
+	defer l.serversMutex.Unlock()
+	return l.servers
+}
+
+// currentServerSetter returns the ServerSetter of the servers in use
+func (l *listener) currentServerSetter() *ServerSetter {
+	return l.currentServers().serverSetter
+}
+
+// name returns the name of the server, for the logs, metrics and events
+func (l *listener) name() string {
+	return l.currentServerSetter().Name
+}
+
+// prepareServers builds and binds the servers that replace running
+func (l *listener) prepareServers(running servers) (servers, error) {
+	serverSetter := *running.serverSetter
+	next := servers{serverSetter: &serverSetter}
+	if err := l.bootstrap(next.serverSetter); err != nil {
+		return next, err
+	}
+	if err := l.initializeServer(&next); err != nil {
+		return next, err
+	}
+	dispatcher, err := l.dispatcherFor(running.dispatcher, next.serverSetter)
+	if err != nil {
+		return next, err
+	}
+	next.dispatcher = dispatcher
+	l.track(&next)
+	return next, nil
+}
+
+// dispatcherFor returns dispatcher, the one in use, when it still accepts on the socket of the ServerSetter,
+// so new connections go to the new server from now on, or binds a new one
+func (l *listener) dispatcherFor(dispatcher *connectionDispatcher, serverSetter *ServerSetter) (*connectionDispatcher, error) {
+	if !dispatcher.accepts(serverSetter) {
+		lis, persistent, err := listen(serverSetter)
+		if err != nil {
+			return nil, err
+		}
+		dispatcher = newConnectionDispatcher(lis, serverSetter.Addr, persistent)
+	}
+	dispatcher.limiter.setLimit(serverSetter.maxConnections())
+	return dispatcher, nil
+}
+
+// track makes the servers accept from their dispatcher, keeping track of the connections
+func (l *listener) track(s *servers) {
+	s.connections = s.dispatcher.newListener()
+	if s.httpServer != nil {
+		s.httpServer.ConnState = s.connections.ConnState
+	}
 }
 
 func (l *listener) onRestoredConfig() {
-	l.logger.Tracef("%v - Restored config", l.serverSetter.Name)
+	l.logger.Tracef("%v - Restored config", l.name())
 	// After restore, just let the listener stop gracefully
 	// Don't attempt to restart as the config has been restored to disk
 	// and the listener should be manually restarted if needed
 }
 
 func (l *listener) onModifiedConfig() {
-	l.logger.Tracef("%v - Modified config", l.serverSetter.Name)
+	l.logger.Tracef("%v - Modified config", l.name())
 
 	// Call validation function
 	if l.configValidatorFunc != nil {
 		valid, err := l.configValidatorFunc(l.configHandler.GetConfig())
 		if err != nil {
-			l.logger.Errorf("%v - Config validation error: %v", l.serverSetter.Name, err)
+			l.logger.Errorf("%v - Config validation error: %v", l.name(), err)
+			l.metrics.reloaded(l.name(), ConfigReloadRejected)
 			return
 		}
 		if !valid {
-			l.logger.Tracef("%v - Config validation cancelled", l.serverSetter.Name)
+			l.logger.Tracef("%v - Config validation cancelled", l.name())
+			l.metrics.reloaded(l.name(), ConfigReloadRejected)
 			return
 		}
 	}
@@ -261,52 +673,110 @@ func (l *listener) onModifiedConfig() {
 		application := ConfigApplication{NeedsRestart: &needsRestart}
 		err := l.configApplicatorFunc(l.configHandler.GetConfig(), &application)
 		if err != nil {
-			l.logger.Errorf("%v - Config application error: %v", l.serverSetter.Name, err)
+			l.logger.Errorf("%v - Config application error: %v", l.name(), err)
+			l.metrics.reloaded(l.name(), ConfigReloadFailed)
 			return
 		}
 	}
 
 	// If application function is not set or needs restart, restart
 	if l.configApplicatorFunc == nil || needsRestart {
+		l.metrics.reloaded(l.name(), ConfigReloadRestarted)
 		l.restart()
 	} else {
-		l.logger.Infof("%v - Config applied without restart", l.serverSetter.Name)
+		l.metrics.reloaded(l.name(), ConfigReloadApplied)
+		l.logger.Infof("%v - Config applied without restart", l.name())
 	}
 }
 
 func (l *listener) handleRecover(r interface{}, sendError bool) {
-	var err error
+	err := panicError(r)
+	l.logger.Errorf("%v - panic recovered: %v", l.name(), err)
+	l.finalizeError(err, sendError)
+}
+
+func panicError(r interface{}) error {
 	switch v := r.(type) {
 	case error:
-		err = v
+		return v
 	case string:
-		err = newListenerError(UnexpectedError, v, nil)
+		return newListenerError(UnexpectedError, v, nil)
 	default:
-		err = newListenerError(UnexpectedError, "panic in listener", nil)
+		return newListenerError(UnexpectedError, "panic in listener", nil)
 	}
-	l.logger.Errorf("%v - panic recovered: %v", l.serverSetter.Name, err)
-	l.finalizeError(err, sendError)
 }
 
 func (l *listener) handleServerError(err error) {
-	if err.Error() != "http: Server closed" {
-		l.logger.Errorf("%v - %v", l.serverSetter.Name, err.Error())
-		l.finalizeError(err, true)
+	if err.Error() == "http: Server closed" {
+		return
+	}
+	if budgetErr, ok := err.(RestartBudgetError); ok {
+		l.giveUp(budgetErr)
+		return
+	}
+	l.logger.Errorf("%v - %v", l.name(), err.Error())
+	if l.restarts.enabled() {
+		l.retry(err)
+		return
+	}
+	l.finalizeError(err, true)
+}
+
+// retry restores the config when possible and starts the server again after the backoff of the restart policy
+func (l *listener) retry(err error) {
+	delay, budgetErr := l.restarts.next(time.Now(), err)
+	if budgetErr != nil {
+		l.giveUp(budgetErr)
+		return
+	}
+	l.setState(Failed, err)
+	l.metrics.restarted(l.name(), "failure")
+	if l.configHandler.CanRestore() {
+		if restoreErr := l.configHandler.Restore(); restoreErr != nil {
+			l.logger.Errorf("%v - Failed to restore config: %v", l.name(), restoreErr)
+		}
+	}
+	l.logger.Warningf("%v - Restarting in %v", l.name(), delay)
+	go func() {
+		if l.restarts.wait(delay) {
+			select {
+			case l.start <- true:
+			default:
+			}
+		}
+	}()
+}
+
+// giveUp stops the listener when the restart policy is exhausted, reporting the attempts
+func (l *listener) giveUp(budgetErr RestartBudgetError) {
+	l.logger.Errorf("%v - %v", l.name(), budgetErr.GetMessage())
+	running := l.currentServers()
+	ctx, cancel := l.shutdownContext()
+	_ = l.stopServer(ctx, &running) //nolint:errcheck // forced shutdowns are already logged
+	cancel()
+	running.dispatcher.stop()
+	l.setState(Failed, budgetErr)
+	select {
+	case l.started <- true:
+	default:
 	}
+	l.stopped.Store(true)
+	l.finish <- budgetErr
 }
 
 func (l *listener) finalizeError(err error, sendError bool) {
+	l.setState(Failed, err)
 	if l.configHandler.CanRestore() {
 		if restoreErr := l.configHandler.Restore(); restoreErr != nil {
-			l.logger.Errorf("%v - Failed to restore config: %v", l.serverSetter.Name, restoreErr)
+			l.logger.Errorf("%v - Failed to restore config: %v", l.name(), restoreErr)
 		}
 	} else if sendError {
+		l.stopped.Store(true)
 		if listenerErr, ok := l.pipeError(err).(ListenerError); ok {
 			l.finish <- listenerErr
 		} else {
-			l.logger.Errorf("%v - Failed to convert error to ListenerError: %v", l.serverSetter.Name, err)
+			l.logger.Errorf("%v - Failed to convert error to ListenerError: %v", l.name(), err)
 		}
-		l.stopped = true
 	}
 }
 
@@ -6,6 +6,7 @@ import (
 	"github.com/janmbaco/go-infrastructure/v2/configuration"
 	"github.com/janmbaco/go-infrastructure/v2/errors"
 	"github.com/janmbaco/go-infrastructure/v2/logs"
+	"github.com/janmbaco/go-infrastructure/v2/metrics"
 )
 
 // ListenerBuilder defines a object responsible to builds listeners
@@ -14,6 +15,8 @@ type ListenerBuilder interface {
 	SetGrpcDefinitions(setProtobufFunc GrpcDefinitionsFunc) ListenerBuilder
 	SetConfigValidatorFunc(configValidationFunc ConfigValidatorFunc) ListenerBuilder
 	SetConfigApplicatorFunc(configApplicatorFunc ConfigApplicatorFunc) ListenerBuilder
+	SetRestartPolicy(restartPolicy RestartPolicy) ListenerBuilder
+	SetMetrics(registry *metrics.Registry) ListenerBuilder
 	GetListener() (Listener, error)
 }
 
@@ -25,6 +28,8 @@ type listenerBuilder struct {
 	grpcDefinitionsFunc GrpcDefinitionsFunc
 	validationFunc      ConfigValidatorFunc
 	applicationFunc     ConfigApplicatorFunc
+	restartPolicy       RestartPolicy
+	metricsRegistry     *metrics.Registry
 }
 
 // NewListenerBuilder returns a ListenerBuilder
@@ -56,6 +61,18 @@ func (lb *listenerBuilder) SetConfigApplicatorFunc(applicationFunc ConfigApplica
 	return lb
 }
 
+// SetRestartPolicy sets the retries and backoff of the restarts after failures and config changes
+func (lb *listenerBuilder) SetRestartPolicy(restartPolicy RestartPolicy) ListenerBuilder {
+	lb.restartPolicy = restartPolicy
+	return lb
+}
+
+// SetMetrics records the restarts, config reloads and state of the listener in registry
+func (lb *listenerBuilder) SetMetrics(registry *metrics.Registry) ListenerBuilder {
+	lb.metricsRegistry = registry
+	return lb
+}
+
 // GetListener gets the listener
 func (lb *listenerBuilder) GetListener() (Listener, error) {
 	if lb.bootstrapperFunc == nil {
@@ -71,14 +88,16 @@ func (lb *listenerBuilder) GetListener() (Listener, error) {
 	}
 	lb.logger.Unmute()
 
-	if serverSetter.ServerType == GRpcSever && lb.grpcDefinitionsFunc == nil {
+	if (serverSetter.ServerType == GRpcSever || serverSetter.ServerType == HTTPGrpcServer) && lb.grpcDefinitionsFunc == nil {
 		return nil, lb.pipError(newListenerBuilderError(NilGrpcDefinitionsError, "grpc definitions function is not set", nil))
 	}
-	listener := newListener(lb.configHandler, lb.logger, lb.errorCatcher, lb.bootstrapperFunc, lb.grpcDefinitionsFunc, lb.validationFunc, lb.applicationFunc)
+	listener := newListener(lb.configHandler, lb.logger, lb.errorCatcher, lb.bootstrapperFunc, lb.grpcDefinitionsFunc, lb.validationFunc, lb.applicationFunc, lb.restartPolicy, newListenerMetrics(lb.metricsRegistry))
 	lb.bootstrapperFunc = nil
 	lb.grpcDefinitionsFunc = nil
 	lb.validationFunc = nil
 	lb.applicationFunc = nil
+	lb.restartPolicy = RestartPolicy{}
+	lb.metricsRegistry = nil
 	return listener, nil
 }
 
@@ -33,4 +33,7 @@ type ListenerErrorType uint8
 const (
 	UnexpectedError ListenerErrorType = iota
 	AddressNotConfigured
+	ShutdownDeadlineExceeded
+	ListenFailed
+	RestartBudgetExhausted
 )
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"github.com/janmbaco/go-infrastructure/v2/metrics"
+)
+
+const (
+	// ConfigReloadApplied is the result of a config change applied without restarting the listener
+	ConfigReloadApplied = "applied"
+	// ConfigReloadRestarted is the result of a config change that restarted the listener
+	ConfigReloadRestarted = "restarted"
+	// ConfigReloadRejected is the result of a config change refused by the ConfigValidatorFunc
+	ConfigReloadRejected = "rejected"
+	// ConfigReloadFailed is the result of a config change the ConfigApplicatorFunc could not apply
+	ConfigReloadFailed = "failed"
+)
+
+// listenerMetrics are the metrics of a listener; a nil listenerMetrics records nothing
+type listenerMetrics struct {
+	restarts  *metrics.Counter
+	reloads   *metrics.Counter
+	listening *metrics.Gauge
+}
+
+func newListenerMetrics(registry *metrics.Registry) *listenerMetrics {
+	if registry == nil {
+		return nil
+	}
+	return &listenerMetrics{
+		restarts:  registry.MustCounter("listener_restarts_total", "Restarts of the listeners by reason, config or failure.", "listener", "reason"),
+		reloads:   registry.MustCounter("config_reloads_total", "Config changes seen by the listeners by result: applied, restarted, rejected or failed.", "listener", "result"),
+		listening: registry.MustGauge("listener_listening", "1 when the listener is accepting connections, 0 otherwise.", "listener"),
+	}
+}
+
+func (m *listenerMetrics) restarted(name, reason string) {
+	if m != nil {
+		m.restarts.Inc(name, reason)
+	}
+}
+
+func (m *listenerMetrics) reloaded(name, result string) {
+	if m != nil {
+		m.reloads.Inc(name, result)
+	}
+}
+
+func (m *listenerMetrics) stateChanged(name string, state State) {
+	if m == nil {
+		return
+	}
+	if state == Listening {
+		m.listening.Set(1, name)
+	} else {
+		m.listening.Set(0, name)
+	}
+}
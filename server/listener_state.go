@@ -0,0 +1,65 @@
+package server
+
+const (
+	// Starting is the state of a listener that is bootstrapping its server
+	Starting State = iota
+	// Listening is the state of a listener that is accepting connections
+	Listening
+	// Restarting is the state of a listener that is restarting its server after a config change
+	Restarting
+	// Stopping is the state of a listener that is draining its server
+	Stopping
+	// Stopped is the state of a listener that is not started or has been stopped
+	Stopped
+	// Failed is the state of a listener whose server finished with an error
+	Failed
+)
+
+// String returns the name of the state
+func (s State) String() string {
+	switch s {
+	case Starting:
+		return "Starting"
+	case Listening:
+		return "Listening"
+	case Restarting:
+		return "Restarting"
+	case Stopping:
+		return "Stopping"
+	case Stopped:
+		return "Stopped"
+	case Failed:
+		return "Failed"
+	default:
+		return "Unknown"
+	}
+}
+
+// ServingReporter is implemented by the listeners of this package. Serving reports whether the server
+// accepts connections, which is also the case while a ZeroDowntimeRestart replaces it
+type ServingReporter interface {
+	Serving() bool
+}
+
+// StateChangedEvent is the event that happens when a listener changes its state
+type StateChangedEvent struct {
+	Err      error
+	Name     string
+	Previous State
+	Current  State
+}
+
+// GetEventArgs gets the args of a event
+func (e StateChangedEvent) GetEventArgs() StateChangedEvent {
+	return e
+}
+
+// StopPropagation stops the propagation of a event
+func (e StateChangedEvent) StopPropagation() bool {
+	return false
+}
+
+// IsParallelPropagation indicates if the propagation of the event is in parallel
+func (e StateChangedEvent) IsParallelPropagation() bool {
+	return false
+}
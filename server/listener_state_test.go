@@ -0,0 +1,38 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestState_String_WhenKnownState_ThenReturnsName(t *testing.T) {
+	tests := []struct {
+		state    State
+		expected string
+	}{
+		{Starting, "Starting"},
+		{Listening, "Listening"},
+		{Restarting, "Restarting"},
+		{Stopping, "Stopping"},
+		{Stopped, "Stopped"},
+		{Failed, "Failed"},
+		{State(255), "Unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.state.String())
+		})
+	}
+}
+
+func TestStateChangedEvent_WhenPublished_ThenPropagatesSequentially(t *testing.T) {
+	// Arrange
+	event := StateChangedEvent{Name: "api", Previous: Starting, Current: Listening}
+
+	// Act & Assert
+	assert.Equal(t, event, event.GetEventArgs())
+	assert.False(t, event.StopPropagation())
+	assert.False(t, event.IsParallelPropagation())
+}
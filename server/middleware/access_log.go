@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/janmbaco/go-infrastructure/v2/logs"
+)
+
+// AccessLog logs every request once it has been served with its method, path, status,
+// response size, duration, remote address and, when RequestID runs before, its request ID
+func AccessLog(logger logs.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			recorder := newResponseRecorder(w)
+			next.ServeHTTP(recorder, r)
+			logger.Infof("%v %v %v %d %dB %v %v %v",
+				r.Method, r.URL.RequestURI(), r.Proto, recorder.status, recorder.written,
+				time.Since(start), r.RemoteAddr, RequestIDFromContext(r.Context()))
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package middleware
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	gzipEncoding    = "gzip"
+	deflateEncoding = "deflate"
+)
+
+// CompressMinSize is the body size below which the responses of Compress are not worth compressing
+const CompressMinSize = 1024
+
+// Compress compresses responses with gzip or deflate, as negotiated through Accept-Encoding,
+// at the given compress/flate level. Responses that already have a Content-Encoding, partial
+// content, HEAD requests, responses without a body, responses of already compressed types like
+// images or archives and bodies below CompressMinSize are sent as they are.
+// The start of the body is held back until it reaches CompressMinSize, or until it is flushed.
+func Compress(level int) Middleware {
+	if level < flate.HuffmanOnly || level > flate.BestCompression {
+		level = flate.DefaultCompression
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+			if encoding == "" || r.Method == http.MethodHead || r.Header.Get("Range") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			cw := &compressWriter{ResponseWriter: w, encoding: encoding, level: level}
+			defer cw.close()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// negotiateEncoding returns the preferred supported encoding not refused with q=0
+func negotiateEncoding(acceptEncoding string) string {
+	best, bestQuality := "", 0.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != gzipEncoding && name != deflateEncoding {
+			continue
+		}
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(q, 64); err == nil {
+				quality = parsed
+			}
+		}
+		if quality <= 0 {
+			continue
+		}
+		if quality > bestQuality || (quality == bestQuality && name == gzipEncoding) {
+			best, bestQuality = name, quality
+		}
+	}
+	return best
+}
+
+type compressWriter struct {
+	http.ResponseWriter
+	writer      io.WriteCloser
+	encoding    string
+	level       int
+	wroteHeader bool
+	// status is held back, with the start of the body in buffer, until the body reaches CompressMinSize
+	status int
+	buffer []byte
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.wroteHeader || cw.status != 0 {
+		return
+	}
+	if status < http.StatusOK {
+		cw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	header := cw.Header()
+	if header.Get("Content-Encoding") != "" || !bodyAllowed(status) || status == http.StatusPartialContent ||
+		incompressible(header.Get("Content-Type")) {
+		cw.send(status, false)
+		return
+	}
+	if length, err := strconv.Atoi(header.Get("Content-Length")); err == nil {
+		cw.send(status, length >= CompressMinSize)
+		return
+	}
+	cw.status = status
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader && cw.status == 0 {
+		if cw.Header().Get("Content-Type") == "" {
+			// sniff before compressing, otherwise the compressed bytes would be sniffed
+			cw.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.status != 0 {
+		cw.buffer = append(cw.buffer, b...)
+		if len(cw.buffer) >= CompressMinSize {
+			if err := cw.release(true); err != nil {
+				return 0, err
+			}
+		}
+		return len(b), nil
+	}
+	if cw.writer == nil {
+		return cw.ResponseWriter.Write(b)
+	}
+	return cw.writer.Write(b)
+}
+
+// release sends the held back status, compressed when compress is true, and the start of the body
+func (cw *compressWriter) release(compress bool) error {
+	status, buffer := cw.status, cw.buffer
+	cw.status, cw.buffer = 0, nil
+	if compress && cw.Header().Get("Content-Type") == "" {
+		// the handler sent the status before the body, which is sniffed now
+		cw.Header().Set("Content-Type", http.DetectContentType(buffer))
+		compress = !incompressible(cw.Header().Get("Content-Type"))
+	}
+	if !compress {
+		cw.Header().Set("Content-Length", strconv.Itoa(len(buffer)))
+	}
+	cw.send(status, compress)
+	if len(buffer) == 0 {
+		return nil
+	}
+	var err error
+	if cw.writer == nil {
+		_, err = cw.ResponseWriter.Write(buffer)
+	} else {
+		_, err = cw.writer.Write(buffer)
+	}
+	return err
+}
+
+// send sends the status, setting up the compression when compress is true
+func (cw *compressWriter) send(status int, compress bool) {
+	cw.wroteHeader = true
+	if compress {
+		header := cw.Header()
+		header.Set("Content-Encoding", cw.encoding)
+		header.Del("Content-Length")
+		// the representation changes, so does its validator
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			header.Set("ETag", "W/"+etag)
+		}
+		if cw.encoding == gzipEncoding {
+			cw.writer, _ = gzip.NewWriterLevel(cw.ResponseWriter, cw.level) //nolint:errcheck // the level is validated by Compress
+		} else {
+			cw.writer, _ = flate.NewWriter(cw.ResponseWriter, cw.level) //nolint:errcheck // the level is validated by Compress
+		}
+	}
+	cw.ResponseWriter.WriteHeader(status)
+}
+
+// Flush flushes the compressed data written so far to the client
+func (cw *compressWriter) Flush() {
+	if !cw.wroteHeader && cw.status == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.status != 0 {
+		// the body is streamed, so its size is unknown
+		_ = cw.release(true) //nolint:errcheck // http.Flusher does not report errors
+	}
+	if flusher, ok := cw.writer.(interface{ Flush() error }); ok {
+		_ = flusher.Flush() //nolint:errcheck // http.Flusher does not report errors
+	}
+	_ = http.NewResponseController(cw.ResponseWriter).Flush() //nolint:errcheck // http.Flusher does not report errors
+}
+
+// Hijack lets the handler take over the connection, the response is not compressed then
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	cw.wroteHeader, cw.status, cw.buffer = true, 0, nil
+	return http.NewResponseController(cw.ResponseWriter).Hijack()
+}
+
+// Unwrap returns the original http.ResponseWriter for http.ResponseController
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+func (cw *compressWriter) close() {
+	if cw.status != 0 {
+		_ = cw.release(false) //nolint:errcheck // the client is gone when the body can not be written
+	}
+	if cw.writer != nil {
+		_ = cw.writer.Close() //nolint:errcheck // the client is gone when the trailer can not be written
+	}
+}
+
+func bodyAllowed(status int) bool {
+	return status != http.StatusNoContent && status != http.StatusNotModified
+}
+
+// incompressible reports whether contentType is already compressed, like images, video, fonts or archives
+func incompressible(contentType string) bool {
+	mediaType, _, _ := strings.Cut(strings.ToLower(contentType), ";")
+	mediaType = strings.TrimSpace(mediaType)
+	switch {
+	case mediaType == "image/svg+xml":
+		return false
+	case strings.HasPrefix(mediaType, "image/"), strings.HasPrefix(mediaType, "video/"), strings.HasPrefix(mediaType, "audio/"):
+		return true
+	}
+	switch mediaType {
+	case "font/woff", "font/woff2", "application/zip", "application/gzip", "application/x-gzip",
+		"application/zstd", "application/x-bzip2", "application/x-7z-compressed", "application/vnd.rar":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var compressibleBody = strings.Repeat("compress me ", 100)
+
+func textHandler(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Length", "1200")
+	_, _ = io.WriteString(w, compressibleBody)
+}
+
+func TestCompress_WhenClientAcceptsGzip_ThenCompressesWithGzip(t *testing.T) {
+	// Arrange
+	handler := Compress(flate.DefaultCompression)(http.HandlerFunc(textHandler))
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set("Accept-Encoding", "deflate, gzip")
+	response := httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(response, request)
+
+	// Assert
+	assert.Equal(t, "gzip", response.Header().Get("Content-Encoding"))
+	assert.Empty(t, response.Header().Get("Content-Length"))
+	assert.Equal(t, "Accept-Encoding", response.Header().Get("Vary"))
+	reader, err := gzip.NewReader(response.Body)
+	require.NoError(t, err)
+	body, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, compressibleBody, string(body))
+}
+
+func TestCompress_WhenClientPrefersDeflate_ThenCompressesWithDeflate(t *testing.T) {
+	// Arrange
+	handler := Compress(flate.BestSpeed)(http.HandlerFunc(textHandler))
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set("Accept-Encoding", "gzip;q=0.5, deflate")
+	response := httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(response, request)
+
+	// Assert
+	assert.Equal(t, "deflate", response.Header().Get("Content-Encoding"))
+	body, err := io.ReadAll(flate.NewReader(response.Body))
+	require.NoError(t, err)
+	assert.Equal(t, compressibleBody, string(body))
+}
+
+func TestCompress_WhenResponseIsAlreadyEncodedOrNotAccepted_ThenSendsItAsIs(t *testing.T) {
+	// Arrange
+	handler := Compress(flate.DefaultCompression)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/encoded" {
+			w.Header().Set("Content-Encoding", "br")
+		}
+		_, _ = io.WriteString(w, compressibleBody)
+	}))
+	encoded := httptest.NewRequest(http.MethodGet, "/encoded", nil)
+	encoded.Header.Set("Accept-Encoding", "gzip, br")
+	refused := httptest.NewRequest(http.MethodGet, "/", nil)
+	refused.Header.Set("Accept-Encoding", "gzip;q=0")
+
+	// Act
+	encodedResponse := httptest.NewRecorder()
+	handler.ServeHTTP(encodedResponse, encoded)
+	refusedResponse := httptest.NewRecorder()
+	handler.ServeHTTP(refusedResponse, refused)
+
+	// Assert
+	assert.Equal(t, "br", encodedResponse.Header().Get("Content-Encoding"))
+	assert.Equal(t, compressibleBody, encodedResponse.Body.String())
+	assert.Empty(t, refusedResponse.Header().Get("Content-Encoding"))
+	assert.Equal(t, compressibleBody, refusedResponse.Body.String())
+}
+
+func TestCompress_WhenContentTypeIsAlreadyCompressed_ThenSendsItAsIs(t *testing.T) {
+	for _, contentType := range []string{"image/png", "video/mp4", "font/woff2", "application/zip", "application/gzip"} {
+		// Arrange
+		handler := Compress(flate.DefaultCompression)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", contentType)
+			_, _ = io.WriteString(w, compressibleBody)
+		}))
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.Header.Set("Accept-Encoding", "gzip")
+		response := httptest.NewRecorder()
+
+		// Act
+		handler.ServeHTTP(response, request)
+
+		// Assert
+		assert.Empty(t, response.Header().Get("Content-Encoding"), contentType)
+		assert.Equal(t, compressibleBody, response.Body.String(), contentType)
+	}
+}
+
+func TestCompress_WhenBodyIsBelowTheMinSize_ThenSendsItAsIs(t *testing.T) {
+	// Arrange
+	small := strings.Repeat("a", CompressMinSize-1)
+	handler := Compress(flate.DefaultCompression)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = io.WriteString(w, small[:10])
+		_, _ = io.WriteString(w, small[10:])
+		if r.URL.Path == "/large" {
+			_, _ = io.WriteString(w, "a")
+		}
+	}))
+	smallRequest := httptest.NewRequest(http.MethodGet, "/small", nil)
+	smallRequest.Header.Set("Accept-Encoding", "gzip")
+	largeRequest := httptest.NewRequest(http.MethodGet, "/large", nil)
+	largeRequest.Header.Set("Accept-Encoding", "gzip")
+
+	// Act
+	smallResponse := httptest.NewRecorder()
+	handler.ServeHTTP(smallResponse, smallRequest)
+	largeResponse := httptest.NewRecorder()
+	handler.ServeHTTP(largeResponse, largeRequest)
+
+	// Assert
+	assert.Equal(t, http.StatusCreated, smallResponse.Code)
+	assert.Empty(t, smallResponse.Header().Get("Content-Encoding"))
+	assert.Equal(t, strconv.Itoa(len(small)), smallResponse.Header().Get("Content-Length"))
+	assert.Equal(t, small, smallResponse.Body.String())
+	assert.Equal(t, http.StatusCreated, largeResponse.Code)
+	assert.Equal(t, "gzip", largeResponse.Header().Get("Content-Encoding"))
+	reader, err := gzip.NewReader(largeResponse.Body)
+	require.NoError(t, err)
+	body, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, small+"a", string(body))
+}
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSOptions configures the CORS middleware
+type CORSOptions struct {
+	// AllowedOrigins lists the allowed origins, "*" allows any origin
+	AllowedOrigins []string
+	// AllowedMethods defaults to GET, HEAD and POST
+	AllowedMethods []string
+	// AllowedHeaders lists the request headers allowed in preflight requests, "*" allows any header
+	AllowedHeaders []string
+	// ExposedHeaders lists the response headers the browser exposes to scripts
+	ExposedHeaders []string
+	// MaxAge is how long preflight responses can be cached, not sent when zero
+	MaxAge           time.Duration
+	AllowCredentials bool
+}
+
+var defaultCORSMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+
+// CORS adds the Cross-Origin Resource Sharing headers to the responses of allowed origins
+// and answers preflight requests without calling the next handler
+func CORS(options CORSOptions) Middleware {
+	allowedMethods := options.AllowedMethods
+	if len(allowedMethods) == 0 {
+		allowedMethods = defaultCORSMethods
+	}
+	methods := strings.Join(allowedMethods, ", ")
+	exposedHeaders := strings.Join(options.ExposedHeaders, ", ")
+	anyOrigin := slices.Contains(options.AllowedOrigins, "*")
+	anyHeader := slices.Contains(options.AllowedHeaders, "*")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+			header := w.Header()
+			header.Add("Vary", "Origin")
+			if preflight {
+				header.Add("Vary", "Access-Control-Request-Method")
+				header.Add("Vary", "Access-Control-Request-Headers")
+			}
+
+			if origin == "" || (!anyOrigin && !containsFold(options.AllowedOrigins, origin)) {
+				if preflight {
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if anyOrigin && !options.AllowCredentials {
+				header.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				header.Set("Access-Control-Allow-Origin", origin)
+			}
+			if options.AllowCredentials {
+				header.Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if !preflight {
+				if exposedHeaders != "" {
+					header.Set("Access-Control-Expose-Headers", exposedHeaders)
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			header.Set("Access-Control-Allow-Methods", methods)
+			if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+				if anyHeader {
+					header.Set("Access-Control-Allow-Headers", requested)
+				} else if len(options.AllowedHeaders) > 0 {
+					header.Set("Access-Control-Allow-Headers", strings.Join(options.AllowedHeaders, ", "))
+				}
+			}
+			if options.MaxAge > 0 {
+				header.Set("Access-Control-Max-Age", strconv.Itoa(int(options.MaxAge.Seconds())))
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+func containsFold(values []string, value string) bool {
+	return slices.ContainsFunc(values, func(v string) bool { return strings.EqualFold(v, value) })
+}
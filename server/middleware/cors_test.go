@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCORS_WhenPreflightFromAllowedOrigin_ThenAnswersWithoutCallingHandler(t *testing.T) {
+	// Arrange
+	called := false
+	handler := CORS(CORSOptions{
+		AllowedOrigins: []string{"https://app.example.com"},
+		AllowedMethods: []string{http.MethodGet, http.MethodPut},
+		AllowedHeaders: []string{"*"},
+		MaxAge:         time.Hour,
+	})(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { called = true }))
+	request := httptest.NewRequest(http.MethodOptions, "/api", nil)
+	request.Header.Set("Origin", "https://app.example.com")
+	request.Header.Set("Access-Control-Request-Method", http.MethodPut)
+	request.Header.Set("Access-Control-Request-Headers", "Authorization")
+	response := httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(response, request)
+
+	// Assert
+	assert.False(t, called)
+	assert.Equal(t, http.StatusNoContent, response.Code)
+	assert.Equal(t, "https://app.example.com", response.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET, PUT", response.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Authorization", response.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "3600", response.Header().Get("Access-Control-Max-Age"))
+}
+
+func TestCORS_WhenOriginIsNotAllowed_ThenOmitsCORSHeaders(t *testing.T) {
+	// Arrange
+	handler := CORS(CORSOptions{AllowedOrigins: []string{"https://app.example.com"}})(http.HandlerFunc(okHandler))
+	request := httptest.NewRequest(http.MethodGet, "/api", nil)
+	request.Header.Set("Origin", "https://evil.example.com")
+	response := httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(response, request)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Empty(t, response.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "Origin", response.Header().Get("Vary"))
+}
+
+func TestCORS_WhenAnyOriginWithCredentials_ThenEchoesOrigin(t *testing.T) {
+	// Arrange
+	handler := CORS(CORSOptions{AllowedOrigins: []string{"*"}, AllowCredentials: true, ExposedHeaders: []string{"X-Request-ID"}})(http.HandlerFunc(okHandler))
+	request := httptest.NewRequest(http.MethodGet, "/api", nil)
+	request.Header.Set("Origin", "https://any.example.com")
+	response := httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(response, request)
+
+	// Assert
+	assert.Equal(t, "https://any.example.com", response.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", response.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "X-Request-ID", response.Header().Get("Access-Control-Expose-Headers"))
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+)
+
+// BodyLimit rejects requests whose body is larger than maxBytes with 413 Request Entity Too Large.
+// Bodies without Content-Length are cut at maxBytes and reading beyond it fails.
+func BodyLimit(maxBytes int64) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > maxBytes {
+				http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Timeout cancels the request context after timeout and responds with 503 Service Unavailable
+// if the next handler has not finished by then. The response is buffered, so it is not suited
+// for streaming handlers.
+func Timeout(timeout time.Duration) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.TimeoutHandler(next, timeout, http.StatusText(http.StatusServiceUnavailable))
+	}
+}
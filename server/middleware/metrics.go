@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/janmbaco/go-infrastructure/v2/metrics"
+)
+
+// Metrics counts the requests in registry by method and status code, with their duration in seconds
+// and the number of requests being served: http_requests_total, http_request_duration_seconds and
+// http_requests_in_flight
+func Metrics(registry *metrics.Registry) Middleware {
+	requests := registry.MustCounter("http_requests_total", "HTTP requests served by method and status code.", "method", "code")
+	durations := registry.MustHistogram("http_request_duration_seconds", "Duration of the HTTP requests in seconds by method and status code.", metrics.DefaultBuckets, "method", "code")
+	inFlight := registry.MustGauge("http_requests_in_flight", "HTTP requests being served.")
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			inFlight.Inc()
+			defer inFlight.Dec()
+			recorder := newResponseRecorder(w)
+			next.ServeHTTP(recorder, r)
+			method, code := methodLabel(r.Method), strconv.Itoa(recorder.status)
+			requests.Inc(method, code)
+			durations.Observe(time.Since(start).Seconds(), method, code)
+		})
+	}
+}
+
+// methodLabel returns the standard methods as they are and OTHER for any other, bounding the label values
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	default:
+		return "OTHER"
+	}
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/janmbaco/go-infrastructure/v2/metrics"
+)
+
+func TestMetrics_WhenRequestsAreServed_ThenCountsThemByMethodAndCode(t *testing.T) {
+	// Arrange
+	registry := metrics.NewRegistry()
+	var inFlight string
+	handler := Metrics(registry)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var out strings.Builder
+		_ = registry.Write(&out)
+		inFlight = out.String()
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	// Act
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/", nil),
+		httptest.NewRequest(http.MethodGet, "/", nil),
+		httptest.NewRequest(http.MethodGet, "/missing", nil),
+		httptest.NewRequest("PURGE", "/", nil),
+	} {
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// Assert
+	var out strings.Builder
+	require.NoError(t, registry.Write(&out))
+	assert.Contains(t, inFlight, "http_requests_in_flight 1")
+	assert.Contains(t, out.String(), "http_requests_in_flight 0")
+	assert.Contains(t, out.String(), `http_requests_total{method="GET",code="200"} 2`)
+	assert.Contains(t, out.String(), `http_requests_total{method="GET",code="404"} 1`)
+	assert.Contains(t, out.String(), `http_requests_total{method="OTHER",code="200"} 1`)
+	assert.Contains(t, out.String(), `http_request_duration_seconds_count{method="GET",code="200"} 2`)
+}
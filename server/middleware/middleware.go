@@ -0,0 +1,68 @@
+// Package middleware provides composable net/http middleware for the handlers served by
+// server.Listener: panic recovery, request IDs, access logging, CORS, compression,
+// security headers, body size limits and timeouts.
+package middleware
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+)
+
+// Middleware wraps an http.Handler with additional behavior
+type Middleware = func(http.Handler) http.Handler
+
+// Chain composes middlewares into a single one, the first middleware being the outermost
+func Chain(middlewares ...Middleware) Middleware {
+	return func(handler http.Handler) http.Handler {
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			handler = middlewares[i](handler)
+		}
+		return handler
+	}
+}
+
+// responseRecorder keeps the status code and the number of bytes written to a response
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	written     int64
+	wroteHeader bool
+}
+
+func newResponseRecorder(w http.ResponseWriter) *responseRecorder {
+	return &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		// informational responses are followed by the final one
+		r.wroteHeader = status >= http.StatusOK
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(b)
+	r.written += int64(n)
+	return n, err
+}
+
+// Flush sends any buffered data to the client
+func (r *responseRecorder) Flush() {
+	r.wroteHeader = true
+	_ = http.NewResponseController(r.ResponseWriter).Flush() //nolint:errcheck // http.Flusher does not report errors
+}
+
+// Hijack lets the handler take over the connection
+func (r *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	r.wroteHeader = true
+	return http.NewResponseController(r.ResponseWriter).Hijack()
+}
+
+// Unwrap returns the original http.ResponseWriter for http.ResponseController
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
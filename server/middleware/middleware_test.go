@@ -0,0 +1,190 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/janmbaco/go-infrastructure/v2/logs"
+)
+
+type recordingLogger struct {
+	logs.Logger
+	mutex   sync.Mutex
+	entries []string
+}
+
+func (l *recordingLogger) Infof(format string, a ...interface{}) {
+	l.record("INFO: "+format, a...)
+}
+
+func (l *recordingLogger) Errorf(format string, a ...interface{}) {
+	l.record("ERROR: "+format, a...)
+}
+
+func (l *recordingLogger) record(format string, a ...interface{}) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.entries = append(l.entries, fmt.Sprintf(format, a...))
+}
+
+func okHandler(w http.ResponseWriter, _ *http.Request) {
+	_, _ = w.Write([]byte("ok"))
+}
+
+func TestChain_WhenMiddlewaresComposed_ThenFirstIsOutermost(t *testing.T) {
+	// Arrange
+	var order []string
+	trace := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	handler := Chain(trace("first"), trace("second"))(http.HandlerFunc(okHandler))
+
+	// Act
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	// Assert
+	assert.Equal(t, []string{"first", "second"}, order)
+}
+
+func TestRecovery_WhenHandlerPanics_ThenLogsAndReturnsInternalServerError(t *testing.T) {
+	// Arrange
+	logger := &recordingLogger{}
+	handler := Recovery(logger)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("boom")
+	}))
+	response := httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+	// Assert
+	assert.Equal(t, http.StatusInternalServerError, response.Code)
+	require.Len(t, logger.entries, 1)
+	assert.True(t, strings.HasPrefix(logger.entries[0], "ERROR: panic serving GET /panic: boom"))
+}
+
+func TestRecovery_WhenHandlerAborts_ThenPropagatesPanic(t *testing.T) {
+	// Arrange
+	handler := Recovery(&recordingLogger{})(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	// Act & Assert
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+}
+
+func TestRequestID_WhenHeaderIsMissingOrValid_ThenGeneratesOrReusesIt(t *testing.T) {
+	// Arrange
+	var seen string
+	handler := RequestID()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestIDFromContext(r.Context())
+	}))
+
+	// Act
+	generated := httptest.NewRecorder()
+	handler.ServeHTTP(generated, httptest.NewRequest(http.MethodGet, "/", nil))
+	generatedID := seen
+	reused := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set(RequestIDHeader, "abc-123")
+	handler.ServeHTTP(reused, request)
+
+	// Assert
+	assert.Len(t, generatedID, 32)
+	assert.Equal(t, generatedID, generated.Header().Get(RequestIDHeader))
+	assert.Equal(t, "abc-123", seen)
+	assert.Equal(t, "abc-123", reused.Header().Get(RequestIDHeader))
+}
+
+func TestRequestID_WhenHeaderHasControlCharacters_ThenReplacesIt(t *testing.T) {
+	// Arrange
+	handler := RequestID()(http.HandlerFunc(okHandler))
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set(RequestIDHeader, "bad id")
+	response := httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(response, request)
+
+	// Assert
+	assert.NotEqual(t, "bad id", response.Header().Get(RequestIDHeader))
+}
+
+func TestAccessLog_WhenRequestServed_ThenLogsStatusSizeAndRequestID(t *testing.T) {
+	// Arrange
+	logger := &recordingLogger{}
+	handler := Chain(RequestID(), AccessLog(logger))(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("created"))
+	}))
+	request := httptest.NewRequest(http.MethodPost, "/items?id=1", nil)
+	request.Header.Set(RequestIDHeader, "req-1")
+
+	// Act
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+
+	// Assert
+	require.Len(t, logger.entries, 1)
+	assert.True(t, strings.HasPrefix(logger.entries[0], "INFO: POST /items?id=1 HTTP/1.1 201 7B "))
+	assert.True(t, strings.HasSuffix(logger.entries[0], " req-1"))
+}
+
+func TestSecurityHeaders_WhenDefaults_ThenSetsBaselineHeadersAndHSTSOnlyOnTLS(t *testing.T) {
+	// Arrange
+	handler := SecurityHeaders(SecurityHeadersOptions{HSTSMaxAge: 24 * time.Hour, HSTSIncludeSubdomains: true})(http.HandlerFunc(okHandler))
+
+	// Act
+	plain := httptest.NewRecorder()
+	handler.ServeHTTP(plain, httptest.NewRequest(http.MethodGet, "http://example.com/", nil))
+	secure := httptest.NewRecorder()
+	handler.ServeHTTP(secure, httptest.NewRequest(http.MethodGet, "https://example.com/", nil))
+
+	// Assert
+	assert.Equal(t, "nosniff", plain.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, "DENY", plain.Header().Get("X-Frame-Options"))
+	assert.Equal(t, "strict-origin-when-cross-origin", plain.Header().Get("Referrer-Policy"))
+	assert.Empty(t, plain.Header().Get("Content-Security-Policy"))
+	assert.Empty(t, plain.Header().Get("Strict-Transport-Security"))
+	assert.Equal(t, "max-age=86400; includeSubDomains", secure.Header().Get("Strict-Transport-Security"))
+}
+
+func TestBodyLimit_WhenBodyExceedsLimit_ThenRejectsRequest(t *testing.T) {
+	// Arrange
+	handler := BodyLimit(4)(http.HandlerFunc(okHandler))
+	response := httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("too large")))
+
+	// Assert
+	assert.Equal(t, http.StatusRequestEntityTooLarge, response.Code)
+}
+
+func TestTimeout_WhenHandlerIsTooSlow_ThenReturnsServiceUnavailable(t *testing.T) {
+	// Arrange
+	handler := Timeout(10 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	response := httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	// Assert
+	assert.Equal(t, http.StatusServiceUnavailable, response.Code)
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net/http"
+	"runtime/debug"
+
+	"github.com/janmbaco/go-infrastructure/v2/logs"
+)
+
+// Recovery recovers from panics in the next handlers, logs them with their stack trace
+// and responds with 500 Internal Server Error when the response has not started yet.
+// http.ErrAbortHandler is propagated to let the server abort the response.
+func Recovery(logger logs.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			recorder := newResponseRecorder(w)
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				if rec == http.ErrAbortHandler { //nolint:errorlint // panic values are compared by identity
+					panic(rec)
+				}
+				logger.Errorf("panic serving %v %v: %v\n%s", r.Method, r.URL.Path, rec, debug.Stack())
+				if !recorder.wroteHeader {
+					http.Error(recorder, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				}
+			}()
+			next.ServeHTTP(recorder, r)
+		})
+	}
+}
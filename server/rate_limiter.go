@@ -0,0 +1,344 @@
+package server
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// RateLimitByIP counts the requests of every client IP address
+	RateLimitByIP = "ip"
+	// RateLimitByHeader counts the requests of every value of a header, like an API key
+	RateLimitByHeader = "header"
+	// RateLimitByRoute counts the requests of every route, whoever sends them
+	RateLimitByRoute = "route"
+)
+
+type (
+	// RateLimits are the limits of a RateLimiter. Configs can embed it to bind them from JSON
+	RateLimits struct {
+		// Rules are token buckets; a request is served when every rule that applies to it allows it
+		Rules []RateLimitRule `json:"rules,omitempty"`
+		// MaxInFlight is the number of requests served at once, no limit when zero
+		MaxInFlight int `json:"max_in_flight,omitempty"`
+	}
+
+	// RateLimitRule allows Rate requests per second, and Burst at once, per client IP, header value or route
+	RateLimitRule struct {
+		// Key is what the requests are counted by: ip, header or route
+		Key string `json:"key"`
+		// Header is the header, or the gRPC metadata, counted by when Key is header, like X-API-Key.
+		// The requests without it share one bucket
+		Header string `json:"header,omitempty"`
+		// Routes are the path prefixes, or the prefixes of the gRPC full methods like /pkg.Service, the rule
+		// applies to; every request when empty. They are matched on path segments and the longest one wins.
+		// When Key is route they are required, and every one is counted apart
+		Routes []string `json:"routes,omitempty"`
+		// Rate is the number of requests per second
+		Rate float64 `json:"rate"`
+		// Burst is the number of requests allowed at once, the ceiling of Rate when zero
+		Burst int `json:"burst,omitempty"`
+	}
+
+	// RateLimiter rejects the requests beyond its rules, or beyond the requests served at once, with
+	// 429 Too Many Requests and Retry-After for HTTP and ResourceExhausted for gRPC.
+	// The limits can be replaced with Set while it serves.
+	RateLimiter struct {
+		limits   atomic.Pointer[rateLimits]
+		inFlight atomic.Int64
+		now      func() time.Time
+	}
+
+	rateLimits struct {
+		rules       []*rateLimitRule
+		maxInFlight int64
+	}
+
+	rateLimitRule struct {
+		config    RateLimitRule
+		routes    []string
+		burst     float64
+		mutex     sync.Mutex
+		buckets   map[string]*tokenBucket
+		lastSweep time.Time
+	}
+
+	tokenBucket struct {
+		tokens float64
+		last   time.Time
+	}
+
+	// rateLimitRequest is what the rules count an HTTP request or a gRPC call by
+	rateLimitRequest struct {
+		ip     string
+		route  string
+		header func(name string) string
+	}
+)
+
+// NewRateLimiter returns a RateLimiter without limits
+func NewRateLimiter() *RateLimiter {
+	limiter := &RateLimiter{now: time.Now}
+	limiter.limits.Store(&rateLimits{})
+	return limiter
+}
+
+// Set replaces the limits; when a rule is invalid the current ones are kept.
+// The buckets of the rules that did not change keep counting.
+func (rl *RateLimiter) Set(limits RateLimits) error {
+	if limits.MaxInFlight < 0 {
+		return newRateLimitError(InvalidRateLimit, "the max in flight requests must not be negative", nil)
+	}
+	current := rl.limits.Load()
+	next := &rateLimits{rules: make([]*rateLimitRule, 0, len(limits.Rules)), maxInFlight: int64(limits.MaxInFlight)}
+	for i, config := range limits.Rules {
+		if err := validateRateLimitRule(config); err != nil {
+			return newRateLimitError(InvalidRateLimit, "rule "+strconv.Itoa(i)+": "+err.Error(), nil)
+		}
+		next.rules = append(next.rules, current.find(config))
+	}
+	rl.limits.Store(next)
+	return nil
+}
+
+// Middleware serves the requests allowed by the limits with next
+func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			ip = r.RemoteAddr
+		}
+		release, retryAfter, ok := rl.acquire(rateLimitRequest{ip: ip, route: r.URL.Path, header: r.Header.Get})
+		if !ok {
+			w.Header().Set("Retry-After", retryAfterSeconds(retryAfter))
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			return
+		}
+		defer release()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// UnaryInterceptor serves the calls allowed by the limits, counted by their full method as route
+func (rl *RateLimiter) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		release, retryAfter, ok := rl.acquire(grpcRateLimitRequest(ctx, info.FullMethod))
+		if !ok {
+			_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", retryAfterSeconds(retryAfter))) //nolint:errcheck // the status is returned anyway
+			return nil, resourceExhausted(retryAfter)
+		}
+		defer release()
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor serves the streams allowed by the limits, which count a stream as one request while it is open
+func (rl *RateLimiter) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		release, retryAfter, ok := rl.acquire(grpcRateLimitRequest(stream.Context(), info.FullMethod))
+		if !ok {
+			_ = stream.SetHeader(metadata.Pairs("retry-after", retryAfterSeconds(retryAfter))) //nolint:errcheck // the status is returned anyway
+			return resourceExhausted(retryAfter)
+		}
+		defer release()
+		return handler(srv, stream)
+	}
+}
+
+// acquire takes a place among the requests in flight and a token of every rule that applies to request.
+// When it is not allowed, it returns how long the client should wait before retrying, and gives back what
+// it took so a rejected request does not count.
+func (rl *RateLimiter) acquire(request rateLimitRequest) (func(), time.Duration, bool) {
+	limits := rl.limits.Load()
+	if inFlight := rl.inFlight.Add(1); limits.maxInFlight > 0 && inFlight > limits.maxInFlight {
+		rl.inFlight.Add(-1)
+		return nil, time.Second, false
+	}
+	now := rl.now()
+	var retryAfter time.Duration
+	rejected := false
+	taken := make([]*tokenBucket, len(limits.rules))
+	for i, rule := range limits.rules {
+		bucket, wait, ok := rule.take(request, now)
+		if !ok {
+			rejected = true
+			retryAfter = max(retryAfter, wait)
+		}
+		taken[i] = bucket
+	}
+	if rejected {
+		for i, rule := range limits.rules {
+			rule.refund(taken[i])
+		}
+		rl.inFlight.Add(-1)
+		return nil, retryAfter, false
+	}
+	return func() { rl.inFlight.Add(-1) }, 0, true
+}
+
+// find returns the rule of the current limits equal to config, or a new one
+func (l *rateLimits) find(config RateLimitRule) *rateLimitRule {
+	for _, rule := range l.rules {
+		if reflect.DeepEqual(rule.config, config) {
+			return rule
+		}
+	}
+	burst := float64(config.Burst)
+	if config.Burst == 0 {
+		burst = math.Max(1, math.Ceil(config.Rate))
+	}
+	routes := make([]string, 0, len(config.Routes))
+	for _, route := range config.Routes {
+		if route != "/" {
+			route = strings.TrimRight(route, "/")
+		}
+		routes = append(routes, route)
+	}
+	return &rateLimitRule{config: config, routes: routes, burst: burst, buckets: make(map[string]*tokenBucket)}
+}
+
+// take takes a token of the bucket of request and returns the bucket, or returns how long it takes to have one.
+// The bucket is nil when no token was taken.
+func (r *rateLimitRule) take(request rateLimitRequest, now time.Time) (*tokenBucket, time.Duration, bool) {
+	route, applies := r.route(request.route)
+	if !applies {
+		return nil, 0, true
+	}
+	var key string
+	switch r.config.Key {
+	case RateLimitByIP:
+		key = request.ip
+	case RateLimitByHeader:
+		key = request.header(r.config.Header)
+	case RateLimitByRoute:
+		key = route
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.sweep(now)
+	bucket, ok := r.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: r.burst, last: now}
+		r.buckets[key] = bucket
+	}
+	bucket.refill(now, r.config.Rate, r.burst)
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return bucket, 0, true
+	}
+	// at high rates the wait truncates to zero when the bucket is about to have a token
+	return nil, max(time.Duration((1-bucket.tokens)/r.config.Rate*float64(time.Second)), time.Nanosecond), false
+}
+
+// refund gives back the token taken from bucket
+func (r *rateLimitRule) refund(bucket *tokenBucket) {
+	if bucket == nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	bucket.tokens = math.Min(r.burst, bucket.tokens+1)
+}
+
+// route returns the longest route of the rule that matches path, and whether the rule applies to it
+func (r *rateLimitRule) route(path string) (string, bool) {
+	if len(r.routes) == 0 {
+		return "", true
+	}
+	matched, applies := "", false
+	for _, route := range r.routes {
+		if matchesPrefix(path, route) && (!applies || len(route) > len(matched)) {
+			matched, applies = route, true
+		}
+	}
+	return matched, applies
+}
+
+// sweep removes, at most once per refill period, the buckets that are full, which are like new ones
+func (r *rateLimitRule) sweep(now time.Time) {
+	refill := time.Duration(r.burst / r.config.Rate * float64(time.Second))
+	if now.Sub(r.lastSweep) < max(refill, time.Minute) {
+		return
+	}
+	r.lastSweep = now
+	for key, bucket := range r.buckets {
+		if bucket.refill(now, r.config.Rate, r.burst); bucket.tokens >= r.burst {
+			delete(r.buckets, key)
+		}
+	}
+}
+
+func (b *tokenBucket) refill(now time.Time, rate, burst float64) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(burst, b.tokens+elapsed*rate)
+		b.last = now
+	}
+}
+
+func validateRateLimitRule(rule RateLimitRule) error {
+	var err error
+	switch {
+	case rule.Key != RateLimitByIP && rule.Key != RateLimitByHeader && rule.Key != RateLimitByRoute:
+		err = newRateLimitError(InvalidRateLimit, "the key `"+rule.Key+"` is not ip, header or route", nil)
+	case rule.Key == RateLimitByHeader && rule.Header == "":
+		err = newRateLimitError(InvalidRateLimit, "the header is required when the key is header", nil)
+	case rule.Key == RateLimitByRoute && len(rule.Routes) == 0:
+		err = newRateLimitError(InvalidRateLimit, "the routes are required when the key is route", nil)
+	case !(rule.Rate > 0) || math.IsInf(rule.Rate, 1):
+		err = newRateLimitError(InvalidRateLimit, "the rate must be a positive number of requests per second", nil)
+	case rule.Burst < 0:
+		err = newRateLimitError(InvalidRateLimit, "the burst must not be negative", nil)
+	}
+	if err != nil {
+		return err
+	}
+	for _, route := range rule.Routes {
+		if !strings.HasPrefix(route, "/") {
+			return newRateLimitError(InvalidRateLimit, "the route `"+route+"` does not start with /", nil)
+		}
+	}
+	return nil
+}
+
+func grpcRateLimitRequest(ctx context.Context, fullMethod string) rateLimitRequest {
+	request := rateLimitRequest{route: fullMethod, header: func(string) string { return "" }}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		request.ip = p.Addr.String()
+		if host, _, err := net.SplitHostPort(request.ip); err == nil {
+			request.ip = host
+		}
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		request.header = func(name string) string {
+			if values := md.Get(name); len(values) > 0 {
+				return values[0]
+			}
+			return ""
+		}
+	}
+	return request
+}
+
+// retryAfterSeconds rounds retryAfter up to whole seconds, as Retry-After is sent
+func retryAfterSeconds(retryAfter time.Duration) string {
+	return strconv.FormatInt(int64(math.Max(1, math.Ceil(retryAfter.Seconds()))), 10)
+}
+
+func resourceExhausted(retryAfter time.Duration) error {
+	return status.Errorf(codes.ResourceExhausted, "rate limit exceeded, retry after %v seconds", retryAfterSeconds(retryAfter))
+}
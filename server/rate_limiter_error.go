@@ -0,0 +1,37 @@
+package server
+
+import (
+	"github.com/janmbaco/go-infrastructure/v2/errors"
+)
+
+// RateLimitError is the errors of RateLimiter
+type RateLimitError interface {
+	errors.CustomError
+	GetErrorType() RateLimitErrorType
+}
+
+type rateLimitError struct {
+	errors.CustomizableError
+	ErrorType RateLimitErrorType
+}
+
+func newRateLimitError(errorType RateLimitErrorType, message string, internalError error) RateLimitError {
+	return &rateLimitError{
+		CustomizableError: errors.CustomizableError{
+			Message:       message,
+			InternalError: internalError,
+		},
+		ErrorType: errorType,
+	}
+}
+
+func (e *rateLimitError) GetErrorType() RateLimitErrorType {
+	return e.ErrorType
+}
+
+type RateLimitErrorType uint8
+
+const (
+	UnexpectedRateLimitError RateLimitErrorType = iota
+	InvalidRateLimit
+)
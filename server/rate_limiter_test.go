@@ -0,0 +1,272 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// newTestRateLimiter returns a RateLimiter whose clock only moves with the returned function
+func newTestRateLimiter(t *testing.T, limits RateLimits) (*RateLimiter, func(time.Duration)) {
+	limiter := NewRateLimiter()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter.now = func() time.Time { return now }
+	require.NoError(t, limiter.Set(limits))
+	return limiter, func(d time.Duration) { now = now.Add(d) }
+}
+
+func limitedGet(handler http.Handler, remoteAddr, target string, header http.Header) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.RemoteAddr = remoteAddr
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimiter_Middleware_WhenClientExceedsItsRate_ThenRejectsItUntilTokensRefill(t *testing.T) {
+	// Arrange
+	limiter, advance := newTestRateLimiter(t, RateLimits{Rules: []RateLimitRule{{Key: RateLimitByIP, Rate: 0.5, Burst: 2}}})
+	handler := limiter.Middleware(namedHandler("app"))
+
+	// Act
+	first := limitedGet(handler, "10.0.0.1:1000", "/", nil)
+	second := limitedGet(handler, "10.0.0.1:1001", "/", nil)
+	rejected := limitedGet(handler, "10.0.0.1:1002", "/", nil)
+	otherClient := limitedGet(handler, "10.0.0.2:1000", "/", nil)
+	advance(2 * time.Second)
+	refilled := limitedGet(handler, "10.0.0.1:1003", "/", nil)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, http.StatusOK, second.Code)
+	assert.Equal(t, http.StatusTooManyRequests, rejected.Code)
+	assert.Equal(t, "2", rejected.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusOK, otherClient.Code)
+	assert.Equal(t, http.StatusOK, refilled.Code)
+}
+
+func TestRateLimiter_Middleware_WhenRulesAreKeyedByHeaderOrRoute_ThenCountsThemApart(t *testing.T) {
+	// Arrange
+	limiter, _ := newTestRateLimiter(t, RateLimits{Rules: []RateLimitRule{
+		{Key: RateLimitByHeader, Header: "X-API-Key", Routes: []string{"/api"}, Rate: 1},
+		{Key: RateLimitByRoute, Routes: []string{"/login", "/signup/"}, Rate: 1},
+	}})
+	handler := limiter.Middleware(namedHandler("app"))
+	keyA, keyB := http.Header{"X-Api-Key": {"a"}}, http.Header{"X-Api-Key": {"b"}}
+
+	cases := []struct {
+		remoteAddr, target string
+		header             http.Header
+		expected           int
+	}{
+		{"10.0.0.1:1", "/api/users", keyA, http.StatusOK},
+		{"10.0.0.2:1", "/api/orders", keyA, http.StatusTooManyRequests},
+		{"10.0.0.1:1", "/api", keyB, http.StatusOK},
+		{"10.0.0.1:1", "/apis", keyA, http.StatusOK},
+		{"10.0.0.1:1", "/login", nil, http.StatusOK},
+		{"10.0.0.2:1", "/login", nil, http.StatusTooManyRequests},
+		{"10.0.0.2:1", "/signup", nil, http.StatusOK},
+		{"10.0.0.2:1", "/", nil, http.StatusOK},
+	}
+	for _, c := range cases {
+		// Act
+		w := limitedGet(handler, c.remoteAddr, c.target, c.header)
+
+		// Assert
+		assert.Equal(t, c.expected, w.Code, c.target)
+	}
+}
+
+func TestRateLimiter_Middleware_WhenMaxInFlightIsReached_ThenRejectsUntilARequestEnds(t *testing.T) {
+	// Arrange
+	limiter, _ := newTestRateLimiter(t, RateLimits{MaxInFlight: 1})
+	entered, release := make(chan struct{}), make(chan struct{})
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			close(entered)
+			<-release
+		}
+	}))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		limitedGet(handler, "10.0.0.1:1", "/slow", nil)
+	}()
+	<-entered
+
+	// Act
+	rejected := limitedGet(handler, "10.0.0.2:1", "/", nil)
+	close(release)
+	<-done
+	accepted := limitedGet(handler, "10.0.0.2:1", "/", nil)
+
+	// Assert
+	assert.Equal(t, http.StatusTooManyRequests, rejected.Code)
+	assert.Equal(t, "1", rejected.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusOK, accepted.Code)
+}
+
+func TestRateLimiter_Middleware_WhenALaterRuleRejects_ThenEarlierRulesKeepTheirTokens(t *testing.T) {
+	// Arrange
+	limiter, _ := newTestRateLimiter(t, RateLimits{Rules: []RateLimitRule{
+		{Key: RateLimitByIP, Rate: 1, Burst: 5},
+		{Key: RateLimitByRoute, Routes: []string{"/login"}, Rate: 1},
+	}})
+	handler := limiter.Middleware(namedHandler("app"))
+	require.Equal(t, http.StatusOK, limitedGet(handler, "10.0.0.1:1", "/login", nil).Code)
+	ipBucket := limiter.limits.Load().rules[0].buckets["10.0.0.1"]
+	require.NotNil(t, ipBucket)
+	tokens := ipBucket.tokens
+
+	// Act
+	rejected := limitedGet(handler, "10.0.0.1:1", "/login", nil)
+
+	// Assert
+	assert.Equal(t, http.StatusTooManyRequests, rejected.Code)
+	assert.Equal(t, tokens, ipBucket.tokens)
+	assert.Zero(t, limiter.inFlight.Load())
+}
+
+func TestRateLimiter_Middleware_WhenTheWaitIsBelowANanosecond_ThenStillRejects(t *testing.T) {
+	// Arrange
+	limiter, _ := newTestRateLimiter(t, RateLimits{Rules: []RateLimitRule{
+		{Key: RateLimitByIP, Rate: 1, Burst: 5},
+		// a token takes a tenth of a nanosecond
+		{Key: RateLimitByRoute, Routes: []string{"/"}, Rate: 1e10, Burst: 1},
+	}})
+	handler := limiter.Middleware(namedHandler("app"))
+	require.Equal(t, http.StatusOK, limitedGet(handler, "10.0.0.1:1", "/", nil).Code)
+	ipBucket := limiter.limits.Load().rules[0].buckets["10.0.0.1"]
+	tokens := ipBucket.tokens
+
+	// Act
+	rejected := limitedGet(handler, "10.0.0.1:1", "/", nil)
+
+	// Assert
+	assert.Equal(t, http.StatusTooManyRequests, rejected.Code)
+	assert.Equal(t, "1", rejected.Header().Get("Retry-After"))
+	assert.Equal(t, tokens, ipBucket.tokens)
+}
+
+func TestRateLimiter_Middleware_WhenMaxInFlightRejects_ThenRulesKeepTheirTokens(t *testing.T) {
+	// Arrange
+	limiter, _ := newTestRateLimiter(t, RateLimits{Rules: []RateLimitRule{{Key: RateLimitByIP, Rate: 1, Burst: 5}}, MaxInFlight: 1})
+	release, _, ok := limiter.acquire(rateLimitRequest{ip: "10.0.0.1", route: "/"})
+	require.True(t, ok)
+	defer release()
+	ipBucket := limiter.limits.Load().rules[0].buckets["10.0.0.1"]
+	tokens := ipBucket.tokens
+
+	// Act
+	rejected := limitedGet(limiter.Middleware(namedHandler("app")), "10.0.0.1:1", "/", nil)
+
+	// Assert
+	assert.Equal(t, http.StatusTooManyRequests, rejected.Code)
+	assert.Equal(t, tokens, ipBucket.tokens)
+}
+
+func TestRateLimiter_Set_WhenLimitsChange_ThenKeepsTheBucketsOfUnchangedRules(t *testing.T) {
+	// Arrange
+	ipRule := RateLimitRule{Key: RateLimitByIP, Rate: 1}
+	limiter, _ := newTestRateLimiter(t, RateLimits{Rules: []RateLimitRule{ipRule}})
+	handler := limiter.Middleware(namedHandler("app"))
+	require.Equal(t, http.StatusOK, limitedGet(handler, "10.0.0.1:1", "/", nil).Code)
+
+	// Act
+	require.NoError(t, limiter.Set(RateLimits{Rules: []RateLimitRule{ipRule, {Key: RateLimitByRoute, Routes: []string{"/"}, Rate: 100}}}))
+	unchanged := limitedGet(handler, "10.0.0.1:1", "/", nil)
+	require.NoError(t, limiter.Set(RateLimits{}))
+	removed := limitedGet(handler, "10.0.0.1:1", "/", nil)
+
+	// Assert
+	assert.Equal(t, http.StatusTooManyRequests, unchanged.Code)
+	assert.Equal(t, http.StatusOK, removed.Code)
+}
+
+func TestRateLimiter_Set_WhenLimitsAreInvalid_ThenKeepsTheCurrentOnes(t *testing.T) {
+	// Arrange
+	limiter, _ := newTestRateLimiter(t, RateLimits{Rules: []RateLimitRule{{Key: RateLimitByIP, Rate: 1}}})
+
+	invalid := []RateLimits{
+		{MaxInFlight: -1},
+		{Rules: []RateLimitRule{{Key: "user", Rate: 1}}},
+		{Rules: []RateLimitRule{{Key: RateLimitByHeader, Rate: 1}}},
+		{Rules: []RateLimitRule{{Key: RateLimitByRoute, Rate: 1}}},
+		{Rules: []RateLimitRule{{Key: RateLimitByIP}}},
+		{Rules: []RateLimitRule{{Key: RateLimitByIP, Rate: 1, Burst: -1}}},
+		{Rules: []RateLimitRule{{Key: RateLimitByIP, Rate: 1, Routes: []string{"api"}}}},
+	}
+	for _, limits := range invalid {
+		// Act
+		err := limiter.Set(limits)
+
+		// Assert
+		var rateLimitErr RateLimitError
+		require.ErrorAs(t, err, &rateLimitErr, limits)
+		assert.Equal(t, InvalidRateLimit, rateLimitErr.GetErrorType())
+	}
+	assert.Len(t, limiter.limits.Load().rules, 1)
+}
+
+func TestRateLimiter_Sweep_WhenBucketsAreFull_ThenRemovesThem(t *testing.T) {
+	// Arrange
+	limiter, advance := newTestRateLimiter(t, RateLimits{Rules: []RateLimitRule{{Key: RateLimitByIP, Rate: 10}}})
+	handler := limiter.Middleware(namedHandler("app"))
+	limitedGet(handler, "10.0.0.1:1", "/", nil)
+	limitedGet(handler, "10.0.0.2:1", "/", nil)
+
+	// Act
+	advance(time.Minute)
+	limitedGet(handler, "10.0.0.3:1", "/", nil)
+
+	// Assert
+	rule := limiter.limits.Load().rules[0]
+	assert.Len(t, rule.buckets, 1)
+}
+
+func TestRateLimiter_Interceptors_WhenCallsExceedTheRate_ThenReturnResourceExhausted(t *testing.T) {
+	// Arrange
+	limiter, _ := newTestRateLimiter(t, RateLimits{Rules: []RateLimitRule{
+		{Key: RateLimitByHeader, Header: "x-api-key", Routes: []string{"/test.Service"}, Rate: 1},
+	}})
+	unary, stream := limiter.UnaryInterceptor(), limiter.StreamInterceptor()
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1000}})
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("x-api-key", "a"))
+	unaryHandler := func(context.Context, interface{}) (interface{}, error) { return "ok", nil }
+	streamHandler := func(interface{}, grpc.ServerStream) error { return nil }
+
+	// Act
+	_, allowed := unary(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}, unaryHandler)
+	_, rejected := unary(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}, unaryHandler)
+	streamRejected := stream(nil, &rateLimitTestStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: "/test.Service/Stream"}, streamHandler)
+	otherService := stream(nil, &rateLimitTestStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: "/other.Service/Stream"}, streamHandler)
+
+	// Assert
+	require.NoError(t, allowed)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(rejected))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(streamRejected))
+	assert.NoError(t, otherService)
+}
+
+type rateLimitTestStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *rateLimitTestStream) Context() context.Context { return s.ctx }
+
+func (s *rateLimitTestStream) SetHeader(metadata.MD) error { return nil }
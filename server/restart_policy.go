@@ -0,0 +1,188 @@
+package server
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultInitialBackoff is the first wait before retrying when RestartPolicy.InitialBackoff is not set
+	DefaultInitialBackoff = 100 * time.Millisecond
+	// DefaultMaxBackoff is the longest wait between restarts when RestartPolicy.MaxBackoff is not set
+	DefaultMaxBackoff = 30 * time.Second
+	// DefaultRestartWindow is the time restarts are remembered when RestartPolicy.Window is not set
+	DefaultRestartWindow = 5 * time.Minute
+)
+
+type (
+	// RestartPolicy bounds the restarts of a listener, after a failure of its server or a config change.
+	// The zero value keeps the listener without retries: a failure restores the config when possible
+	// or stops the listener, and config changes restart it right away
+	RestartPolicy struct {
+		// MaxRetries is the number of restarts allowed within Window, one more stops the listener
+		// with a RestartBudgetExhausted ListenerError. Zero disables the policy
+		MaxRetries int
+		// InitialBackoff is the wait before retrying after a failure, DefaultInitialBackoff when zero.
+		// It doubles with every restart within Window up to MaxBackoff, DefaultMaxBackoff when zero.
+		// A config change waits only when there were other restarts within Window
+		InitialBackoff time.Duration
+		MaxBackoff     time.Duration
+		// Window is the time restarts are remembered, DefaultRestartWindow when zero
+		Window time.Duration
+		// Jitter spreads every wait randomly by up to this fraction of it, between 0 and 1
+		Jitter float64
+	}
+
+	// RestartAttempt is a restart of a listener
+	RestartAttempt struct {
+		Time time.Time
+		// Cause is the failure that made the listener restart, nil for a config change
+		Cause error
+		// Delay is the wait before the restart
+		Delay time.Duration
+	}
+
+	// RestartBudgetError is the ListenerError that stops a listener when its RestartPolicy is exhausted
+	RestartBudgetError interface {
+		ListenerError
+		// GetAttempts returns the restarts within the window, the last one being the one refused
+		GetAttempts() []RestartAttempt
+	}
+
+	restartBudgetError struct {
+		listenerError
+		attempts []RestartAttempt
+	}
+
+	// restartBudget keeps the restarts of a listener to apply its RestartPolicy
+	restartBudget struct {
+		policy   RestartPolicy
+		attempts []RestartAttempt
+		stopped  chan struct{}
+		once     *sync.Once
+		mutex    sync.Mutex
+	}
+)
+
+func newRestartBudget(policy RestartPolicy) *restartBudget {
+	return &restartBudget{policy: policy, stopped: make(chan struct{}), once: &sync.Once{}}
+}
+
+func newRestartBudgetError(attempts []RestartAttempt, window time.Duration) RestartBudgetError {
+	last := attempts[len(attempts)-1]
+	message := fmt.Sprintf("restart budget exhausted, %d restarts within %v", len(attempts)-1, window)
+	if last.Cause != nil {
+		message += ": " + last.Cause.Error()
+	}
+	return &restartBudgetError{
+		listenerError: *newListenerError(RestartBudgetExhausted, message, last.Cause).(*listenerError),
+		attempts:      attempts,
+	}
+}
+
+func (e *restartBudgetError) GetAttempts() []RestartAttempt {
+	return append([]RestartAttempt{}, e.attempts...)
+}
+
+func (rb *restartBudget) enabled() bool {
+	return rb.policy.MaxRetries > 0
+}
+
+// reset forgets the restarts when the listener starts
+func (rb *restartBudget) reset() {
+	rb.mutex.Lock()
+	defer rb.mutex.Unlock()
+	rb.attempts = nil
+	rb.stopped = make(chan struct{})
+	rb.once = &sync.Once{}
+}
+
+// stop interrupts the waits in progress
+func (rb *restartBudget) stop() {
+	rb.mutex.Lock()
+	stopped, once := rb.stopped, rb.once
+	rb.mutex.Unlock()
+	once.Do(func() { close(stopped) })
+}
+
+// next records a restart caused by cause, nil for a config change, and returns the wait before it.
+// It returns a RestartBudgetError when the restart exceeds the policy.
+func (rb *restartBudget) next(now time.Time, cause error) (time.Duration, RestartBudgetError) {
+	rb.mutex.Lock()
+	defer rb.mutex.Unlock()
+	window := rb.policy.Window
+	if window <= 0 {
+		window = DefaultRestartWindow
+	}
+	recent := rb.attempts[:0]
+	for _, attempt := range rb.attempts {
+		if now.Sub(attempt.Time) < window {
+			recent = append(recent, attempt)
+		}
+	}
+	rb.attempts = recent
+	if len(rb.attempts) >= rb.policy.MaxRetries {
+		attempts := append(append([]RestartAttempt{}, rb.attempts...), RestartAttempt{Time: now, Cause: cause})
+		return 0, newRestartBudgetError(attempts, window)
+	}
+	steps := len(rb.attempts)
+	if cause != nil {
+		steps++
+	}
+	delay := rb.backoff(steps)
+	rb.attempts = append(rb.attempts, RestartAttempt{Time: now, Cause: cause, Delay: delay})
+	return delay, nil
+}
+
+// backoff returns the wait before the restart number steps, doubling from InitialBackoff
+func (rb *restartBudget) backoff(steps int) time.Duration {
+	if steps == 0 {
+		return 0
+	}
+	initial, maxBackoff := rb.policy.InitialBackoff, rb.policy.MaxBackoff
+	if initial <= 0 {
+		initial = DefaultInitialBackoff
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultMaxBackoff
+	}
+	delay := initial
+	for i := 1; i < steps && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	if jitter := rb.policy.Jitter; jitter > 0 {
+		if jitter > 1 {
+			jitter = 1
+		}
+		delay += time.Duration(float64(delay) * jitter * (2*rand.Float64() - 1)) //nolint:gosec // jitter does not need a secure source
+	}
+	return delay
+}
+
+// wait waits for delay and reports false when the listener stops meanwhile
+func (rb *restartBudget) wait(delay time.Duration) bool {
+	rb.mutex.Lock()
+	stopped := rb.stopped
+	rb.mutex.Unlock()
+	if delay <= 0 {
+		select {
+		case <-stopped:
+			return false
+		default:
+			return true
+		}
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-stopped:
+		return false
+	}
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRestartBudget_next_WhenFailuresRepeat_ThenBacksOffExponentiallyUpToMax(t *testing.T) {
+	// Arrange
+	budget := newRestartBudget(RestartPolicy{MaxRetries: 10, InitialBackoff: 100 * time.Millisecond, MaxBackoff: 500 * time.Millisecond})
+	now := time.Now()
+	failure := errors.New("bind: address already in use")
+
+	// Act
+	var delays []time.Duration
+	for i := 0; i < 5; i++ {
+		delay, budgetErr := budget.next(now, failure)
+		require.Nil(t, budgetErr)
+		delays = append(delays, delay)
+	}
+
+	// Assert
+	assert.Equal(t, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 500 * time.Millisecond, 500 * time.Millisecond}, delays)
+}
+
+func TestRestartBudget_next_WhenConfigChangeIsTheFirstRestart_ThenDoesNotWait(t *testing.T) {
+	// Arrange
+	budget := newRestartBudget(RestartPolicy{MaxRetries: 3, InitialBackoff: time.Second})
+	now := time.Now()
+
+	// Act
+	first, _ := budget.next(now, nil)
+	second, _ := budget.next(now, nil)
+
+	// Assert
+	assert.Zero(t, first)
+	assert.Equal(t, time.Second, second)
+}
+
+func TestRestartBudget_next_WhenBudgetExhausted_ThenReturnsErrorWithAttempts(t *testing.T) {
+	// Arrange
+	budget := newRestartBudget(RestartPolicy{MaxRetries: 2, Window: time.Minute})
+	now := time.Now()
+	_, _ = budget.next(now.Add(-2*time.Minute), errors.New("forgotten"))
+	_, _ = budget.next(now, nil)
+	_, _ = budget.next(now, errors.New("first"))
+
+	// Act
+	_, budgetErr := budget.next(now, errors.New("last"))
+
+	// Assert
+	require.NotNil(t, budgetErr)
+	assert.Equal(t, RestartBudgetExhausted, budgetErr.GetErrorType())
+	assert.Equal(t, "restart budget exhausted, 2 restarts within 1m0s: last", budgetErr.GetMessage())
+	attempts := budgetErr.GetAttempts()
+	require.Len(t, attempts, 3)
+	assert.Nil(t, attempts[0].Cause)
+	assert.EqualError(t, attempts[1].Cause, "first")
+	assert.EqualError(t, attempts[2].Cause, "last")
+}
+
+func TestRestartBudget_backoff_WhenJitterSet_ThenStaysWithinSpread(t *testing.T) {
+	// Arrange
+	budget := newRestartBudget(RestartPolicy{MaxRetries: 1, InitialBackoff: time.Second, Jitter: 0.5})
+
+	for i := 0; i < 100; i++ {
+		// Act
+		delay := budget.backoff(1)
+
+		// Assert
+		assert.GreaterOrEqual(t, delay, 500*time.Millisecond)
+		assert.LessOrEqual(t, delay, 1500*time.Millisecond)
+	}
+}
+
+func TestRestartBudget_wait_WhenStopped_ThenReturnsFalse(t *testing.T) {
+	// Arrange
+	budget := newRestartBudget(RestartPolicy{MaxRetries: 1})
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		budget.stop()
+	}()
+
+	// Act
+	waited := budget.wait(time.Minute)
+	budget.reset()
+
+	// Assert
+	assert.False(t, waited)
+	assert.True(t, budget.wait(0))
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/janmbaco/go-infrastructure/v2/configuration"
+)
+
+type listenerResult struct {
+	err   ListenerError
+	index int
+}
+
+// Run starts the listeners and blocks until ctx is done, SIGINT or SIGTERM is received
+// or one of the listeners finishes. Then it stops the remaining listeners gracefully
+// and returns the first ListenerError reported by any of them.
+func Run(ctx context.Context, listeners ...Listener) error {
+	return run(ctx, nil, listeners)
+}
+
+// RunWithConfigReload works like Run and also forces a refresh of the configuration
+// handled by configHandler every time SIGHUP is received.
+func RunWithConfigReload(ctx context.Context, configHandler configuration.ConfigHandler, listeners ...Listener) error {
+	return run(ctx, configHandler, listeners)
+}
+
+func run(ctx context.Context, configHandler configuration.ConfigHandler, listeners []Listener) error {
+	if len(listeners) == 0 {
+		return nil
+	}
+
+	ctx, stopSignals := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	var reload chan os.Signal
+	if configHandler != nil {
+		reload = make(chan os.Signal, 1)
+		signal.Notify(reload, syscall.SIGHUP)
+		defer signal.Stop(reload)
+	}
+
+	results := make(chan listenerResult, len(listeners))
+	for i, listener := range listeners {
+		finish := listener.Start()
+		go func(index int) {
+			results <- listenerResult{index: index, err: <-finish}
+		}(i)
+	}
+
+	var firstErr ListenerError
+	finished := make([]bool, len(listeners))
+	waiting := true
+	for waiting {
+		select {
+		case result := <-results:
+			finished[result.index] = true
+			firstErr = result.err
+			waiting = false
+		case <-ctx.Done():
+			waiting = false
+		case <-reload:
+			_ = configHandler.ForceRefresh() //nolint:errcheck // a failed refresh keeps the current config
+		}
+	}
+
+	remaining := 0
+	for i, listener := range listeners {
+		if !finished[i] {
+			remaining++
+			go listener.Stop()
+		}
+	}
+	for ; remaining > 0; remaining-- {
+		if result := <-results; firstErr == nil {
+			firstErr = result.err
+		}
+	}
+
+	if firstErr == nil {
+		return nil
+	}
+	return firstErr
+}
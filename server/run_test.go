@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeListener struct {
+	finish  chan ListenerError
+	started chan bool
+	stopped chan bool
+}
+
+func newFakeListener() *fakeListener {
+	return &fakeListener{finish: make(chan ListenerError, 1), started: make(chan bool, 1), stopped: make(chan bool, 1)}
+}
+
+func (f *fakeListener) Start() chan ListenerError {
+	f.started <- true
+	return f.finish
+}
+
+func (f *fakeListener) Stop() {
+	f.stopped <- true
+	f.finish <- nil
+}
+
+func (f *fakeListener) StopCtx(context.Context) error {
+	f.Stop()
+	return nil
+}
+
+func (f *fakeListener) State() State {
+	return Stopped
+}
+
+func (f *fakeListener) StateChangedSubscribe(func(StateChangedEvent)) error {
+	return nil
+}
+
+func (f *fakeListener) StateChangedUnsubscribe(func(StateChangedEvent)) error {
+	return nil
+}
+
+func TestRun_WhenNoListeners_ThenReturnsNil(t *testing.T) {
+	// Act
+	err := Run(context.Background())
+
+	// Assert
+	assert.NoError(t, err)
+}
+
+func TestRun_WhenContextIsDone_ThenStopsAllListeners(t *testing.T) {
+	// Arrange
+	first := newFakeListener()
+	second := newFakeListener()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	// Act
+	err := Run(ctx, first, second)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Len(t, first.started, 1)
+	assert.Len(t, second.started, 1)
+	assert.Len(t, first.stopped, 1)
+	assert.Len(t, second.stopped, 1)
+}
+
+func TestRun_WhenListenerFails_ThenStopsOthersAndReturnsItsError(t *testing.T) {
+	// Arrange
+	failing := newFakeListener()
+	healthy := newFakeListener()
+	listenerErr := newListenerError(UnexpectedError, "boom", nil)
+	failing.finish <- listenerErr
+
+	// Act
+	err := Run(context.Background(), failing, healthy)
+
+	// Assert
+	assert.Equal(t, listenerErr, err)
+	assert.Len(t, failing.stopped, 0)
+	assert.Len(t, healthy.stopped, 1)
+}
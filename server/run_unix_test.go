@@ -0,0 +1,66 @@
+//go:build !windows
+
+package server
+
+import (
+	"context"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/janmbaco/go-infrastructure/v2/configuration"
+)
+
+type refreshCountingConfigHandler struct {
+	configuration.ConfigHandler
+	refreshed chan bool
+}
+
+func (h *refreshCountingConfigHandler) ForceRefresh() error {
+	h.refreshed <- true
+	return nil
+}
+
+func TestRunWithConfigReload_WhenSIGHUPReceived_ThenForcesConfigRefresh(t *testing.T) {
+	// Arrange
+	listener := newFakeListener()
+	configHandler := &refreshCountingConfigHandler{refreshed: make(chan bool, 1)}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- RunWithConfigReload(ctx, configHandler, listener)
+	}()
+	<-listener.started
+
+	// Act
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGHUP))
+
+	// Assert
+	select {
+	case <-configHandler.refreshed:
+	case <-time.After(time.Second):
+		t.Fatal("config was not refreshed")
+	}
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+func TestRun_WhenSIGTERMReceived_ThenStopsListeners(t *testing.T) {
+	// Arrange
+	listener := newFakeListener()
+	done := make(chan error, 1)
+	go func() {
+		done <- Run(context.Background(), listener)
+	}()
+	<-listener.started
+
+	// Act
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGTERM))
+
+	// Assert
+	assert.NoError(t, <-done)
+	assert.Len(t, listener.stopped, 1)
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync/atomic"
+)
+
+const (
+	// DefaultRuntimeConfigGlobal is the window property that holds the runtime configuration in the browser
+	DefaultRuntimeConfigGlobal = "__ENV__"
+	// DefaultRuntimeConfigPath is the path of the script that sets the runtime configuration
+	DefaultRuntimeConfigPath = "/env.js"
+)
+
+type (
+	// RuntimeConfig holds the environment-specific settings of a single page app, like the API base URL or
+	// the feature flags, so they change without rebuilding the app. Set can be called while the handler serves.
+	RuntimeConfig struct {
+		global string
+		script atomic.Pointer[runtimeScript]
+	}
+
+	runtimeScript struct {
+		content []byte
+		etag    string
+	}
+)
+
+// NewRuntimeConfig returns a RuntimeConfig exposed as window[global], DefaultRuntimeConfigGlobal when global is empty
+func NewRuntimeConfig(global string) *RuntimeConfig {
+	if global == "" {
+		global = DefaultRuntimeConfigGlobal
+	}
+	rc := &RuntimeConfig{global: global}
+	_ = rc.Set(nil) //nolint:errcheck // nil always marshals
+	return rc
+}
+
+// Set replaces the settings with values marshalled to JSON; nil values, like a nil map, are an empty object
+func (rc *RuntimeConfig) Set(values interface{}) error {
+	content, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	if string(content) == "null" {
+		content = []byte("{}")
+	}
+	global, _ := json.Marshal(rc.global) //nolint:errcheck // strings always marshal
+	// json.Marshal escapes <, > and &, so the script can not close the tag it is injected in
+	script := []byte("window[" + string(global) + "]=" + string(content) + ";")
+	hash := sha256.Sum256(script)
+	rc.script.Store(&runtimeScript{content: script, etag: `"` + hex.EncodeToString(hash[:16]) + `"`})
+	return nil
+}
+
+// Script returns the JavaScript that sets the settings in the browser
+func (rc *RuntimeConfig) Script() []byte {
+	return rc.script.Load().content
+}
+
+func (rc *RuntimeConfig) current() *runtimeScript {
+	return rc.script.Load()
+}
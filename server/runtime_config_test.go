@@ -0,0 +1,69 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRuntimeConfig_WhenNoGlobal_ThenSetsEmptyDefault(t *testing.T) {
+	// Act
+	rc := NewRuntimeConfig("")
+
+	// Assert
+	assert.Equal(t, `window["__ENV__"]={};`, string(rc.Script()))
+}
+
+func TestRuntimeConfig_Set_WhenValues_ThenScriptSetsThem(t *testing.T) {
+	// Arrange
+	rc := NewRuntimeConfig("APP_CONFIG")
+	before := rc.current().etag
+
+	// Act
+	err := rc.Set(map[string]interface{}{"apiBaseUrl": "https://api.example.com", "features": map[string]bool{"beta": true}})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, `window["APP_CONFIG"]={"apiBaseUrl":"https://api.example.com","features":{"beta":true}};`, string(rc.Script()))
+	assert.NotEqual(t, before, rc.current().etag)
+}
+
+func TestRuntimeConfig_Set_WhenValuesCloseScriptTag_ThenEscapesThem(t *testing.T) {
+	// Arrange
+	rc := NewRuntimeConfig(`</script><script>alert(1)</script>`)
+
+	// Act
+	err := rc.Set(map[string]string{"title": "</script><script>alert(1)</script>"})
+
+	// Assert
+	require.NoError(t, err)
+	assert.NotContains(t, string(rc.Script()), "</script>")
+	assert.NotContains(t, string(rc.Script()), "<script>")
+}
+
+func TestRuntimeConfig_Set_WhenValuesCanNotBeMarshalled_ThenKeepsLastScript(t *testing.T) {
+	// Arrange
+	rc := NewRuntimeConfig("")
+	require.NoError(t, rc.Set(map[string]int{"retries": 3}))
+
+	// Act
+	err := rc.Set(map[string]interface{}{"invalid": make(chan int)})
+
+	// Assert
+	assert.Error(t, err)
+	assert.Equal(t, `window["__ENV__"]={"retries":3};`, string(rc.Script()))
+}
+
+func TestRuntimeConfig_Set_WhenNilMap_ThenSetsEmptyObject(t *testing.T) {
+	// Arrange
+	rc := NewRuntimeConfig("")
+	var values map[string]interface{}
+
+	// Act
+	err := rc.Set(values)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, `window["__ENV__"]={};`, string(rc.Script()))
+}
@@ -1,34 +1,376 @@
 package server
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
 	"net/http"
-	"os"
-	"path/filepath"
+	"path"
+	"strings"
+	"time"
 )
 
-type singlePageApp struct {
-	staticPath string
-	indexPath  string
+const (
+	// ImmutableCacheControl is the Cache-Control sent for fingerprinted assets
+	ImmutableCacheControl = "public, max-age=31536000, immutable"
+	// NoCacheControl is the Cache-Control sent for the index file
+	NoCacheControl = "no-cache"
+)
+
+// DefaultPrecompressedEncodings are the encodings whose siblings (app.js.br, app.js.gz) are served, in order of preference
+var DefaultPrecompressedEncodings = []string{"br", "gzip"}
+
+var encodingExtensions = map[string]string{"br": ".br", "gzip": ".gz", "zstd": ".zst"}
+
+type (
+	// SinglePageAppOption configures the handler returned by NewSinglePageApp and NewSinglePageAppFS
+	SinglePageAppOption func(*singlePageApp)
+
+	singlePageApp struct {
+		fsys      fs.FS
+		index     string
+		encodings []string
+		immutable func(name string) bool
+		assets    string
+		fallback  func(r *http.Request) bool
+		etags     *etagCache
+		env       *RuntimeConfig
+		envPath   string
+		envInject *RuntimeConfig
+	}
+)
+
+// WithPrecompressedEncodings sets the encodings whose precompressed siblings are served, in order of preference.
+// With no encodings the files are always served as they are.
+func WithPrecompressedEncodings(encodings ...string) SinglePageAppOption {
+	return func(sap *singlePageApp) {
+		sap.encodings = encodings
+	}
+}
+
+// WithImmutableAssets sets the function that tells whether a file name is fingerprinted,
+// so it is served with ImmutableCacheControl. IsFingerprinted is used by default.
+func WithImmutableAssets(immutable func(name string) bool) SinglePageAppOption {
+	return func(sap *singlePageApp) {
+		sap.immutable = immutable
+	}
+}
+
+// WithAssetCacheControl sets the Cache-Control sent for the files that are neither the index nor fingerprinted,
+// which have none by default
+func WithAssetCacheControl(cacheControl string) SinglePageAppOption {
+	return func(sap *singlePageApp) {
+		sap.assets = cacheControl
+	}
+}
+
+// WithFallback sets the function that tells whether a request for a path that does not exist is answered
+// with the index file instead of 404 Not Found. IsNavigationRequest is used by default, and nil never falls back.
+func WithFallback(fallback func(r *http.Request) bool) SinglePageAppOption {
+	return func(sap *singlePageApp) {
+		sap.fallback = fallback
+	}
+}
+
+// WithRuntimeConfigScript serves the script of env at path, DefaultRuntimeConfigPath when path is empty,
+// to be loaded by the index with <script src="/env.js"></script> before the app
+func WithRuntimeConfigScript(env *RuntimeConfig, path string) SinglePageAppOption {
+	return func(sap *singlePageApp) {
+		if path == "" {
+			path = DefaultRuntimeConfigPath
+		}
+		sap.env, sap.envPath = env, path
+	}
+}
+
+// WithRuntimeConfigInjection injects the script of env in the index file, before </head>
+func WithRuntimeConfigInjection(env *RuntimeConfig) SinglePageAppOption {
+	return func(sap *singlePageApp) {
+		sap.envInject = env
+	}
 }
 
-// NewSinglePageApp return the handler for a Single Page App
-func NewSinglePageApp(staticPath, indexPath string) http.Handler {
-	return &singlePageApp{staticPath: staticPath, indexPath: indexPath}
+// NewSinglePageApp return the handler for a Single Page App.
+// Symlinks that resolve outside staticPath are not served.
+func NewSinglePageApp(staticPath, indexPath string, opts ...SinglePageAppOption) http.Handler {
+	return NewSinglePageAppFS(rootFS(staticPath), indexPath, opts...)
+}
+
+// NewSinglePageAppFS returns the handler for a Single Page App served from fsys, like an embed.FS.
+// Missing paths accepted by the fallback are answered with the index file, the rest with 404 Not Found.
+// Hidden files, whose path has a segment starting with a dot other than .well-known, are never served.
+// The ETag of a file is computed when it is first served and again when it changes.
+func NewSinglePageAppFS(fsys fs.FS, index string, opts ...SinglePageAppOption) http.Handler {
+	sap := &singlePageApp{
+		fsys:      fsys,
+		index:     fsName(index),
+		encodings: DefaultPrecompressedEncodings,
+		immutable: IsFingerprinted,
+		fallback:  IsNavigationRequest,
+		etags:     newEtagCache(fsys),
+	}
+	for _, opt := range opts {
+		opt(sap)
+	}
+	return sap
 }
 
 func (sap *singlePageApp) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	path, err := filepath.Abs(filepath.Join(sap.staticPath, r.URL.Path))
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if sap.env != nil && r.URL.Path == sap.envPath {
+		sap.serveRuntimeConfig(w, r)
 		return
 	}
-	_, err = os.Stat(path)
-	if os.IsNotExist(err) {
-		http.ServeFile(w, r, filepath.Join(sap.staticPath, sap.indexPath))
+	name := fsName(r.URL.Path)
+	if isHidden(name) {
+		http.NotFound(w, r)
 		return
-	} else if err != nil {
+	}
+	info, err := fs.Stat(sap.fsys, name)
+	if err == nil && info.IsDir() {
+		name = path.Join(name, "index.html")
+		info, err = fs.Stat(sap.fsys, name)
+		if err == nil && info.IsDir() {
+			err = fs.ErrNotExist
+		}
+	}
+	switch {
+	case errors.Is(err, fs.ErrNotExist) && sap.fallback != nil && sap.fallback(r):
+		sap.serveFile(w, r, sap.index)
+	case errors.Is(err, fs.ErrNotExist), errors.Is(err, fs.ErrPermission):
+		http.NotFound(w, r)
+	case errors.Is(err, fs.ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case err != nil:
 		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		sap.serveFile(w, r, name)
+	}
+}
+
+func (sap *singlePageApp) serveFile(w http.ResponseWriter, r *http.Request, name string) {
+	if name == sap.index && sap.envInject != nil {
+		sap.serveInjectedIndex(w, r)
+		return
+	}
+	switch {
+	case name == sap.index:
+		w.Header().Set("Cache-Control", NoCacheControl)
+	case sap.immutable != nil && sap.immutable(name):
+		w.Header().Set("Cache-Control", ImmutableCacheControl)
+	case sap.assets != "":
+		w.Header().Set("Cache-Control", sap.assets)
+	}
+
+	served, encoding := name, ""
+	if variant, enc, found := sap.precompressed(name, r.Header.Get("Accept-Encoding")); found {
+		served, encoding = variant, enc
+	}
+	if len(sap.encodings) > 0 {
+		w.Header().Add("Vary", "Accept-Encoding")
+	}
+
+	file, err := sap.fsys.Open(served)
+	if err != nil {
+		serveOpenError(w, err)
+		return
+	}
+	defer func() { _ = file.Close() }()
+
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		w.Header().Del("Cache-Control")
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
 		return
 	}
-	http.FileServer(http.Dir(sap.staticPath)).ServeHTTP(w, r)
+
+	content, ok := file.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(file)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		content = strings.NewReader(string(data))
+	}
+
+	if etag, err := sap.etags.get(served, info); err == nil {
+		w.Header().Set("ETag", etag)
+	}
+	if encoding != "" {
+		w.Header().Set("Content-Encoding", encoding)
+	}
+	// the original name keeps the Content-Type of the uncompressed file
+	http.ServeContent(w, r, name, info.ModTime(), content)
+}
+
+func (sap *singlePageApp) serveRuntimeConfig(w http.ResponseWriter, r *http.Request) {
+	script := sap.env.current()
+	w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+	w.Header().Set("Cache-Control", NoCacheControl)
+	w.Header().Set("ETag", script.etag)
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(script.content))
+}
+
+// serveInjectedIndex serves the index with the runtime configuration, without precompressed siblings
+// because its content changes with the configuration
+func (sap *singlePageApp) serveInjectedIndex(w http.ResponseWriter, r *http.Request) {
+	content, err := fs.ReadFile(sap.fsys, sap.index)
+	if err != nil {
+		serveOpenError(w, err)
+		return
+	}
+	content = injectScript(content, sap.envInject.current().content)
+	hash := sha256.Sum256(content)
+	w.Header().Set("Cache-Control", NoCacheControl)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(hash[:16])+`"`)
+	// no modification time, the configuration changes without touching the file
+	http.ServeContent(w, r, sap.index, time.Time{}, bytes.NewReader(content))
+}
+
+// injectScript inserts script in an inline script element before </head>, or at the beginning without a head
+func injectScript(html, script []byte) []byte {
+	element := append(append([]byte("<script>"), script...), "</script>"...)
+	at := 0
+	for i := 0; i+len("</head>") <= len(html); i++ {
+		if bytes.EqualFold(html[i:i+len("</head>")], []byte("</head>")) {
+			at = i
+			break
+		}
+	}
+	injected := make([]byte, 0, len(html)+len(element))
+	injected = append(injected, html[:at]...)
+	injected = append(injected, element...)
+	return append(injected, html[at:]...)
+}
+
+// serveOpenError answers the error of opening a file, hiding the ones denied as not found
+func serveOpenError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrPermission) {
+		status = http.StatusNotFound
+	}
+	w.Header().Del("Cache-Control")
+	http.Error(w, http.StatusText(status), status)
+}
+
+// IsNavigationRequest reports whether a request is for a client side route:
+// its path has no extension, like /users/42, or it accepts text/html, like a browser navigating to /users/john.doe.
+// Missing asset-like paths requested by scripts, like a stale /assets/app.3f2a1b9c.js, are not.
+func IsNavigationRequest(r *http.Request) bool {
+	if path.Ext(r.URL.Path) == "" {
+		return true
+	}
+	for _, accept := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(accept, ",") {
+			mediaType, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+			if strings.EqualFold(strings.TrimSpace(mediaType), "text/html") && !isZeroQuality(params) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isHidden reports whether a name has a segment starting with a dot, other than .well-known
+func isHidden(name string) bool {
+	for _, segment := range strings.Split(name, "/") {
+		if strings.HasPrefix(segment, ".") && segment != "." && segment != ".well-known" {
+			return true
+		}
+	}
+	return false
+}
+
+// precompressed returns the sibling of name in the preferred encoding accepted by the client
+func (sap *singlePageApp) precompressed(name, acceptEncoding string) (string, string, bool) {
+	if acceptEncoding == "" {
+		return "", "", false
+	}
+	accepted := parseAcceptEncoding(acceptEncoding)
+	for _, encoding := range sap.encodings {
+		ext, known := encodingExtensions[encoding]
+		if !known || !accepted(encoding) {
+			continue
+		}
+		if info, err := fs.Stat(sap.fsys, name+ext); err == nil && !info.IsDir() {
+			return name + ext, encoding, true
+		}
+	}
+	return "", "", false
+}
+
+// parseAcceptEncoding returns whether an encoding is accepted by an Accept-Encoding header
+func parseAcceptEncoding(header string) func(encoding string) bool {
+	qualities := make(map[string]bool)
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" {
+			continue
+		}
+		qualities[coding] = !isZeroQuality(params)
+	}
+	return func(encoding string) bool {
+		if accepted, listed := qualities[encoding]; listed {
+			return accepted
+		}
+		return qualities["*"]
+	}
+}
+
+func isZeroQuality(params string) bool {
+	for _, param := range strings.Split(params, ";") {
+		key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+		if strings.EqualFold(strings.TrimSpace(key), "q") {
+			value = strings.TrimRight(strings.TrimSpace(value), "0")
+			return value == "" || value == "." || value == "0."
+		}
+	}
+	return false
+}
+
+// IsFingerprinted reports whether a file name carries a content hash, like app.3f2a1b9c.js or index-B2x9kQ7a.js:
+// a dot or dash separated segment of at least 8 letters, digits or underscores with at least one digit.
+func IsFingerprinted(name string) bool {
+	base := path.Base(name)
+	dot := strings.LastIndex(base, ".")
+	if dot <= 0 {
+		return false
+	}
+	segments := strings.FieldsFunc(base[:dot], func(r rune) bool { return r == '.' || r == '-' })
+	for _, segment := range segments[min(1, len(segments)):] {
+		if isHash(segment) {
+			return true
+		}
+	}
+	return false
+}
+
+func isHash(segment string) bool {
+	if len(segment) < 8 {
+		return false
+	}
+	digit := false
+	for _, r := range segment {
+		switch {
+		case r >= '0' && r <= '9':
+			digit = true
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_':
+		default:
+			return false
+		}
+	}
+	return digit
+}
+
+// fsName returns the fs.FS name of a URL path, which can not leave the root
+func fsName(urlPath string) string {
+	name := strings.TrimPrefix(path.Clean("/"+urlPath), "/")
+	if name == "" {
+		return "."
+	}
+	return name
 }
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"sync"
+	"time"
+)
+
+type (
+	etagCache struct {
+		fsys  fs.FS
+		mutex sync.RWMutex
+		tags  map[string]fileTag
+	}
+
+	fileTag struct {
+		size    int64
+		modTime time.Time
+		etag    string
+	}
+)
+
+func newEtagCache(fsys fs.FS) *etagCache {
+	return &etagCache{fsys: fsys, tags: make(map[string]fileTag)}
+}
+
+// get returns the strong ETag of a file, computing it again when its size or modification time change
+func (c *etagCache) get(name string, info fs.FileInfo) (string, error) {
+	c.mutex.RLock()
+	tag, found := c.tags[name]
+	c.mutex.RUnlock()
+	if found && tag.size == info.Size() && tag.modTime.Equal(info.ModTime()) {
+		return tag.etag, nil
+	}
+
+	etag, err := c.compute(name)
+	if err != nil {
+		return "", err
+	}
+	c.mutex.Lock()
+	c.tags[name] = fileTag{size: info.Size(), modTime: info.ModTime(), etag: etag}
+	c.mutex.Unlock()
+	return etag, nil
+}
+
+func (c *etagCache) compute(name string) (string, error) {
+	file, err := c.fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = file.Close() }()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`, nil
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// rootFS is a directory served through os.Root, so symlinks can not escape it.
+// The root is opened on every call, so the directory can be replaced while the handler runs.
+type rootFS string
+
+func (dir rootFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	root, err := os.OpenRoot(string(dir))
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	defer func() { _ = root.Close() }()
+
+	file, err := root.FS().Open(name)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) && dir.escapes(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+	return file, err
+}
+
+// escapes reports whether name resolves outside the directory, which os.Root refuses with an unexported error
+func (dir rootFS) escapes(name string) bool {
+	base, err := filepath.EvalSymlinks(string(dir))
+	if err != nil {
+		return false
+	}
+	target, err := filepath.EvalSymlinks(filepath.Join(base, filepath.FromSlash(name)))
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(base, target)
+	return err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"testing/fstest"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -52,6 +53,7 @@ func TestSinglePageApp_ServeHTTP_WhenFileDoesNotExist_ThenServesIndex(t *testing
 
 	handler := NewSinglePageApp(tempDir, "index.html")
 	req := httptest.NewRequest("GET", "/nonexistent.html", nil)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,*/*;q=0.8")
 	w := httptest.NewRecorder()
 
 	// Act
@@ -126,3 +128,445 @@ func TestSinglePageApp_ServeHTTP_WhenRootPath_ThenServesIndex(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Equal(t, "root content", w.Body.String())
 }
+
+func TestNewSinglePageAppFS_WhenFileExists_ThenServesItFromFS(t *testing.T) {
+	// Arrange
+	fsys := fstest.MapFS{
+		"index.html":     {Data: []byte("<html>index</html>")},
+		"assets/app.js":  {Data: []byte("console.log('app')")},
+		"assets/app.css": {Data: []byte("body{}")},
+	}
+	handler := NewSinglePageAppFS(fsys, "/index.html")
+	req := httptest.NewRequest("GET", "/assets/app.js", nil)
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "console.log('app')", w.Body.String())
+}
+
+func TestNewSinglePageAppFS_WhenPathDoesNotExist_ThenServesIndex(t *testing.T) {
+	// Arrange
+	fsys := fstest.MapFS{"index.html": {Data: []byte("<html>index</html>")}}
+	handler := NewSinglePageAppFS(fsys, "index.html")
+
+	for _, target := range []string{"/", "/app/route"} {
+		req := httptest.NewRequest("GET", target, nil)
+		w := httptest.NewRecorder()
+
+		// Act
+		handler.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, w.Code, target)
+		assert.Equal(t, "<html>index</html>", w.Body.String(), target)
+	}
+}
+
+func TestSinglePageApp_ServeHTTP_WhenPrecompressedSiblingAccepted_ThenServesIt(t *testing.T) {
+	// Arrange
+	fsys := fstest.MapFS{
+		"index.html":     {Data: []byte("<html>index</html>")},
+		"app.js":         {Data: []byte("plain")},
+		"app.js.br":      {Data: []byte("brotli")},
+		"app.js.gz":      {Data: []byte("gzip")},
+		"vendor.js":      {Data: []byte("vendor")},
+		"vendor.js.gz":   {Data: []byte("vendor gzip")},
+		"assets/app.css": {Data: []byte("body{}")},
+	}
+	handler := NewSinglePageAppFS(fsys, "index.html")
+	cases := []struct {
+		target, acceptEncoding, encoding, body string
+	}{
+		{"/app.js", "gzip, deflate, br", "br", "brotli"},
+		{"/app.js", "gzip", "gzip", "gzip"},
+		{"/app.js", "br;q=0, gzip;q=0.5", "gzip", "gzip"},
+		{"/app.js", "*", "br", "brotli"},
+		{"/app.js", "", "", "plain"},
+		{"/app.js", "identity", "", "plain"},
+		{"/vendor.js", "br, gzip", "gzip", "vendor gzip"},
+		{"/assets/app.css", "br, gzip", "", "body{}"},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", c.target, nil)
+		if c.acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", c.acceptEncoding)
+		}
+		w := httptest.NewRecorder()
+
+		// Act
+		handler.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, w.Code, c.acceptEncoding)
+		assert.Equal(t, c.encoding, w.Header().Get("Content-Encoding"), c.acceptEncoding)
+		assert.Equal(t, c.body, w.Body.String(), c.acceptEncoding)
+		assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"), c.acceptEncoding)
+		assert.Contains(t, w.Header().Get("Content-Type"), map[string]string{"/app.js": "javascript", "/vendor.js": "javascript", "/assets/app.css": "text/css"}[c.target])
+	}
+}
+
+func TestSinglePageApp_ServeHTTP_WhenPrecompressedDisabled_ThenServesPlainFile(t *testing.T) {
+	// Arrange
+	fsys := fstest.MapFS{
+		"app.js":    {Data: []byte("plain")},
+		"app.js.br": {Data: []byte("brotli")},
+	}
+	handler := NewSinglePageAppFS(fsys, "index.html", WithPrecompressedEncodings())
+	req := httptest.NewRequest("GET", "/app.js", nil)
+	req.Header.Set("Accept-Encoding", "br")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(w, req)
+
+	// Assert
+	assert.Equal(t, "plain", w.Body.String())
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Empty(t, w.Header().Get("Vary"))
+}
+
+func TestSinglePageApp_ServeHTTP_ThenSetsCacheControlByFile(t *testing.T) {
+	// Arrange
+	fsys := fstest.MapFS{
+		"index.html":                {Data: []byte("<html>index</html>")},
+		"assets/index-B2x9kQ7a.js":  {Data: []byte("hashed")},
+		"assets/main.3f2a1b9c.css":  {Data: []byte("hashed")},
+		"favicon.ico":               {Data: []byte("icon")},
+		"assets/jquery-validate.js": {Data: []byte("not hashed")},
+	}
+	handler := NewSinglePageAppFS(fsys, "index.html")
+	cases := map[string]string{
+		"/":                          NoCacheControl,
+		"/index.html":                NoCacheControl,
+		"/app/route":                 NoCacheControl,
+		"/assets/index-B2x9kQ7a.js":  ImmutableCacheControl,
+		"/assets/main.3f2a1b9c.css":  ImmutableCacheControl,
+		"/favicon.ico":               "",
+		"/assets/jquery-validate.js": "",
+	}
+
+	for target, cacheControl := range cases {
+		req := httptest.NewRequest("GET", target, nil)
+		w := httptest.NewRecorder()
+
+		// Act
+		handler.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, w.Code, target)
+		assert.Equal(t, cacheControl, w.Header().Get("Cache-Control"), target)
+	}
+}
+
+func TestSinglePageApp_ServeHTTP_WhenETagMatches_ThenReturnsNotModified(t *testing.T) {
+	// Arrange
+	fsys := fstest.MapFS{"index.html": {Data: []byte("<html>index</html>")}}
+	handler := NewSinglePageAppFS(fsys, "index.html")
+	first := httptest.NewRecorder()
+	handler.ServeHTTP(first, httptest.NewRequest("GET", "/", nil))
+	etag := first.Header().Get("ETag")
+	req := httptest.NewRequest("GET", "/app/route", nil)
+	req.Header.Set("If-None-Match", etag)
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(w, req)
+
+	// Assert
+	require.Regexp(t, `^"[0-9a-f]{32}"$`, etag)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+}
+
+func TestSinglePageApp_ServeHTTP_WhenFileIsServed_ThenComputesOnlyItsETag(t *testing.T) {
+	// Arrange
+	fsys := fstest.MapFS{
+		"index.html": {Data: []byte("<html>index</html>")},
+		"app.js":     {Data: []byte("app")},
+	}
+	handler := NewSinglePageAppFS(fsys, "index.html")
+	etags := handler.(*singlePageApp).etags
+	created := len(etags.tags)
+
+	// Act
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/app.js", nil))
+
+	// Assert
+	assert.Zero(t, created)
+	assert.Len(t, etags.tags, 1)
+	assert.Contains(t, etags.tags, "app.js")
+}
+
+func TestSinglePageApp_ServeHTTP_WhenFileChanges_ThenETagChanges(t *testing.T) {
+	// Arrange
+	tempDir := t.TempDir()
+	file := filepath.Join(tempDir, "app.js")
+	require.NoError(t, os.WriteFile(file, []byte("v1"), 0644))
+	handler := NewSinglePageApp(tempDir, "index.html")
+	serve := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/app.js", nil))
+		return w
+	}
+	before := serve().Header().Get("ETag")
+	require.NoError(t, os.WriteFile(file, []byte("version 2"), 0644))
+
+	// Act
+	w := serve()
+
+	// Assert
+	assert.Equal(t, "version 2", w.Body.String())
+	assert.NotEmpty(t, before)
+	assert.NotEqual(t, before, w.Header().Get("ETag"))
+}
+
+func TestIsFingerprinted(t *testing.T) {
+	cases := map[string]bool{
+		"assets/index-B2x9kQ7a.js": true,
+		"main.3f2a1b9c.css":        true,
+		"chunk.a1b2c3d4e5.js":      true,
+		"app.js":                   false,
+		"jquery-validate.js":       false,
+		"app-v2.js":                false,
+		"3f2a1b9c.js":              false,
+		"LICENSE":                  false,
+	}
+	for name, expected := range cases {
+		assert.Equal(t, expected, IsFingerprinted(name), name)
+	}
+}
+
+func TestSinglePageApp_ServeHTTP_WhenMissingPath_ThenFallsBackOnlyForNavigation(t *testing.T) {
+	// Arrange
+	fsys := fstest.MapFS{"index.html": {Data: []byte("<html>index</html>")}}
+	handler := NewSinglePageAppFS(fsys, "index.html")
+	cases := []struct {
+		target, accept string
+		status         int
+	}{
+		{"/users/42", "", http.StatusOK},
+		{"/users/john.doe", "text/html,application/xhtml+xml,*/*;q=0.8", http.StatusOK},
+		{"/assets/app.3f2a1b9c.js", "*/*", http.StatusNotFound},
+		{"/assets/app.3f2a1b9c.js", "", http.StatusNotFound},
+		{"/logo.png", "image/avif,image/webp,*/*", http.StatusNotFound},
+		{"/report.pdf", "text/html;q=0, */*", http.StatusNotFound},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", c.target, nil)
+		if c.accept != "" {
+			req.Header.Set("Accept", c.accept)
+		}
+		w := httptest.NewRecorder()
+
+		// Act
+		handler.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, c.status, w.Code, c.target)
+		if c.status == http.StatusOK {
+			assert.Equal(t, "<html>index</html>", w.Body.String(), c.target)
+		} else {
+			assert.Empty(t, w.Header().Get("Cache-Control"), c.target)
+		}
+	}
+}
+
+func TestSinglePageApp_ServeHTTP_WhenFallbackConfigured_ThenUsesIt(t *testing.T) {
+	// Arrange
+	fsys := fstest.MapFS{"index.html": {Data: []byte("<html>index</html>")}}
+	always := NewSinglePageAppFS(fsys, "index.html", WithFallback(func(*http.Request) bool { return true }))
+	never := NewSinglePageAppFS(fsys, "index.html", WithFallback(nil))
+	alwaysRecorder, neverRecorder := httptest.NewRecorder(), httptest.NewRecorder()
+
+	// Act
+	always.ServeHTTP(alwaysRecorder, httptest.NewRequest("GET", "/missing.js", nil))
+	never.ServeHTTP(neverRecorder, httptest.NewRequest("GET", "/users/42", nil))
+
+	// Assert
+	assert.Equal(t, http.StatusOK, alwaysRecorder.Code)
+	assert.Equal(t, "<html>index</html>", alwaysRecorder.Body.String())
+	assert.Equal(t, http.StatusNotFound, neverRecorder.Code)
+}
+
+func TestSinglePageApp_ServeHTTP_WhenHiddenFile_ThenReturnsNotFound(t *testing.T) {
+	// Arrange
+	fsys := fstest.MapFS{
+		"index.html":                         {Data: []byte("<html>index</html>")},
+		".env":                               {Data: []byte("SECRET=1")},
+		".git/config":                        {Data: []byte("[core]")},
+		"assets/.DS_Store":                   {Data: []byte("finder")},
+		".well-known/security.txt":           {Data: []byte("Contact: security@example.com")},
+		".well-known/acme-challenge/.hidden": {Data: []byte("hidden")},
+	}
+	handler := NewSinglePageAppFS(fsys, "index.html")
+	cases := map[string]int{
+		"/.env":                               http.StatusNotFound,
+		"/.env.local":                         http.StatusNotFound,
+		"/.git/config":                        http.StatusNotFound,
+		"/.git/":                              http.StatusNotFound,
+		"/assets/.DS_Store":                   http.StatusNotFound,
+		"/.well-known/acme-challenge/.hidden": http.StatusNotFound,
+		"/.well-known/security.txt":           http.StatusOK,
+	}
+
+	for target, status := range cases {
+		req := httptest.NewRequest("GET", target, nil)
+		req.Header.Set("Accept", "text/html")
+		w := httptest.NewRecorder()
+
+		// Act
+		handler.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, status, w.Code, target)
+		assert.NotContains(t, w.Body.String(), "SECRET", target)
+	}
+}
+
+func TestSinglePageApp_ServeHTTP_WhenTraversalAttempt_ThenDoesNotLeaveStaticPath(t *testing.T) {
+	// Arrange
+	parent := t.TempDir()
+	staticPath := filepath.Join(parent, "static")
+	require.NoError(t, os.Mkdir(staticPath, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(parent, "secret.txt"), []byte("secret"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(staticPath, "index.html"), []byte("<html>index</html>"), 0644))
+	handler := NewSinglePageApp(staticPath, "index.html")
+	targets := []string{
+		"/../secret.txt",
+		"/../../secret.txt",
+		"/static/../../secret.txt",
+		"/%2e%2e/secret.txt",
+		"/%2E%2E%2Fsecret.txt",
+		"/..%2fsecret.txt",
+		"/..%5csecret.txt",
+		"/./../secret.txt",
+		"//../secret.txt",
+	}
+
+	for _, target := range targets {
+		req := httptest.NewRequest("GET", target, nil)
+		req.Header.Set("Accept", "text/html")
+		w := httptest.NewRecorder()
+
+		// Act
+		handler.ServeHTTP(w, req)
+
+		// Assert
+		assert.NotContains(t, w.Body.String(), "secret", target)
+		assert.True(t, w.Code == http.StatusOK || w.Code == http.StatusNotFound || w.Code == http.StatusBadRequest, target)
+	}
+}
+
+func TestIsNavigationRequest(t *testing.T) {
+	cases := []struct {
+		target, accept string
+		expected       bool
+	}{
+		{"/", "", true},
+		{"/users/42", "application/json", true},
+		{"/users/john.doe", "text/html", true},
+		{"/users/john.doe", "TEXT/HTML; q=0.9", true},
+		{"/users/john.doe", "text/html;q=0", false},
+		{"/app.js", "*/*", false},
+		{"/app.js", "", false},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", c.target, nil)
+		if c.accept != "" {
+			req.Header.Set("Accept", c.accept)
+		}
+		assert.Equal(t, c.expected, IsNavigationRequest(req), c.target+" "+c.accept)
+	}
+}
+
+func TestSinglePageApp_ServeHTTP_WhenRuntimeConfigScript_ThenServesCurrentSettings(t *testing.T) {
+	// Arrange
+	fsys := fstest.MapFS{"index.html": {Data: []byte("<html>index</html>")}}
+	env := NewRuntimeConfig("")
+	require.NoError(t, env.Set(map[string]string{"apiBaseUrl": "https://staging.example.com"}))
+	handler := NewSinglePageAppFS(fsys, "index.html", WithRuntimeConfigScript(env, ""))
+	first := httptest.NewRecorder()
+	handler.ServeHTTP(first, httptest.NewRequest("GET", DefaultRuntimeConfigPath, nil))
+	require.NoError(t, env.Set(map[string]string{"apiBaseUrl": "https://api.example.com"}))
+	req := httptest.NewRequest("GET", DefaultRuntimeConfigPath, nil)
+	req.Header.Set("If-None-Match", first.Header().Get("ETag"))
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(w, req)
+
+	// Assert
+	assert.Equal(t, `window["__ENV__"]={"apiBaseUrl":"https://staging.example.com"};`, first.Body.String())
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `window["__ENV__"]={"apiBaseUrl":"https://api.example.com"};`, w.Body.String())
+	assert.Equal(t, "text/javascript; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, NoCacheControl, w.Header().Get("Cache-Control"))
+	assert.NotEqual(t, first.Header().Get("ETag"), w.Header().Get("ETag"))
+}
+
+func TestSinglePageApp_ServeHTTP_WhenRuntimeConfigInjection_ThenInjectsItInIndex(t *testing.T) {
+	// Arrange
+	fsys := fstest.MapFS{
+		"index.html":    {Data: []byte("<html><HEAD><title>app</title></HEAD><body></body></html>")},
+		"index.html.br": {Data: []byte("brotli")},
+	}
+	env := NewRuntimeConfig("")
+	require.NoError(t, env.Set(map[string]bool{"beta": false}))
+	handler := NewSinglePageAppFS(fsys, "index.html", WithRuntimeConfigInjection(env))
+	first := httptest.NewRecorder()
+	handler.ServeHTTP(first, httptest.NewRequest("GET", "/", nil))
+	require.NoError(t, env.Set(map[string]bool{"beta": true}))
+	req := httptest.NewRequest("GET", "/users/42", nil)
+	req.Header.Set("Accept-Encoding", "br")
+	req.Header.Set("If-None-Match", first.Header().Get("ETag"))
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(w, req)
+
+	// Assert
+	assert.Equal(t, `<html><HEAD><title>app</title><script>window["__ENV__"]={"beta":false};</script></HEAD><body></body></html>`, first.Body.String())
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `<html><HEAD><title>app</title><script>window["__ENV__"]={"beta":true};</script></HEAD><body></body></html>`, w.Body.String())
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Equal(t, NoCacheControl, w.Header().Get("Cache-Control"))
+}
+
+func TestInjectScript_WhenNoHead_ThenPrependsIt(t *testing.T) {
+	// Act
+	html := injectScript([]byte("<div id=app></div>"), []byte("window.x=1;"))
+
+	// Assert
+	assert.Equal(t, "<script>window.x=1;</script><div id=app></div>", string(html))
+}
+
+func TestSinglePageApp_ServeHTTP_WhenAssetCacheControlSet_ThenSendsItForOtherAssets(t *testing.T) {
+	// Arrange
+	fsys := fstest.MapFS{
+		"index.html":      {Data: []byte("<html>index</html>")},
+		"favicon.ico":     {Data: []byte("icon")},
+		"app.3f2a1b9c.js": {Data: []byte("hashed")},
+	}
+	handler := NewSinglePageAppFS(fsys, "index.html", WithAssetCacheControl("public, max-age=3600"))
+	cases := map[string]string{
+		"/":                NoCacheControl,
+		"/favicon.ico":     "public, max-age=3600",
+		"/app.3f2a1b9c.js": ImmutableCacheControl,
+	}
+
+	for target, cacheControl := range cases {
+		w := httptest.NewRecorder()
+
+		// Act
+		handler.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+
+		// Assert
+		assert.Equal(t, cacheControl, w.Header().Get("Cache-Control"), target)
+	}
+}
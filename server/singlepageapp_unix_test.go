@@ -0,0 +1,49 @@
+//go:build !windows
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSinglePageApp_ServeHTTP_WhenSymlinkEscapesStaticPath_ThenReturnsNotFound(t *testing.T) {
+	// Arrange
+	parent := t.TempDir()
+	staticPath := filepath.Join(parent, "static")
+	outside := filepath.Join(parent, "outside")
+	require.NoError(t, os.MkdirAll(filepath.Join(staticPath, "assets"), 0755))
+	require.NoError(t, os.Mkdir(outside, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(staticPath, "index.html"), []byte("<html>index</html>"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(staticPath, "assets", "app.js"), []byte("app"), 0644))
+	require.NoError(t, os.Symlink(filepath.Join(outside, "secret.txt"), filepath.Join(staticPath, "secret.txt")))
+	require.NoError(t, os.Symlink(outside, filepath.Join(staticPath, "linked")))
+	require.NoError(t, os.Symlink(filepath.Join("assets", "app.js"), filepath.Join(staticPath, "app.js")))
+	handler := NewSinglePageApp(staticPath, "index.html")
+	cases := map[string]int{
+		"/secret.txt":        http.StatusNotFound,
+		"/linked/secret.txt": http.StatusNotFound,
+		"/linked/":           http.StatusNotFound,
+		"/app.js":            http.StatusOK,
+	}
+
+	for target, status := range cases {
+		req := httptest.NewRequest("GET", target, nil)
+		req.Header.Set("Accept", "text/html")
+		w := httptest.NewRecorder()
+
+		// Act
+		handler.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, status, w.Code, target)
+		assert.NotContains(t, w.Body.String(), "secret", target)
+	}
+}
@@ -0,0 +1,401 @@
+// Package sse pushes events to browsers with server-sent events, the protocol of EventSource.
+//
+// A Hub streams every published event as JSON to the connected clients and keeps the last ones
+// in a bounded buffer, so a client that reconnects with the Last-Event-ID header receives the
+// events it missed. Events come from Hub.Publish or from eventsmanager subscriptions through Subscribe.
+//
+// Basic usage:
+//
+//	hub := sse.NewHub(logger, sse.Options{})
+//	defer hub.Close()
+//	if err := sse.Subscribe(hub, userCreatedSubscriptions, "user.created"); err != nil {
+//		return err
+//	}
+//	mux.Handle("/events", hub)
+//
+// Clients choose the event types they receive with the types query parameter, like
+// /events?types=user.created,user.deleted, and receive all of them without it.
+package sse
+
+import (
+	"encoding/json"
+	stdErrors "errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/janmbaco/go-infrastructure/v2/eventsmanager"
+	"github.com/janmbaco/go-infrastructure/v2/logs"
+)
+
+const (
+	// DefaultBufferSize is the number of last events kept to replay them
+	DefaultBufferSize = 256
+	// DefaultClientBuffer is the number of events waiting to be written to a client
+	DefaultClientBuffer = 64
+	// DefaultHeartbeat is the interval of the comments sent to keep idle connections open
+	DefaultHeartbeat = 15 * time.Second
+	// TypesParam is the query parameter with the comma separated event types a client receives
+	TypesParam = "types"
+)
+
+type (
+	// Options configures the buffers, heartbeats and filters of a Hub
+	Options struct {
+		// BufferSize is the number of last events kept to replay them, DefaultBufferSize when zero and none when negative
+		BufferSize int
+		// ClientBuffer is the number of events waiting to be written to a client before it is disconnected
+		// as too slow, DefaultClientBuffer when zero
+		ClientBuffer int
+		// Heartbeat is the interval of the comments that keep idle connections open through proxies,
+		// DefaultHeartbeat when zero and none when negative
+		Heartbeat time.Duration
+		// Retry is the reconnection delay sent to the clients; the browser chooses it when zero
+		Retry time.Duration
+		// Filter decides whether the client of r receives event, after the types query parameter;
+		// every event when nil. It runs for every event and client, so keep it cheap
+		Filter func(r *http.Request, event Event) bool
+	}
+
+	// Event is an event published in a Hub
+	Event struct {
+		// ID is the sequence number of the event in the Hub, sent as the event id
+		ID uint64
+		// Type is the event type, sent as the event name
+		Type string
+		// Data is the JSON of the event, sent as the event data
+		Data json.RawMessage
+	}
+
+	// Hub is the http.Handler that streams the published events to its clients
+	Hub struct {
+		logger       logs.Logger
+		options      Options
+		mutex        sync.Mutex
+		lastID       uint64
+		buffer       []Event
+		clients      map[*client]struct{}
+		unsubscribes []func() error
+		closed       bool
+	}
+
+	client struct {
+		// events is closed when the hub disconnects the client
+		events chan Event
+	}
+
+	// fanout is the function subscribed to an eventsmanager.Subscriptions, which publishes its events in targets.
+	// eventsmanager tells the subscribed functions apart by their code, which is the same for every call of
+	// Subscribe, so a Subscriptions gets only one of them
+	fanout struct {
+		targets map[fanoutTarget]struct{}
+		remove  func() error
+	}
+
+	fanoutTarget struct {
+		hub       *Hub
+		eventType string
+	}
+)
+
+// fanouts are the fanouts of the subscriptions that publish in a hub
+var fanouts = struct {
+	mutex           sync.Mutex
+	bySubscriptions map[interface{}]*fanout
+}{bySubscriptions: make(map[interface{}]*fanout)}
+
+// NewHub returns a Hub without events nor clients
+func NewHub(logger logs.Logger, options Options) *Hub {
+	if options.BufferSize == 0 {
+		options.BufferSize = DefaultBufferSize
+	}
+	if options.ClientBuffer <= 0 {
+		options.ClientBuffer = DefaultClientBuffer
+	}
+	if options.Heartbeat == 0 {
+		options.Heartbeat = DefaultHeartbeat
+	}
+	return &Hub{logger: logger, options: options, clients: make(map[*client]struct{})}
+}
+
+// Subscribe publishes in hub, as eventType, the JSON of the event args of subscriptions until the hub is closed.
+// The same subscriptions can publish in several hubs and as several event types.
+func Subscribe[T eventsmanager.EventObject[T]](hub *Hub, subscriptions eventsmanager.Subscriptions[T], eventType string) error {
+	if err := validateEventType(eventType); err != nil {
+		return err
+	}
+
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+	if hub.closed {
+		return newHubError(ClosedHub, "the hub is closed", nil)
+	}
+	target := fanoutTarget{hub: hub, eventType: eventType}
+	fanouts.mutex.Lock()
+	defer fanouts.mutex.Unlock()
+	f, subscribed := fanouts.bySubscriptions[subscriptions]
+	if subscribed {
+		if _, ok := f.targets[target]; ok {
+			return newHubError(DuplicatedSubscription, "the subscriptions of `"+eventType+"` already publish in the hub", nil)
+		}
+	} else {
+		publish := func(event T) {
+			args := event.GetEventArgs()
+			for _, target := range fanoutTargets(subscriptions) {
+				if err := target.hub.Publish(target.eventType, args); err != nil {
+					target.hub.logger.Errorf("sse: the `%v` event was not published: %v", target.eventType, err)
+				}
+			}
+		}
+		if err := subscriptions.Add(publish); err != nil {
+			return newHubError(UnexpectedError, "the hub could not subscribe to `"+eventType+"`", err)
+		}
+		f = &fanout{targets: make(map[fanoutTarget]struct{}), remove: func() error { return subscriptions.Remove(publish) }}
+		fanouts.bySubscriptions[subscriptions] = f
+	}
+	f.targets[target] = struct{}{}
+	hub.unsubscribes = append(hub.unsubscribes, func() error { return unsubscribe(subscriptions, target) })
+	return nil
+}
+
+// fanoutTargets returns the hubs and event types the events of subscriptions are published in
+func fanoutTargets(subscriptions interface{}) []fanoutTarget {
+	fanouts.mutex.Lock()
+	defer fanouts.mutex.Unlock()
+	f, ok := fanouts.bySubscriptions[subscriptions]
+	if !ok {
+		return nil
+	}
+	targets := make([]fanoutTarget, 0, len(f.targets))
+	for target := range f.targets {
+		targets = append(targets, target)
+	}
+	return targets
+}
+
+// unsubscribe stops publishing the events of subscriptions in target, and removes the subscription after the last one
+func unsubscribe(subscriptions interface{}, target fanoutTarget) error {
+	fanouts.mutex.Lock()
+	defer fanouts.mutex.Unlock()
+	f, ok := fanouts.bySubscriptions[subscriptions]
+	if !ok {
+		return nil
+	}
+	delete(f.targets, target)
+	if len(f.targets) > 0 {
+		return nil
+	}
+	delete(fanouts.bySubscriptions, subscriptions)
+	return f.remove()
+}
+
+// Publish sends the JSON of value as an event of eventType to the clients and keeps it to replay it.
+// Clients that can not keep up are disconnected, and they receive the events they missed when they reconnect.
+func (h *Hub) Publish(eventType string, value interface{}) error {
+	if err := validateEventType(eventType); err != nil {
+		return err
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return newHubError(InvalidEvent, "the `"+eventType+"` event can not be serialized to JSON", err)
+	}
+
+	h.mutex.Lock()
+	if h.closed {
+		h.mutex.Unlock()
+		return newHubError(ClosedHub, "the hub is closed", nil)
+	}
+	h.lastID++
+	event := Event{ID: h.lastID, Type: eventType, Data: data}
+	if h.options.BufferSize > 0 {
+		if len(h.buffer) == h.options.BufferSize {
+			h.buffer = h.buffer[1:]
+		}
+		h.buffer = append(h.buffer, event)
+	}
+	slow := 0
+	for c := range h.clients {
+		select {
+		case c.events <- event:
+		default:
+			delete(h.clients, c)
+			close(c.events)
+			slow++
+		}
+	}
+	h.mutex.Unlock()
+
+	if slow > 0 {
+		h.logger.Warningf("sse: %v clients were disconnected because they are too slow", slow)
+	}
+	return nil
+}
+
+// Clients returns the number of connected clients
+func (h *Hub) Clients() int {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return len(h.clients)
+}
+
+// Close unsubscribes the hub from eventsmanager and disconnects its clients; the hub can not be used afterwards
+func (h *Hub) Close() error {
+	h.mutex.Lock()
+	if h.closed {
+		h.mutex.Unlock()
+		return nil
+	}
+	h.closed = true
+	unsubscribes := h.unsubscribes
+	h.unsubscribes = nil
+	for c := range h.clients {
+		delete(h.clients, c)
+		close(c.events)
+	}
+	h.mutex.Unlock()
+
+	errs := make([]error, 0)
+	for _, unsubscribe := range unsubscribes {
+		if err := unsubscribe(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return newHubError(UnexpectedError, "the hub could not unsubscribe from every event", stdErrors.Join(errs...))
+	}
+	return nil
+}
+
+// ServeHTTP streams the events to the client of r until it disconnects, after replaying the buffered events
+// following its Last-Event-ID header
+func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	lastEventID, _ := strconv.ParseUint(r.Header.Get("Last-Event-ID"), 10, 64) //nolint:errcheck // no replay when it is not a number
+	c, replay, ok := h.connect(lastEventID)
+	if !ok {
+		http.Error(w, "the event stream is closed", http.StatusServiceUnavailable)
+		return
+	}
+	defer h.disconnect(c)
+
+	accepts := h.filter(r)
+	controller := http.NewResponseController(w)
+	// the events are streamed for as long as the client is connected, beyond the write timeout of the server
+	_ = controller.SetWriteDeadline(time.Time{}) //nolint:errcheck // not every http.ResponseWriter supports deadlines
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if h.options.Retry > 0 {
+		_, _ = fmt.Fprintf(w, "retry: %d\n\n", h.options.Retry.Milliseconds())
+	}
+	for _, event := range replay {
+		if accepts(event) {
+			writeEvent(w, event)
+		}
+	}
+	if err := controller.Flush(); err != nil {
+		h.logger.Errorf("sse: the response can not stream events: %v", err)
+		return
+	}
+
+	var heartbeat <-chan time.Time
+	if h.options.Heartbeat > 0 {
+		ticker := time.NewTicker(h.options.Heartbeat)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-c.events:
+			if !ok {
+				return
+			}
+			if !accepts(event) {
+				continue
+			}
+			writeEvent(w, event)
+		case <-heartbeat:
+			_, _ = w.Write([]byte(": heartbeat\n\n"))
+		}
+		if err := controller.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// connect adds a client and returns the buffered events after lastEventID. The whole buffer is returned when
+// lastEventID is ahead of the hub, because the client saw it before the process restarted.
+func (h *Hub) connect(lastEventID uint64) (*client, []Event, bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.closed {
+		return nil, nil, false
+	}
+	c := &client{events: make(chan Event, h.options.ClientBuffer)}
+	h.clients[c] = struct{}{}
+
+	var replay []Event
+	if lastEventID > 0 {
+		if lastEventID > h.lastID {
+			lastEventID = 0
+		}
+		for i, event := range h.buffer {
+			if event.ID > lastEventID {
+				replay = append(replay, h.buffer[i:]...)
+				break
+			}
+		}
+	}
+	return c, replay, true
+}
+
+func (h *Hub) disconnect(c *client) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if _, ok := h.clients[c]; ok {
+		delete(h.clients, c)
+		close(c.events)
+	}
+}
+
+// filter returns whether the client of r receives an event, by the types query parameter and Options.Filter
+func (h *Hub) filter(r *http.Request) func(Event) bool {
+	types := make(map[string]struct{})
+	for _, value := range r.URL.Query()[TypesParam] {
+		for _, eventType := range strings.Split(value, ",") {
+			if eventType = strings.TrimSpace(eventType); eventType != "" {
+				types[eventType] = struct{}{}
+			}
+		}
+	}
+	return func(event Event) bool {
+		if len(types) > 0 {
+			if _, ok := types[event.Type]; !ok {
+				return false
+			}
+		}
+		return h.options.Filter == nil || h.options.Filter(r, event)
+	}
+}
+
+// writeEvent writes event in the text/event-stream format; JSON has no line breaks, so data is a single line
+func writeEvent(w http.ResponseWriter, event Event) {
+	_, _ = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+}
+
+func validateEventType(eventType string) error {
+	if eventType == "" || strings.ContainsAny(eventType, "\r\n") {
+		return newHubError(InvalidEvent, "the event type `"+eventType+"` is not valid", nil)
+	}
+	return nil
+}
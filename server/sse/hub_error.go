@@ -0,0 +1,39 @@
+package sse
+
+import (
+	"github.com/janmbaco/go-infrastructure/v2/errors"
+)
+
+// HubError is the errors of Hub
+type HubError interface {
+	errors.CustomError
+	GetErrorType() HubErrorType
+}
+
+type hubError struct {
+	errors.CustomizableError
+	ErrorType HubErrorType
+}
+
+func newHubError(errorType HubErrorType, message string, internalError error) HubError {
+	return &hubError{
+		CustomizableError: errors.CustomizableError{
+			Message:       message,
+			InternalError: internalError,
+		},
+		ErrorType: errorType,
+	}
+}
+
+func (e *hubError) GetErrorType() HubErrorType {
+	return e.ErrorType
+}
+
+type HubErrorType uint8
+
+const (
+	UnexpectedError HubErrorType = iota
+	InvalidEvent
+	DuplicatedSubscription
+	ClosedHub
+)
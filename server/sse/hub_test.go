@@ -0,0 +1,273 @@
+package sse
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/janmbaco/go-infrastructure/v2/eventsmanager"
+	"github.com/janmbaco/go-infrastructure/v2/logs"
+)
+
+type (
+	userCreated struct {
+		ID    string `json:"id"`
+		Email string `json:"email"`
+	}
+
+	silentLogger struct {
+		logs.Logger
+	}
+
+	// stream reads the events of a connected client
+	stream struct {
+		reader *bufio.Reader
+		cancel context.CancelFunc
+	}
+)
+
+func (e userCreated) GetEventArgs() userCreated { return e }
+
+func (e userCreated) StopPropagation() bool { return false }
+
+func (e userCreated) IsParallelPropagation() bool { return false }
+
+func (silentLogger) Errorf(string, ...interface{}) {}
+
+func (silentLogger) Warningf(string, ...interface{}) {}
+
+func connect(t *testing.T, hub *Hub, target, lastEventID string) *stream {
+	server := httptest.NewServer(hub)
+	t.Cleanup(server.Close)
+	clients := hub.Clients()
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+target, nil)
+	require.NoError(t, err)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { cancel(); _ = resp.Body.Close() })
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	require.Eventually(t, func() bool { return hub.Clients() > clients }, time.Second, time.Millisecond)
+	return &stream{reader: bufio.NewReader(resp.Body), cancel: cancel}
+}
+
+// next returns the next message of the stream, without its trailing blank line
+func (s *stream) next(t *testing.T) string {
+	var lines []string
+	for {
+		line, err := s.reader.ReadString('\n')
+		require.NoError(t, err)
+		if line == "\n" {
+			return strings.Join(lines, "")
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestHub_WhenEventsArePublished_ThenStreamsThemAsJSON(t *testing.T) {
+	// Arrange
+	hub := NewHub(silentLogger{}, Options{Retry: 2 * time.Second})
+	defer hub.Close()
+	client := connect(t, hub, "/", "")
+
+	// Act
+	require.NoError(t, hub.Publish("user.created", userCreated{ID: "1", Email: "a@example.com"}))
+	require.NoError(t, hub.Publish("user.deleted", map[string]string{"id": "1"}))
+
+	// Assert
+	assert.Equal(t, "retry: 2000\n", client.next(t))
+	assert.Equal(t, "id: 1\nevent: user.created\ndata: {\"id\":\"1\",\"email\":\"a@example.com\"}\n", client.next(t))
+	assert.Equal(t, "id: 2\nevent: user.deleted\ndata: {\"id\":\"1\"}\n", client.next(t))
+}
+
+func TestHub_WhenClientFilters_ThenStreamsOnlyItsEvents(t *testing.T) {
+	// Arrange
+	hub := NewHub(silentLogger{}, Options{Filter: func(r *http.Request, event Event) bool {
+		return !strings.Contains(string(event.Data), "hidden")
+	}})
+	defer hub.Close()
+	client := connect(t, hub, "/?types=user.created,%20user.deleted", "")
+
+	// Act
+	require.NoError(t, hub.Publish("user.updated", userCreated{ID: "1"}))
+	require.NoError(t, hub.Publish("user.created", userCreated{ID: "hidden"}))
+	require.NoError(t, hub.Publish("user.deleted", userCreated{ID: "2"}))
+
+	// Assert
+	assert.Equal(t, "id: 3\nevent: user.deleted\ndata: {\"id\":\"2\",\"email\":\"\"}\n", client.next(t))
+}
+
+func TestHub_WhenClientReconnectsWithLastEventID_ThenReplaysTheBufferedEventsAfterIt(t *testing.T) {
+	// Arrange
+	hub := NewHub(silentLogger{}, Options{BufferSize: 3})
+	defer hub.Close()
+	for i := 0; i < 5; i++ {
+		require.NoError(t, hub.Publish("tick", i))
+	}
+
+	// Act
+	resumed := connect(t, hub, "/", "3")
+	lost := connect(t, hub, "/", "1")
+	restarted := connect(t, hub, "/", "99")
+	require.NoError(t, hub.Publish("tick", 5))
+
+	// Assert
+	assert.Equal(t, "id: 4\nevent: tick\ndata: 3\n", resumed.next(t))
+	assert.Equal(t, "id: 5\nevent: tick\ndata: 4\n", resumed.next(t))
+	assert.Equal(t, "id: 6\nevent: tick\ndata: 5\n", resumed.next(t))
+	assert.Equal(t, "id: 3\nevent: tick\ndata: 2\n", lost.next(t))
+	assert.Equal(t, "id: 3\nevent: tick\ndata: 2\n", restarted.next(t))
+}
+
+func TestHub_WhenIdle_ThenSendsHeartbeats(t *testing.T) {
+	// Arrange
+	hub := NewHub(silentLogger{}, Options{Heartbeat: 10 * time.Millisecond})
+	defer hub.Close()
+
+	// Act
+	client := connect(t, hub, "/", "")
+
+	// Assert
+	assert.Equal(t, ": heartbeat\n", client.next(t))
+}
+
+func TestHub_WhenClientDisconnects_ThenRemovesIt(t *testing.T) {
+	// Arrange
+	hub := NewHub(silentLogger{}, Options{})
+	defer hub.Close()
+	client := connect(t, hub, "/", "")
+
+	// Act
+	client.cancel()
+
+	// Assert
+	assert.Eventually(t, func() bool { return hub.Clients() == 0 }, time.Second, time.Millisecond)
+}
+
+func TestHub_WhenClientIsTooSlow_ThenDisconnectsIt(t *testing.T) {
+	// Arrange
+	hub := NewHub(silentLogger{}, Options{ClientBuffer: 1})
+	defer hub.Close()
+	c, _, ok := hub.connect(0)
+	require.True(t, ok)
+
+	// Act
+	require.NoError(t, hub.Publish("tick", 1))
+	require.NoError(t, hub.Publish("tick", 2))
+
+	// Assert
+	assert.Zero(t, hub.Clients())
+	<-c.events
+	_, open := <-c.events
+	assert.False(t, open)
+}
+
+func TestSubscribe_WhenEventsAreRaised_ThenStreamsThemUntilTheHubIsClosed(t *testing.T) {
+	// Arrange
+	subscriptions := eventsmanager.NewSubscriptions[userCreated]()
+	eventManager := eventsmanager.NewEventManager()
+	eventsmanager.Register(eventManager, eventsmanager.NewPublisher(subscriptions, silentLogger{}))
+	hub := NewHub(silentLogger{}, Options{})
+	require.NoError(t, Subscribe(hub, subscriptions, "user.created"))
+	client := connect(t, hub, "/", "")
+
+	// Act
+	eventsmanager.Publish(eventManager, userCreated{ID: "7", Email: "b@example.com"})
+	received := client.next(t)
+	require.NoError(t, hub.Close())
+
+	// Assert
+	assert.Equal(t, "id: 1\nevent: user.created\ndata: {\"id\":\"7\",\"email\":\"b@example.com\"}\n", received)
+	assert.Empty(t, subscriptions.GetAlls())
+	assert.Zero(t, hub.Clients())
+}
+
+func TestSubscribe_WhenSubscriptionsAlreadyPublishInTheHubAsTheEventType_ThenReturnsError(t *testing.T) {
+	// Arrange
+	subscriptions := eventsmanager.NewSubscriptions[userCreated]()
+	hub := NewHub(silentLogger{}, Options{})
+	defer hub.Close()
+	require.NoError(t, Subscribe(hub, subscriptions, "user.created"))
+
+	// Act
+	err := Subscribe(hub, subscriptions, "user.created")
+
+	// Assert
+	var hubErr HubError
+	require.ErrorAs(t, err, &hubErr)
+	assert.Equal(t, DuplicatedSubscription, hubErr.GetErrorType())
+}
+
+func TestSubscribe_WhenSubscriptionsPublishInSeveralHubsAndTypes_ThenEveryOneReceivesTheEvents(t *testing.T) {
+	// Arrange
+	subscriptions := eventsmanager.NewSubscriptions[userCreated]()
+	eventManager := eventsmanager.NewEventManager()
+	eventsmanager.Register(eventManager, eventsmanager.NewPublisher(subscriptions, silentLogger{}))
+	first, second := NewHub(silentLogger{}, Options{}), NewHub(silentLogger{}, Options{})
+	defer second.Close()
+	require.NoError(t, Subscribe(first, subscriptions, "user.created"))
+	require.NoError(t, Subscribe(second, subscriptions, "user.created"))
+	require.NoError(t, Subscribe(second, subscriptions, "user.registered"))
+	firstClient := connect(t, first, "/", "")
+	secondClient := connect(t, second, "/", "")
+
+	// Act
+	eventsmanager.Publish(eventManager, userCreated{ID: "7", Email: "b@example.com"})
+	firstReceived := firstClient.next(t)
+	secondReceived := []string{secondClient.next(t), secondClient.next(t)}
+	require.NoError(t, first.Close())
+
+	// Assert
+	assert.Contains(t, firstReceived, "event: user.created\n")
+	assert.ElementsMatch(t, []string{"user.created", "user.registered"}, []string{eventName(secondReceived[0]), eventName(secondReceived[1])})
+	assert.Len(t, subscriptions.GetAlls(), 1)
+	require.NoError(t, second.Close())
+	assert.Empty(t, subscriptions.GetAlls())
+}
+
+// eventName returns the event name of a received event
+func eventName(received string) string {
+	for _, line := range strings.Split(received, "\n") {
+		if name, ok := strings.CutPrefix(line, "event: "); ok {
+			return name
+		}
+	}
+	return ""
+}
+
+func TestHub_WhenEventOrRequestIsInvalid_ThenRejectsIt(t *testing.T) {
+	// Arrange
+	hub := NewHub(silentLogger{}, Options{})
+	post := httptest.NewRecorder()
+
+	// Act
+	invalidType := hub.Publish("user\ncreated", nil)
+	invalidData := hub.Publish("tick", func() {})
+	hub.ServeHTTP(post, httptest.NewRequest(http.MethodPost, "/", nil))
+	require.NoError(t, hub.Close())
+	closed := httptest.NewRecorder()
+	hub.ServeHTTP(closed, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	// Assert
+	for _, err := range []error{invalidType, invalidData} {
+		var hubErr HubError
+		require.ErrorAs(t, err, &hubErr)
+		assert.Equal(t, InvalidEvent, hubErr.GetErrorType())
+	}
+	assert.Equal(t, http.StatusMethodNotAllowed, post.Code)
+	assert.Equal(t, http.StatusServiceUnavailable, closed.Code)
+	var hubErr HubError
+	require.ErrorAs(t, hub.Publish("tick", 1), &hubErr)
+	assert.Equal(t, ClosedHub, hubErr.GetErrorType())
+}
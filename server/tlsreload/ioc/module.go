@@ -0,0 +1,25 @@
+package ioc
+
+import (
+	"github.com/janmbaco/go-infrastructure/v2/dependencyinjection"
+	"github.com/janmbaco/go-infrastructure/v2/server/tlsreload"
+)
+
+// TLSReloadModule implements Module for tlsreload services
+type TLSReloadModule struct{}
+
+// NewTLSReloadModule creates a new tlsreload module
+func NewTLSReloadModule() *TLSReloadModule {
+	return &TLSReloadModule{}
+}
+
+// RegisterServices registers all tlsreload services
+func (m *TLSReloadModule) RegisterServices(register dependencyinjection.Register) error {
+	dependencyinjection.RegisterTypeWithParams[*tlsreload.Certificate](
+		register,
+		tlsreload.Watch,
+		map[int]string{0: "certFile", 1: "keyFile"},
+	)
+
+	return nil
+}
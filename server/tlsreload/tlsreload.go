@@ -0,0 +1,145 @@
+// Package tlsreload serves a TLS certificate loaded from disk and reloads it when
+// the certificate or key file changes, so certificates rotate without restarting
+// the listener.
+//
+// A reload that fails, for example while only one of the files has been replaced,
+// is logged and the last good certificate keeps being served.
+//
+// Basic usage:
+//
+//	certificate, err := tlsreload.Watch("server.crt", "server.key", logger)
+//	if err != nil {
+//		return err
+//	}
+//	serverSetter.TLSConfig = certificate.TLSConfig()
+package tlsreload
+
+import (
+	"crypto/tls"
+	stdErrors "errors"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/janmbaco/go-infrastructure/v2/disk"
+	"github.com/janmbaco/go-infrastructure/v2/eventsmanager"
+	"github.com/janmbaco/go-infrastructure/v2/logs"
+)
+
+// Certificate is a certificate and key pair kept up to date with the files it was loaded from
+type Certificate struct {
+	logger   logs.Logger
+	current  atomic.Pointer[tls.Certificate]
+	certFile string
+	keyFile  string
+	// notifiers are the notifiers created by Watch, closed by Close
+	notifiers []disk.FileChangedNotifier
+	closeOnce sync.Once
+}
+
+// Watch loads the certificate and key files and reloads them when they are written, until it is closed
+func Watch(certFile, keyFile string, logger logs.Logger) (*Certificate, error) {
+	certNotifier, err := disk.NewFileChangedNotifier(certFile, eventsmanager.NewEventManager(), logger)
+	if err != nil {
+		return nil, newCertificateError(WatchFileError, "failed to watch "+certFile, err)
+	}
+	notifiers := []disk.FileChangedNotifier{certNotifier}
+	keyNotifier := certNotifier
+	if keyFile != certFile {
+		if keyNotifier, err = disk.NewFileChangedNotifier(keyFile, eventsmanager.NewEventManager(), logger); err != nil {
+			_ = closeNotifiers(notifiers) //nolint:errcheck // the watch failed already
+			return nil, newCertificateError(WatchFileError, "failed to watch "+keyFile, err)
+		}
+		notifiers = append(notifiers, keyNotifier)
+	}
+	certificate, err := NewCertificate(certFile, keyFile, certNotifier, keyNotifier, logger)
+	if err != nil {
+		_ = closeNotifiers(notifiers) //nolint:errcheck // the watch failed already
+		return nil, err
+	}
+	certificate.notifiers = notifiers
+	return certificate, nil
+}
+
+// NewCertificate loads the certificate and key files and reloads them when the notifiers report a change.
+// The notifiers may be the same when the certificate and the key are in the same file.
+func NewCertificate(certFile, keyFile string, certNotifier, keyNotifier disk.FileChangedNotifier, logger logs.Logger) (*Certificate, error) {
+	c := &Certificate{certFile: certFile, keyFile: keyFile, logger: logger}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	if err := certNotifier.Subscribe(c.onFileChanged); err != nil {
+		return nil, newCertificateError(WatchFileError, "failed to watch "+certFile, err)
+	}
+	if keyNotifier != certNotifier {
+		if err := keyNotifier.Subscribe(c.onFileChanged); err != nil {
+			return nil, newCertificateError(WatchFileError, "failed to watch "+keyFile, err)
+		}
+	}
+	return c, nil
+}
+
+// Reload loads the files again, keeping the current certificate if they are not a valid pair
+func (c *Certificate) Reload() error {
+	if err := c.load(); err != nil {
+		c.logger.Errorf("tlsreload - %v, keeping the last certificate: %v", err.GetMessage(), err.GetInternalError())
+		return err
+	}
+	c.logger.Infof("tlsreload - Reloaded certificate %v", c.certFile)
+	return nil
+}
+
+// GetCertificate returns the last certificate loaded, it is meant for tls.Config.GetCertificate
+func (c *Certificate) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return c.current.Load(), nil
+}
+
+// GetClientCertificate returns the last certificate loaded, it is meant for tls.Config.GetClientCertificate
+func (c *Certificate) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return c.current.Load(), nil
+}
+
+// TLSConfig returns a server tls.Config that always presents the last certificate loaded
+func (c *Certificate) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: c.GetCertificate,
+	}
+}
+
+// Close stops watching the files of a certificate returned by Watch; the last certificate loaded is still served.
+// The notifiers given to NewCertificate are left to their owner.
+func (c *Certificate) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		if closeErr := closeNotifiers(c.notifiers); closeErr != nil {
+			err = newCertificateError(WatchFileError, "failed to stop watching "+c.certFile, closeErr)
+		}
+	})
+	return err
+}
+
+func (c *Certificate) onFileChanged() {
+	_ = c.Reload() //nolint:errcheck // the failure is logged and the last certificate is kept
+}
+
+func closeNotifiers(notifiers []disk.FileChangedNotifier) error {
+	errs := make([]error, 0)
+	for _, notifier := range notifiers {
+		if closer, ok := notifier.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return stdErrors.Join(errs...)
+}
+
+func (c *Certificate) load() CertificateError {
+	certificate, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return newCertificateError(LoadCertificateError, "failed to load "+c.certFile+" and "+c.keyFile, err)
+	}
+	c.current.Store(&certificate)
+	return nil
+}
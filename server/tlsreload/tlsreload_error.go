@@ -0,0 +1,38 @@
+package tlsreload
+
+import (
+	"github.com/janmbaco/go-infrastructure/v2/errors"
+)
+
+// CertificateError is the errors of Certificate
+type CertificateError interface {
+	errors.CustomError
+	GetErrorType() CertificateErrorType
+}
+
+type certificateError struct {
+	errors.CustomizableError
+	ErrorType CertificateErrorType
+}
+
+func newCertificateError(errorType CertificateErrorType, message string, internalError error) CertificateError {
+	return &certificateError{
+		CustomizableError: errors.CustomizableError{
+			Message:       message,
+			InternalError: internalError,
+		},
+		ErrorType: errorType,
+	}
+}
+
+func (e *certificateError) GetErrorType() CertificateErrorType {
+	return e.ErrorType
+}
+
+type CertificateErrorType uint8
+
+const (
+	UnexpectedError CertificateErrorType = iota
+	LoadCertificateError
+	WatchFileError
+)
@@ -0,0 +1,211 @@
+package tlsreload
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/janmbaco/go-infrastructure/v2/logs"
+)
+
+type recordingLogger struct {
+	logs.Logger
+	mutex   sync.Mutex
+	entries []string
+}
+
+func (l *recordingLogger) Infof(format string, a ...interface{}) {
+	l.record("INFO: "+format, a...)
+}
+
+func (l *recordingLogger) Errorf(format string, a ...interface{}) {
+	l.record("ERROR: "+format, a...)
+}
+
+func (l *recordingLogger) record(format string, a ...interface{}) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.entries = append(l.entries, fmt.Sprintf(format, a...))
+}
+
+type mockFileChangedNotifier struct {
+	subscribeFuncs []func()
+}
+
+func (m *mockFileChangedNotifier) Subscribe(subscribeFunc func()) error {
+	m.subscribeFuncs = append(m.subscribeFuncs, subscribeFunc)
+	return nil
+}
+
+func (m *mockFileChangedNotifier) notify() {
+	for _, fn := range m.subscribeFuncs {
+		fn()
+	}
+}
+
+// writeKeyPair writes a self-signed certificate for commonName and its key
+func writeKeyPair(t *testing.T, certFile, keyFile, commonName string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0o600))
+}
+
+func commonName(t *testing.T, certificate *tls.Certificate) string {
+	t.Helper()
+	leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+	require.NoError(t, err)
+	return leaf.Subject.CommonName
+}
+
+func keyPairFiles(t *testing.T) (string, string) {
+	dir := t.TempDir()
+	return filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+}
+
+func TestNewCertificate_WhenFilesAreValid_ThenServesCertificate(t *testing.T) {
+	// Arrange
+	certFile, keyFile := keyPairFiles(t)
+	writeKeyPair(t, certFile, keyFile, "v1")
+	notifier := &mockFileChangedNotifier{}
+
+	// Act
+	certificate, err := NewCertificate(certFile, keyFile, notifier, notifier, &recordingLogger{})
+
+	// Assert
+	require.NoError(t, err)
+	served, err := certificate.TLSConfig().GetCertificate(&tls.ClientHelloInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, "v1", commonName(t, served))
+	assert.Len(t, notifier.subscribeFuncs, 1)
+}
+
+func TestNewCertificate_WhenFilesAreMissing_ThenReturnsLoadCertificateError(t *testing.T) {
+	// Arrange
+	certFile, keyFile := keyPairFiles(t)
+	notifier := &mockFileChangedNotifier{}
+
+	// Act
+	certificate, err := NewCertificate(certFile, keyFile, notifier, notifier, &recordingLogger{})
+
+	// Assert
+	assert.Nil(t, certificate)
+	var certificateErr CertificateError
+	require.ErrorAs(t, err, &certificateErr)
+	assert.Equal(t, LoadCertificateError, certificateErr.GetErrorType())
+}
+
+func TestCertificate_WhenFilesChange_ThenServesNewCertificate(t *testing.T) {
+	// Arrange
+	certFile, keyFile := keyPairFiles(t)
+	writeKeyPair(t, certFile, keyFile, "v1")
+	certNotifier := &mockFileChangedNotifier{}
+	keyNotifier := &mockFileChangedNotifier{}
+	logger := &recordingLogger{}
+	certificate, err := NewCertificate(certFile, keyFile, certNotifier, keyNotifier, logger)
+	require.NoError(t, err)
+
+	// Act
+	writeKeyPair(t, certFile, keyFile, "v2")
+	keyNotifier.notify()
+
+	// Assert
+	served, err := certificate.GetCertificate(&tls.ClientHelloInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, "v2", commonName(t, served))
+	assert.Len(t, certNotifier.subscribeFuncs, 1)
+	assert.Contains(t, logger.entries, "INFO: tlsreload - Reloaded certificate "+certFile)
+}
+
+func TestCertificate_WhenReloadFails_ThenKeepsLastCertificateAndLogs(t *testing.T) {
+	// Arrange
+	certFile, keyFile := keyPairFiles(t)
+	writeKeyPair(t, certFile, keyFile, "v1")
+	notifier := &mockFileChangedNotifier{}
+	logger := &recordingLogger{}
+	certificate, err := NewCertificate(certFile, keyFile, notifier, notifier, logger)
+	require.NoError(t, err)
+
+	// Act
+	require.NoError(t, os.WriteFile(certFile, []byte("half written"), 0o600))
+	notifier.notify()
+	reloadErr := certificate.Reload()
+
+	// Assert
+	served, err := certificate.GetCertificate(&tls.ClientHelloInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, "v1", commonName(t, served))
+	var certificateErr CertificateError
+	require.ErrorAs(t, reloadErr, &certificateErr)
+	assert.Equal(t, LoadCertificateError, certificateErr.GetErrorType())
+	require.Len(t, logger.entries, 2)
+	assert.Contains(t, logger.entries[0], "ERROR: tlsreload - failed to load "+certFile)
+	assert.Contains(t, logger.entries[0], "keeping the last certificate")
+}
+
+func TestWatch_WhenFilesAreWritten_ThenReloadsWithoutRestart(t *testing.T) {
+	// Arrange
+	certFile, keyFile := keyPairFiles(t)
+	writeKeyPair(t, certFile, keyFile, "v1")
+	certificate, err := Watch(certFile, keyFile, &recordingLogger{})
+	require.NoError(t, err)
+	defer func() { _ = certificate.Close() }()
+
+	// Act
+	writeKeyPair(t, certFile, keyFile, "v2")
+
+	// Assert
+	assert.Eventually(t, func() bool {
+		served, err := certificate.GetCertificate(&tls.ClientHelloInfo{})
+		return err == nil && commonName(t, served) == "v2"
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestWatch_WhenClosed_ThenStopsReloadingAndKeepsServing(t *testing.T) {
+	// Arrange
+	certFile, keyFile := keyPairFiles(t)
+	writeKeyPair(t, certFile, keyFile, "v1")
+	logger := &recordingLogger{}
+	certificate, err := Watch(certFile, keyFile, logger)
+	require.NoError(t, err)
+
+	// Act
+	closeErr := certificate.Close()
+	writeKeyPair(t, certFile, keyFile, "v2")
+	time.Sleep(100 * time.Millisecond)
+
+	// Assert
+	require.NoError(t, closeErr)
+	assert.NoError(t, certificate.Close())
+	served, err := certificate.GetCertificate(&tls.ClientHelloInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, "v1", commonName(t, served))
+	logger.mutex.Lock()
+	defer logger.mutex.Unlock()
+	assert.Empty(t, logger.entries)
+}
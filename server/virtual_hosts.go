@@ -0,0 +1,169 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+type (
+	// VirtualHost routes the requests for Host below PathPrefix to Handler, without the prefix
+	VirtualHost struct {
+		// Host is a name like example.com, a wildcard like *.example.com that matches its subdomains,
+		// or empty or * to match any host
+		Host string
+		// PathPrefix is matched on path segments, the root when empty
+		PathPrefix string
+		Handler    http.Handler
+	}
+
+	// VirtualHostRouter serves several sites from one listener by Host header and path prefix.
+	// The hosts can be replaced with Set while it serves.
+	VirtualHostRouter struct {
+		hosts atomic.Pointer[[]virtualHost]
+	}
+
+	virtualHost struct {
+		host     string
+		wildcard bool
+		prefix   string
+		handler  http.Handler
+	}
+)
+
+// NewVirtualHostRouter returns a VirtualHostRouter without hosts, which answers 404 Not Found
+func NewVirtualHostRouter() *VirtualHostRouter {
+	router := &VirtualHostRouter{}
+	router.hosts.Store(&[]virtualHost{})
+	return router
+}
+
+// Set replaces the hosts; when a host is invalid or repeated the current ones are kept
+func (v *VirtualHostRouter) Set(hosts []VirtualHost) error {
+	compiled := make([]virtualHost, 0, len(hosts))
+	seen := make(map[string]bool, len(hosts))
+	for _, host := range hosts {
+		vh, err := newVirtualHost(host)
+		if err != nil {
+			return err
+		}
+		key := vh.host + vh.prefix
+		if vh.wildcard {
+			key = "*." + key
+		}
+		if seen[key] {
+			return newVirtualHostError(DuplicatedVirtualHost, "the host `"+host.Host+"` with the path prefix `"+host.PathPrefix+"` is repeated", nil)
+		}
+		seen[key] = true
+		compiled = append(compiled, vh)
+	}
+	// exact hosts first, then the longest wildcards and any host; the longest prefix first for each host
+	sort.SliceStable(compiled, func(i, j int) bool {
+		if rank, other := compiled[i].rank(), compiled[j].rank(); rank != other {
+			return rank > other
+		}
+		return len(compiled[i].prefix) > len(compiled[j].prefix)
+	})
+	v.hosts.Store(&compiled)
+	return nil
+}
+
+func (v *VirtualHostRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	host := requestHost(r)
+	for _, vh := range *v.hosts.Load() {
+		if !vh.matchesHost(host) || !matchesPrefix(r.URL.Path, vh.prefix) {
+			continue
+		}
+		if vh.prefix != "/" && r.URL.Path == vh.prefix {
+			target := vh.prefix + "/"
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
+			return
+		}
+		vh.serve(w, r)
+		return
+	}
+	http.NotFound(w, r)
+}
+
+func newVirtualHost(host VirtualHost) (virtualHost, error) {
+	if host.Handler == nil {
+		return virtualHost{}, newVirtualHostError(InvalidVirtualHost, "the host `"+host.Host+"` has no handler", nil)
+	}
+	name := strings.ToLower(strings.TrimSuffix(host.Host, "."))
+	wildcard := false
+	switch {
+	case name == "*":
+		name = ""
+	case strings.HasPrefix(name, "*."):
+		name, wildcard = name[1:], true
+	}
+	if strings.ContainsAny(name, "*/: ") {
+		return virtualHost{}, newVirtualHostError(InvalidVirtualHost, "the host `"+host.Host+"` is not a name or a *. wildcard", nil)
+	}
+
+	prefix := host.PathPrefix
+	if prefix == "" {
+		prefix = "/"
+	}
+	if !strings.HasPrefix(prefix, "/") {
+		return virtualHost{}, newVirtualHostError(InvalidVirtualHost, "the path prefix `"+host.PathPrefix+"` does not start with /", nil)
+	}
+	if prefix != "/" {
+		prefix = strings.TrimRight(prefix, "/")
+	}
+	return virtualHost{host: name, wildcard: wildcard, prefix: prefix, handler: host.Handler}, nil
+}
+
+// rank orders the hosts from the most specific one
+func (vh virtualHost) rank() int {
+	switch {
+	case vh.host == "":
+		return 0
+	case vh.wildcard:
+		return len(vh.host)
+	default:
+		return 1 << 16
+	}
+}
+
+func (vh virtualHost) matchesHost(host string) bool {
+	switch {
+	case vh.host == "":
+		return true
+	case vh.wildcard:
+		return strings.HasSuffix(host, vh.host) && len(host) > len(vh.host)
+	default:
+		return host == vh.host
+	}
+}
+
+// serve calls the handler with the path below the prefix, like http.StripPrefix
+func (vh virtualHost) serve(w http.ResponseWriter, r *http.Request) {
+	if vh.prefix == "/" {
+		vh.handler.ServeHTTP(w, r)
+		return
+	}
+	r2 := new(http.Request)
+	*r2 = *r
+	u := *r.URL
+	r2.URL = &u
+	r2.URL.Path = trimPathPrefix(r.URL.Path, vh.prefix)
+	if r.URL.RawPath != "" {
+		r2.URL.RawPath = trimPathPrefix(r.URL.RawPath, vh.prefix)
+	}
+	vh.handler.ServeHTTP(w, r2)
+}
+
+// requestHost returns the host of the request in lower case, without the port and the trailing dot
+func requestHost(r *http.Request) string {
+	host := r.Host
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		host = name
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"github.com/janmbaco/go-infrastructure/v2/errors"
+)
+
+// VirtualHostError is the errors of VirtualHostRouter
+type VirtualHostError interface {
+	errors.CustomError
+	GetErrorType() VirtualHostErrorType
+}
+
+type virtualHostError struct {
+	errors.CustomizableError
+	ErrorType VirtualHostErrorType
+}
+
+func newVirtualHostError(errorType VirtualHostErrorType, message string, internalError error) VirtualHostError {
+	return &virtualHostError{
+		CustomizableError: errors.CustomizableError{
+			Message:       message,
+			InternalError: internalError,
+		},
+		ErrorType: errorType,
+	}
+}
+
+func (e *virtualHostError) GetErrorType() VirtualHostErrorType {
+	return e.ErrorType
+}
+
+type VirtualHostErrorType uint8
+
+const (
+	UnexpectedVirtualHostError VirtualHostErrorType = iota
+	InvalidVirtualHost
+	DuplicatedVirtualHost
+)
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// namedHandler answers its name and the path it receives
+func namedHandler(name string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(name + " " + r.URL.Path))
+	})
+}
+
+func hostGet(handler http.Handler, host, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", target, nil)
+	req.Host = host
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestVirtualHostRouter_ServeHTTP_WhenHostsOverlap_ThenMostSpecificWins(t *testing.T) {
+	// Arrange
+	router := NewVirtualHostRouter()
+	require.NoError(t, router.Set([]VirtualHost{
+		{Handler: namedHandler("default")},
+		{Host: "*.example.com", Handler: namedHandler("wildcard")},
+		{Host: "*.api.example.com", Handler: namedHandler("api-wildcard")},
+		{Host: "www.example.com", Handler: namedHandler("www")},
+		{Host: "www.example.com", PathPrefix: "/docs", Handler: namedHandler("docs")},
+	}))
+
+	cases := []struct{ host, target, expected string }{
+		{"www.example.com", "/users", "www /users"},
+		{"WWW.Example.com:8080", "/", "www /"},
+		{"www.example.com.", "/", "www /"},
+		{"www.example.com", "/docs/intro", "docs /intro"},
+		{"www.example.com", "/docsets", "www /docsets"},
+		{"shop.example.com", "/", "wildcard /"},
+		{"v1.api.example.com", "/", "api-wildcard /"},
+		{"example.com", "/", "default /"},
+		{"other.org", "/docs/intro", "default /docs/intro"},
+	}
+
+	for _, c := range cases {
+		// Act
+		w := hostGet(router, c.host, c.target)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, w.Code, c.host+c.target)
+		assert.Equal(t, c.expected, w.Body.String(), c.host+c.target)
+	}
+}
+
+func TestVirtualHostRouter_ServeHTTP_WhenPathIsThePrefix_ThenRedirectsBelowIt(t *testing.T) {
+	// Arrange
+	router := NewVirtualHostRouter()
+	require.NoError(t, router.Set([]VirtualHost{{Host: "example.com", PathPrefix: "/app/", Handler: namedHandler("app")}}))
+
+	// Act
+	w := hostGet(router, "example.com", "/app?lang=en")
+
+	// Assert
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "/app/?lang=en", w.Header().Get("Location"))
+}
+
+func TestVirtualHostRouter_ServeHTTP_WhenNoHostMatches_ThenReturnsNotFound(t *testing.T) {
+	// Arrange
+	router := NewVirtualHostRouter()
+	require.NoError(t, router.Set([]VirtualHost{{Host: "example.com", PathPrefix: "/app", Handler: namedHandler("app")}}))
+
+	for _, c := range []struct{ host, target string }{{"other.org", "/app/"}, {"example.com", "/"}} {
+		// Act
+		w := hostGet(router, c.host, c.target)
+
+		// Assert
+		assert.Equal(t, http.StatusNotFound, w.Code, c.host+c.target)
+	}
+}
+
+func TestVirtualHostRouter_Set_WhenHostsChange_ThenServesNewHosts(t *testing.T) {
+	// Arrange
+	router := NewVirtualHostRouter()
+	require.NoError(t, router.Set([]VirtualHost{{Host: "a.example.com", Handler: namedHandler("a")}}))
+
+	// Act
+	err := router.Set([]VirtualHost{{Host: "b.example.com", Handler: namedHandler("b")}})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, hostGet(router, "a.example.com", "/").Code)
+	assert.Equal(t, "b /", hostGet(router, "b.example.com", "/").Body.String())
+}
+
+func TestVirtualHostRouter_Set_WhenHostIsInvalid_ThenKeepsCurrentHosts(t *testing.T) {
+	// Arrange
+	router := NewVirtualHostRouter()
+	require.NoError(t, router.Set([]VirtualHost{{Host: "example.com", Handler: namedHandler("site")}}))
+
+	cases := []struct {
+		hosts    []VirtualHost
+		expected VirtualHostErrorType
+	}{
+		{[]VirtualHost{{Host: "example.com"}}, InvalidVirtualHost},
+		{[]VirtualHost{{Host: "www.*.com", Handler: namedHandler("x")}}, InvalidVirtualHost},
+		{[]VirtualHost{{Host: "example.com:80", Handler: namedHandler("x")}}, InvalidVirtualHost},
+		{[]VirtualHost{{Host: "example.com", PathPrefix: "app", Handler: namedHandler("x")}}, InvalidVirtualHost},
+		{[]VirtualHost{{Host: "Example.com", Handler: namedHandler("x")}, {Host: "example.com", PathPrefix: "/", Handler: namedHandler("y")}}, DuplicatedVirtualHost},
+		{[]VirtualHost{{Handler: namedHandler("x")}, {Host: "*", Handler: namedHandler("y")}}, DuplicatedVirtualHost},
+	}
+
+	for _, c := range cases {
+		// Act
+		err := router.Set(c.hosts)
+
+		// Assert
+		require.Error(t, err)
+		var hostErr VirtualHostError
+		require.ErrorAs(t, err, &hostErr)
+		assert.Equal(t, c.expected, hostErr.GetErrorType(), err.Error())
+		assert.Equal(t, "site /", hostGet(router, "example.com", "/").Body.String())
+	}
+}
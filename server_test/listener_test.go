@@ -1,9 +1,18 @@
 package server_test
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
+	"io"
+	"net"
 	"net/http"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -16,17 +25,28 @@ import (
 	errorsIoc "github.com/janmbaco/go-infrastructure/v2/errors/ioc"
 	eventsIoc "github.com/janmbaco/go-infrastructure/v2/eventsmanager/ioc"
 	logsIoc "github.com/janmbaco/go-infrastructure/v2/logs/ioc"
+	logsResolver "github.com/janmbaco/go-infrastructure/v2/logs/ioc/resolver"
+	"github.com/janmbaco/go-infrastructure/v2/metrics"
 	"github.com/janmbaco/go-infrastructure/v2/server"
+	"github.com/janmbaco/go-infrastructure/v2/server/devtls"
 	serverIoc "github.com/janmbaco/go-infrastructure/v2/server/ioc"
 	serverResolver "github.com/janmbaco/go-infrastructure/v2/server/ioc/resolver"
+	"github.com/janmbaco/go-infrastructure/v2/server/middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
 )
 
 // / <summary>
 // / Implementa la responsabilidad de probar el comportamiento del Listener con recuperación de pánico.
 // / </summary>
 type ListenerTests struct {
-	configHandler  configuration.ConfigHandler
-	Resolver       dependencyinjection.Resolver
+	configHandler configuration.ConfigHandler
+	Resolver      dependencyinjection.Resolver
+	// listeners are served instead of binding the addresses of the config that name them
+	listeners      map[string]net.Listener
 	configFilePath string
 }
 
@@ -67,13 +87,17 @@ func (lt *ListenerTests) teardown() {
 }
 
 func (lt *ListenerTests) createListener(name string, addressSelector func(*testConfig) string) (server.Listener, error) {
+	return lt.createListenerWithHandler(name, addressSelector, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("started"))
+	}))
+}
+
+func (lt *ListenerTests) createListenerWithHandler(name string, addressSelector func(*testConfig) string, handler http.Handler) (server.Listener, error) {
 	builder := serverResolver.GetListenerBuilder(lt.Resolver, lt.configHandler)
 	builder.SetBootstrapper(func(config interface{}, serverSetter *server.ServerSetter) error {
 		serverSetter.Name = name
 		mux := http.NewServeMux()
-		mux.Handle("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			_, _ = w.Write([]byte("started"))
-		}))
+		mux.Handle("/", handler)
 		serverSetter.Addr = addressSelector(config.(*testConfig))
 		serverSetter.Handler = mux
 		return nil
@@ -81,6 +105,40 @@ func (lt *ListenerTests) createListener(name string, addressSelector func(*testC
 	return builder.GetListener()
 }
 
+// listenLocal returns a listener on an ephemeral port to serve through ServerSetter.Listener, closed when the test ends
+func listenLocal(t *testing.T) (net.Listener, string) {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { _ = lis.Close() })
+	return lis, lis.Addr().String()
+}
+
+func (lt *ListenerTests) createProvidedListener(name string, lis net.Listener, handler http.Handler) (server.Listener, error) {
+	builder := serverResolver.GetListenerBuilder(lt.Resolver, lt.configHandler)
+	builder.SetBootstrapper(func(config interface{}, serverSetter *server.ServerSetter) error {
+		serverSetter.Name = name
+		serverSetter.Listener = lis
+		serverSetter.Handler = handler
+		return nil
+	})
+	return builder.GetListener()
+}
+
+func waitForServer(t *testing.T, address string) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		if conn, err := net.Dial("tcp", address); err == nil {
+			_ = conn.Close()
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("server on %v did not start", address)
+}
+
 func (lt *ListenerTests) updateConfigToDuplicatePort(t *testing.T) {
 	content, err := json.MarshalIndent(&testConfig{
 		Address:  firstAddress,
@@ -133,3 +191,801 @@ func TestListener_WhenPortConflictOccurs_ThenRecoversByRestoringConfig(t *testin
 		t.Errorf("Assert failed: Listener2 terminó con error: %v", err2)
 	}
 }
+
+func TestListener_StopCtx_WhenRequestOutlivesDeadline_ThenForcesShutdownAndReportsCutConnections(t *testing.T) {
+	// Arrange
+	lt := &ListenerTests{}
+	lt.setup(t)
+	defer lt.teardown()
+	lis, address := listenLocal(t)
+
+	entered := make(chan bool, 1)
+	release := make(chan bool)
+	defer close(release)
+	listener, err := lt.createProvidedListener("HungListener", lis,
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			entered <- true
+			<-release
+		}))
+	if err != nil {
+		t.Fatalf("Arrange failed: %v", err)
+	}
+	finish := listener.Start()
+	waitForServer(t, address)
+	go func() {
+		_, _ = http.Get("http://" + address + "/")
+	}()
+	<-entered
+
+	// Act
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	stopErr := listener.StopCtx(ctx)
+
+	// Assert
+	listenerErr, ok := stopErr.(server.ListenerError)
+	if !ok {
+		t.Fatalf("Assert failed: expected ListenerError, got %v", stopErr)
+	}
+	if listenerErr.GetErrorType() != server.ShutdownDeadlineExceeded {
+		t.Errorf("Assert failed: expected ShutdownDeadlineExceeded, got %v", listenerErr.GetErrorType())
+	}
+	if listenerErr.GetMessage() != "shutdown deadline exceeded, 1 connections were closed" {
+		t.Errorf("Assert failed: unexpected message %q", listenerErr.GetMessage())
+	}
+	if err := <-finish; err != nil {
+		t.Errorf("Assert failed: listener finished with error: %v", err)
+	}
+}
+
+func TestListener_StateChangedSubscribe_WhenStartedAndStopped_ThenPublishesTransitions(t *testing.T) {
+	// Arrange
+	lt := &ListenerTests{}
+	lt.setup(t)
+	defer lt.teardown()
+	lis, address := listenLocal(t)
+
+	listener, err := lt.createProvidedListener("StateListener", lis, http.NotFoundHandler())
+	if err != nil {
+		t.Fatalf("Arrange failed: %v", err)
+	}
+	var mutex sync.Mutex
+	transitions := make([]server.State, 0)
+	if err := listener.StateChangedSubscribe(func(event server.StateChangedEvent) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		transitions = append(transitions, event.Current)
+	}); err != nil {
+		t.Fatalf("Arrange failed: %v", err)
+	}
+
+	// Act
+	finish := listener.Start()
+	waitForServer(t, address)
+	stateWhileRunning := listener.State()
+	listener.Stop()
+	<-finish
+
+	// Assert
+	if stateWhileRunning != server.Listening {
+		t.Errorf("Assert failed: expected Listening while running, got %v", stateWhileRunning)
+	}
+	if listener.State() != server.Stopped {
+		t.Errorf("Assert failed: expected Stopped after stop, got %v", listener.State())
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	expected := []server.State{server.Starting, server.Listening, server.Stopping, server.Stopped}
+	if !reflect.DeepEqual(expected, transitions) {
+		t.Errorf("Assert failed: expected transitions %v, got %v", expected, transitions)
+	}
+}
+
+func TestListener_WhenHTTPGrpcServer_ThenServesHTTPAndGrpcOnSameAddress(t *testing.T) {
+	// Arrange
+	lt := &ListenerTests{}
+	lt.setup(t)
+	defer lt.teardown()
+	lis, address := listenLocal(t)
+
+	listener, err := serverResolver.GetListenerBuilder(lt.Resolver, lt.configHandler).
+		SetBootstrapper(func(config interface{}, serverSetter *server.ServerSetter) error {
+			serverSetter.Name = "MixedListener"
+			serverSetter.Listener = lis
+			serverSetter.ServerType = server.HTTPGrpcServer
+			serverSetter.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("http"))
+			})
+			return nil
+		}).
+		SetGrpcDefinitions(func(grpcServer *grpc.Server) {
+			healthpb.RegisterHealthServer(grpcServer, health.NewServer())
+		}).
+		GetListener()
+	if err != nil {
+		t.Fatalf("Arrange failed: %v", err)
+	}
+	finish := listener.Start()
+	defer func() {
+		listener.Stop()
+		<-finish
+	}()
+	waitForServer(t, address)
+
+	// Act
+	httpResponse, httpErr := http.Get("http://" + address + "/")
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Act failed: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	grpcResponse, grpcErr := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+
+	// Assert
+	if httpErr != nil {
+		t.Fatalf("Assert failed: http request failed: %v", httpErr)
+	}
+	defer func() { _ = httpResponse.Body.Close() }()
+	body, _ := io.ReadAll(httpResponse.Body)
+	if string(body) != "http" {
+		t.Errorf("Assert failed: expected http body, got %q", body)
+	}
+	if grpcErr != nil {
+		t.Fatalf("Assert failed: grpc request failed: %v", grpcErr)
+	}
+	if grpcResponse.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("Assert failed: expected SERVING, got %v", grpcResponse.GetStatus())
+	}
+}
+
+func TestListener_WhenTLSConfigSet_ThenServesHTTPS(t *testing.T) {
+	// Arrange
+	lt := &ListenerTests{}
+	lt.setup(t)
+	defer lt.teardown()
+	lis, address := listenLocal(t)
+
+	authority, err := devtls.LoadOrCreateAuthority(t.TempDir())
+	if err != nil {
+		t.Fatalf("Arrange failed: %v", err)
+	}
+	tlsConfig, err := authority.ServerTLSConfig("127.0.0.1")
+	if err != nil {
+		t.Fatalf("Arrange failed: %v", err)
+	}
+	listener, err := serverResolver.GetListenerBuilder(lt.Resolver, lt.configHandler).
+		SetBootstrapper(func(config interface{}, serverSetter *server.ServerSetter) error {
+			serverSetter.Name = "TLSListener"
+			serverSetter.Listener = lis
+			serverSetter.TLSConfig = tlsConfig
+			serverSetter.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(r.Proto))
+			})
+			return nil
+		}).
+		GetListener()
+	if err != nil {
+		t.Fatalf("Arrange failed: %v", err)
+	}
+	finish := listener.Start()
+	defer func() {
+		listener.Stop()
+		<-finish
+	}()
+	waitForServer(t, address)
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{MinVersion: tls.VersionTLS12, RootCAs: authority.CertPool()},
+		ForceAttemptHTTP2: true,
+	}}
+
+	// Act
+	response, err := client.Get("https://" + address + "/")
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Assert failed: https request failed: %v", err)
+	}
+	defer func() { _ = response.Body.Close() }()
+	body, _ := io.ReadAll(response.Body)
+	if string(body) != "HTTP/2.0" {
+		t.Errorf("Assert failed: expected HTTP/2.0 over TLS, got %q", body)
+	}
+}
+
+func TestListener_WhenListenerProvided_ThenServesItAcrossRestartsAndStops(t *testing.T) {
+	// Arrange
+	lt := &ListenerTests{}
+	lt.setup(t)
+	defer lt.teardown()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Arrange failed: %v", err)
+	}
+	address := lis.Addr().String()
+	listener, err := serverResolver.GetListenerBuilder(lt.Resolver, lt.configHandler).
+		SetBootstrapper(func(config interface{}, serverSetter *server.ServerSetter) error {
+			body := []byte(config.(*testConfig).Address2)
+			serverSetter.Name = "ProvidedListener"
+			serverSetter.Listener = lis
+			serverSetter.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write(body)
+			})
+			return nil
+		}).
+		GetListener()
+	if err != nil {
+		t.Fatalf("Arrange failed: %v", err)
+	}
+	restarted := waitForRestart(t, listener)
+	finish := listener.Start()
+	before, beforeErr := getBody(address)
+
+	// Act
+	lt.writeConfig(t, &testConfig{Address: firstAddress, Address2: "v2"})
+	state := <-restarted
+	after, afterErr := getBody(address)
+	listener.Stop()
+	<-finish
+	finish = listener.Start()
+	started, startedErr := getBody(address)
+	listener.Stop()
+	<-finish
+	_, stoppedErr := net.Dial("tcp", address)
+	_ = lis.Close()
+
+	// Assert
+	if beforeErr != nil || before != secondAddress {
+		t.Errorf("Assert failed: expected %q before the restart, got %q, %v", secondAddress, before, beforeErr)
+	}
+	if state != server.Listening {
+		t.Errorf("Assert failed: expected Listening after restart, got %v", state)
+	}
+	if afterErr != nil || after != "v2" {
+		t.Errorf("Assert failed: expected v2 on the same listener after the restart, got %q, %v", after, afterErr)
+	}
+	if startedErr != nil || started != "v2" {
+		t.Errorf("Assert failed: expected v2 on the same listener after starting again, got %q, %v", started, startedErr)
+	}
+	if stoppedErr != nil {
+		t.Errorf("Assert failed: expected the provided listener to stay open on stop, got %v", stoppedErr)
+	}
+}
+
+func (lt *ListenerTests) createListenerWithPolicy(name, address string, policy server.RestartPolicy) (server.Listener, error) {
+	return serverResolver.GetListenerBuilder(lt.Resolver, lt.configHandler).
+		SetBootstrapper(func(config interface{}, serverSetter *server.ServerSetter) error {
+			serverSetter.Name = name
+			serverSetter.Addr = address
+			return nil
+		}).
+		SetRestartPolicy(policy).
+		GetListener()
+}
+
+func TestListener_WhenRestartBudgetExhausted_ThenFinishesWithAttemptHistory(t *testing.T) {
+	// Arrange
+	lt := &ListenerTests{}
+	lt.setup(t)
+	defer lt.teardown()
+
+	occupied, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Arrange failed: %v", err)
+	}
+	defer func() { _ = occupied.Close() }()
+	listener, err := lt.createListenerWithPolicy("CrashLoopListener", occupied.Addr().String(),
+		server.RestartPolicy{MaxRetries: 2, InitialBackoff: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Arrange failed: %v", err)
+	}
+
+	// Act
+	finish := listener.Start()
+	var listenerErr server.ListenerError
+	select {
+	case listenerErr = <-finish:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Act failed: the listener did not give up")
+	}
+
+	// Assert
+	budgetErr, ok := listenerErr.(server.RestartBudgetError)
+	if !ok {
+		t.Fatalf("Assert failed: expected a RestartBudgetError, got %v", listenerErr)
+	}
+	if budgetErr.GetErrorType() != server.RestartBudgetExhausted {
+		t.Errorf("Assert failed: expected RestartBudgetExhausted, got %v", budgetErr.GetErrorType())
+	}
+	if attempts := budgetErr.GetAttempts(); len(attempts) != 3 || attempts[2].Cause == nil {
+		t.Errorf("Assert failed: expected 3 failed attempts, got %v", attempts)
+	}
+	if listener.State() != server.Failed {
+		t.Errorf("Assert failed: expected Failed, got %v", listener.State())
+	}
+}
+
+func TestListener_WhenStartFailsWithRestartPolicy_ThenRetriesUntilItListens(t *testing.T) {
+	// Arrange
+	lt := &ListenerTests{}
+	lt.setup(t)
+	defer lt.teardown()
+
+	occupied, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Arrange failed: %v", err)
+	}
+	address := occupied.Addr().String()
+	listener, err := lt.createListenerWithPolicy("RetryingListener", address,
+		server.RestartPolicy{MaxRetries: 100, InitialBackoff: 10 * time.Millisecond, MaxBackoff: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Arrange failed: %v", err)
+	}
+	listening := make(chan bool, 1)
+	if err := listener.StateChangedSubscribe(func(event server.StateChangedEvent) {
+		if event.Current == server.Listening {
+			listening <- true
+		}
+	}); err != nil {
+		t.Fatalf("Arrange failed: %v", err)
+	}
+	finish := listener.Start()
+	defer func() {
+		listener.Stop()
+		<-finish
+	}()
+
+	// Act
+	time.Sleep(50 * time.Millisecond)
+	_ = occupied.Close()
+
+	// Assert
+	select {
+	case <-listening:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Assert failed: the listener did not listen after the address was freed")
+	}
+	waitForServer(t, address)
+}
+
+func TestListener_WhenServerSetterUsesMiddlewares_ThenWrapsHandlerInOrder(t *testing.T) {
+	// Arrange
+	lt := &ListenerTests{}
+	lt.setup(t)
+	defer lt.teardown()
+	lis, address := listenLocal(t)
+
+	trace := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("X-Trace", name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	listener, err := serverResolver.GetListenerBuilder(lt.Resolver, lt.configHandler).
+		SetBootstrapper(func(config interface{}, serverSetter *server.ServerSetter) error {
+			serverSetter.Name = "MiddlewareListener"
+			serverSetter.Listener = lis
+			serverSetter.Handler = http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+				panic("handler failure")
+			})
+			serverSetter.Use(trace("outer"), middleware.Recovery(logsResolver.GetLogger(lt.Resolver)))
+			serverSetter.Use(trace("inner"))
+			return nil
+		}).
+		GetListener()
+	if err != nil {
+		t.Fatalf("Arrange failed: %v", err)
+	}
+	finish := listener.Start()
+	defer func() {
+		listener.Stop()
+		<-finish
+	}()
+	waitForServer(t, address)
+
+	// Act
+	response, err := http.Get("http://" + address + "/")
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Assert failed: request failed: %v", err)
+	}
+	defer func() { _ = response.Body.Close() }()
+	if response.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Assert failed: expected 500 from the recovery middleware, got %v", response.StatusCode)
+	}
+	if trace := response.Header.Values("X-Trace"); !reflect.DeepEqual([]string{"outer", "inner"}, trace) {
+		t.Errorf("Assert failed: expected middlewares [outer inner], got %v", trace)
+	}
+}
+
+func TestListener_WhenGrpcInterceptorsAndReflectionSet_ThenAppliesThem(t *testing.T) {
+	// Arrange
+	lt := &ListenerTests{}
+	lt.setup(t)
+	defer lt.teardown()
+	lis, address := listenLocal(t)
+
+	var mutex sync.Mutex
+	methods := make([]string, 0)
+	listener, err := serverResolver.GetListenerBuilder(lt.Resolver, lt.configHandler).
+		SetBootstrapper(func(config interface{}, serverSetter *server.ServerSetter) error {
+			serverSetter.Name = "InterceptedListener"
+			serverSetter.Listener = lis
+			serverSetter.ServerType = server.GRpcSever
+			serverSetter.GrpcReflection = true
+			serverSetter.UnaryInterceptors = append(serverSetter.UnaryInterceptors,
+				func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+					mutex.Lock()
+					methods = append(methods, info.FullMethod)
+					mutex.Unlock()
+					return handler(ctx, req)
+				})
+			serverSetter.GrpcOptions = append(serverSetter.GrpcOptions, grpc.MaxRecvMsgSize(1<<20))
+			return nil
+		}).
+		SetGrpcDefinitions(func(grpcServer *grpc.Server) {
+			healthpb.RegisterHealthServer(grpcServer, health.NewServer())
+		}).
+		GetListener()
+	if err != nil {
+		t.Fatalf("Arrange failed: %v", err)
+	}
+	finish := listener.Start()
+	defer func() {
+		listener.Stop()
+		<-finish
+	}()
+	waitForServer(t, address)
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Arrange failed: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Act
+	_, checkErr := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	stream, streamErr := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if streamErr != nil {
+		t.Fatalf("Act failed: %v", streamErr)
+	}
+	_ = stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	})
+	reflectionResponse, reflectionErr := stream.Recv()
+
+	// Assert
+	if checkErr != nil {
+		t.Fatalf("Assert failed: grpc request failed: %v", checkErr)
+	}
+	mutex.Lock()
+	if !reflect.DeepEqual([]string{healthpb.Health_Check_FullMethodName}, methods) {
+		t.Errorf("Assert failed: expected intercepted health check, got %v", methods)
+	}
+	mutex.Unlock()
+	if reflectionErr != nil {
+		t.Fatalf("Assert failed: reflection request failed: %v", reflectionErr)
+	}
+	services := make([]string, 0)
+	for _, service := range reflectionResponse.GetListServicesResponse().GetService() {
+		services = append(services, service.GetName())
+	}
+	if !slices.Contains(services, healthpb.Health_ServiceDesc.ServiceName) {
+		t.Errorf("Assert failed: expected reflection to list the health service, got %v", services)
+	}
+}
+
+func (lt *ListenerTests) createZeroDowntimeListener(name string, bootstrapErr error) (server.Listener, error) {
+	builder := serverResolver.GetListenerBuilder(lt.Resolver, lt.configHandler)
+	builder.SetBootstrapper(func(config interface{}, serverSetter *server.ServerSetter) error {
+		cfg := config.(*testConfig)
+		if cfg.Address2 == "broken" {
+			return bootstrapErr
+		}
+		serverSetter.Name = name
+		serverSetter.Addr = cfg.Address
+		serverSetter.Listener = lt.listeners[cfg.Address]
+		serverSetter.RestartMode = server.ZeroDowntimeRestart
+		body := []byte(cfg.Address2)
+		serverSetter.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write(body)
+		})
+		return nil
+	})
+	return builder.GetListener()
+}
+
+func (lt *ListenerTests) writeConfig(t *testing.T, config *testConfig) {
+	t.Helper()
+	content, err := json.MarshalIndent(config, "", "\t")
+	if err != nil {
+		t.Fatalf("failed to marshal config: %v", err)
+	}
+	_ = disk.CreateFile(lt.configFilePath, content)
+}
+
+// waitForRestart returns a channel that receives the state reached after the next restart
+func waitForRestart(t *testing.T, listener server.Listener) chan server.State {
+	t.Helper()
+	restarted := make(chan server.State, 1)
+	var mutex sync.Mutex
+	restarting := false
+	if err := listener.StateChangedSubscribe(func(event server.StateChangedEvent) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		switch {
+		case event.Current == server.Restarting:
+			restarting = true
+		case restarting:
+			restarting = false
+			restarted <- event.Current
+		}
+	}); err != nil {
+		t.Fatalf("failed to subscribe to state changes: %v", err)
+	}
+	return restarted
+}
+
+// waitForClosed reports whether the address stops accepting connections, old servers drain in the background
+func waitForClosed(address string) bool {
+	for i := 0; i < 100; i++ {
+		conn, err := net.Dial("tcp", address)
+		if err != nil {
+			return true
+		}
+		_ = conn.Close()
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+func getBody(address string) (string, error) {
+	response, err := http.Get("http://" + address + "/")
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = response.Body.Close() }()
+	body, err := io.ReadAll(response.Body)
+	return string(body), err
+}
+
+func TestListener_WhenZeroDowntimeRestartOnSameAddress_ThenServesEveryRequest(t *testing.T) {
+	// Arrange
+	lt := &ListenerTests{}
+	lt.setup(t)
+	defer lt.teardown()
+
+	listener, err := lt.createZeroDowntimeListener("ZeroDowntimeListener", nil)
+	if err != nil {
+		t.Fatalf("Arrange failed: %v", err)
+	}
+	restarted := waitForRestart(t, listener)
+	finish := listener.Start()
+	defer func() {
+		listener.Stop()
+		<-finish
+	}()
+	waitForServer(t, firstAddress)
+
+	var failures []error
+	bodies := make(map[string]int)
+	done := make(chan bool)
+	requesting := make(chan bool)
+	served := make(chan bool)
+	var once sync.Once
+	go func() {
+		defer close(requesting)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			body, err := getBody("127.0.0.1" + firstAddress)
+			if err != nil {
+				failures = append(failures, err)
+				continue
+			}
+			bodies[body]++
+			once.Do(func() { close(served) })
+		}
+	}()
+	<-served
+
+	// Act
+	lt.writeConfig(t, &testConfig{Address: firstAddress, Address2: "v2"})
+	state := <-restarted
+	time.Sleep(50 * time.Millisecond)
+	close(done)
+	<-requesting
+
+	// Assert
+	if state != server.Listening {
+		t.Errorf("Assert failed: expected Listening after restart, got %v", state)
+	}
+	if len(failures) > 0 {
+		t.Errorf("Assert failed: %d requests failed during restart, first: %v", len(failures), failures[0])
+	}
+	if bodies[secondAddress] == 0 || bodies["v2"] == 0 {
+		t.Errorf("Assert failed: expected responses from both servers, got %v", bodies)
+	}
+}
+
+func TestListener_WhenZeroDowntimeRestartChangesAddress_ThenMovesToNewAddress(t *testing.T) {
+	// Arrange
+	lt := &ListenerTests{}
+	lt.setup(t)
+	defer lt.teardown()
+	moved, movedAddress := listenLocal(t)
+	lt.listeners = map[string]net.Listener{"moved": moved}
+
+	listener, err := lt.createZeroDowntimeListener("MovingListener", nil)
+	if err != nil {
+		t.Fatalf("Arrange failed: %v", err)
+	}
+	restarted := waitForRestart(t, listener)
+	finish := listener.Start()
+	defer func() {
+		listener.Stop()
+		<-finish
+	}()
+	waitForServer(t, firstAddress)
+
+	// Act
+	lt.writeConfig(t, &testConfig{Address: "moved", Address2: "v2"})
+	<-restarted
+	body, err := getBody(movedAddress)
+	oldClosed := waitForClosed("127.0.0.1" + firstAddress)
+
+	// Assert
+	if err != nil || body != "v2" {
+		t.Errorf("Assert failed: expected v2 on the new address, got %q, %v", body, err)
+	}
+	if !oldClosed {
+		t.Errorf("Assert failed: expected the old address to be closed")
+	}
+}
+
+func TestListener_WhenZeroDowntimeRestartFails_ThenKeepsRunningServerAndRestoresConfig(t *testing.T) {
+	// Arrange
+	lt := &ListenerTests{}
+	lt.setup(t)
+	defer lt.teardown()
+
+	listener, err := lt.createZeroDowntimeListener("FallbackListener", errors.New("bootstrap failed"))
+	if err != nil {
+		t.Fatalf("Arrange failed: %v", err)
+	}
+	restarted := waitForRestart(t, listener)
+	finish := listener.Start()
+	defer func() {
+		listener.Stop()
+		<-finish
+	}()
+	waitForServer(t, firstAddress)
+
+	// Act
+	lt.writeConfig(t, &testConfig{Address: firstAddress, Address2: "broken"})
+	state := <-restarted
+	body, bodyErr := getBody("127.0.0.1" + firstAddress)
+
+	// Assert
+	if state != server.Listening {
+		t.Errorf("Assert failed: expected Listening after the failed restart, got %v", state)
+	}
+	if bodyErr != nil || body != secondAddress {
+		t.Errorf("Assert failed: expected the running server to answer its address, got %q, %v", body, bodyErr)
+	}
+	if address2 := lt.configHandler.GetConfig().(*testConfig).Address2; address2 != secondAddress {
+		t.Errorf("Assert failed: expected config to be restored, got %q", address2)
+	}
+}
+
+func TestListener_WhenStoppedWhileZeroDowntimeRestartDrains_ThenDoesNotWaitForTheDrain(t *testing.T) {
+	// Arrange
+	lt := &ListenerTests{}
+	lt.setup(t)
+	defer lt.teardown()
+
+	entered, release := make(chan bool, 1), make(chan bool)
+	defer close(release)
+	builder := serverResolver.GetListenerBuilder(lt.Resolver, lt.configHandler)
+	builder.SetBootstrapper(func(config interface{}, serverSetter *server.ServerSetter) error {
+		serverSetter.Name = "DrainingListener"
+		serverSetter.Addr = config.(*testConfig).Address
+		serverSetter.RestartMode = server.ZeroDowntimeRestart
+		serverSetter.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/slow" {
+				entered <- true
+				<-release
+			}
+		})
+		return nil
+	})
+	listener, err := builder.GetListener()
+	if err != nil {
+		t.Fatalf("Arrange failed: %v", err)
+	}
+	restarted := waitForRestart(t, listener)
+	finish := listener.Start()
+	waitForServer(t, firstAddress)
+	go func() {
+		if response, err := http.Get("http://127.0.0.1" + firstAddress + "/slow"); err == nil {
+			_ = response.Body.Close()
+		}
+	}()
+	<-entered
+	lt.writeConfig(t, &testConfig{Address: firstAddress, Address2: "v2"})
+	<-restarted
+
+	// Act
+	stopped := make(chan bool)
+	go func() {
+		listener.Stop()
+		<-finish
+		close(stopped)
+	}()
+
+	// Assert
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Errorf("Assert failed: expected Stop not to wait for the old server to drain")
+	}
+}
+
+func TestListener_WhenMetricsSet_ThenRecordsConfigReloadsAndRestarts(t *testing.T) {
+	// Arrange
+	lt := &ListenerTests{}
+	lt.setup(t)
+	defer lt.teardown()
+
+	registry := metrics.NewRegistry()
+	listener, err := serverResolver.GetListenerBuilder(lt.Resolver, lt.configHandler).
+		SetBootstrapper(func(config interface{}, serverSetter *server.ServerSetter) error {
+			serverSetter.Name = "MetricsListener"
+			serverSetter.Addr = config.(*testConfig).Address
+			serverSetter.Handler = http.NotFoundHandler()
+			return nil
+		}).
+		SetMetrics(registry).
+		GetListener()
+	if err != nil {
+		t.Fatalf("Arrange failed: %v", err)
+	}
+	restarted := waitForRestart(t, listener)
+	finish := listener.Start()
+	defer func() {
+		listener.Stop()
+		<-finish
+	}()
+	waitForServer(t, firstAddress)
+
+	// Act
+	lt.writeConfig(t, &testConfig{Address: firstAddress, Address2: "v2"})
+	state := <-restarted
+
+	// Assert
+	var out strings.Builder
+	if err := registry.Write(&out); err != nil {
+		t.Fatalf("Assert failed: %v", err)
+	}
+	if state != server.Listening {
+		t.Errorf("Assert failed: expected Listening after restart, got %v", state)
+	}
+	for _, expected := range []string{
+		`config_reloads_total{listener="MetricsListener",result="restarted"} 1`,
+		`listener_restarts_total{listener="MetricsListener",reason="config"} 1`,
+		`listener_listening{listener="MetricsListener"} 1`,
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Assert failed: expected %v in\n%v", expected, out.String())
+		}
+	}
+}
@@ -0,0 +1,63 @@
+//go:build !windows
+
+package server_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/janmbaco/go-infrastructure/v2/server"
+	serverResolver "github.com/janmbaco/go-infrastructure/v2/server/ioc/resolver"
+)
+
+func TestListener_WhenAddrIsUnixSocket_ThenServesOnSocket(t *testing.T) {
+	// Arrange
+	lt := &ListenerTests{}
+	lt.setup(t)
+	defer lt.teardown()
+
+	path := filepath.Join(t.TempDir(), "app.sock")
+	listener, err := serverResolver.GetListenerBuilder(lt.Resolver, lt.configHandler).
+		SetBootstrapper(func(config interface{}, serverSetter *server.ServerSetter) error {
+			serverSetter.Name = "UnixListener"
+			serverSetter.Addr = server.UnixAddrPrefix + path
+			serverSetter.SocketMode = 0o600
+			serverSetter.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("unix"))
+			})
+			return nil
+		}).
+		GetListener()
+	if err != nil {
+		t.Fatalf("Arrange failed: %v", err)
+	}
+	finish := listener.Start()
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+
+	// Act
+	response, err := client.Get("http://unix/")
+	listener.Stop()
+	<-finish
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Assert failed: request over the unix socket failed: %v", err)
+	}
+	defer func() { _ = response.Body.Close() }()
+	body, _ := io.ReadAll(response.Body)
+	if string(body) != "unix" {
+		t.Errorf("Assert failed: expected unix body, got %q", body)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Assert failed: expected the socket file to be removed on stop, got %v", err)
+	}
+}